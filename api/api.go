@@ -0,0 +1,19 @@
+// Package api holds the static API documentation assets compiled into the binary.
+package api
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// DocsHTML is the interactive documentation page served at /docs. It renders /openapi.json.
+//
+//go:embed docs.html
+var DocsHTML []byte
+
+//go:embed assets
+var assets embed.FS
+
+// Assets holds the scripts and styles of the documentation page, including a
+// pinned copy of swagger-ui, served under /docs/assets/
+var Assets, _ = fs.Sub(assets, "assets")
@@ -0,0 +1,8 @@
+// Renders /openapi.json. Kept out of docs.html so that the page needs no inline script.
+window.onload = () => {
+  window.ui = SwaggerUIBundle({
+    url: "/openapi.json",
+    dom_id: "#swagger-ui",
+    deepLinking: true,
+  });
+};
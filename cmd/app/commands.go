@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/raytr/go-template/internal/config"
+	"github.com/raytr/go-template/internal/database"
+	"github.com/raytr/go-template/internal/model"
+	"github.com/raytr/go-template/internal/ratelimit"
+	"github.com/raytr/go-template/internal/repository"
+	"gorm.io/gorm"
+)
+
+// commands run instead of the server when named as the first argument
+var commands = map[string]func(db *gorm.DB) error{
+	"report-duplicate-emails": reportDuplicateEmails,
+	"check-schema":            checkSchema,
+}
+
+// reportDuplicateEmails lists the users sharing an email when case is ignored.
+// Migration 000008 fails until none are left. It fails when it finds any, so
+// that it can gate a deployment.
+func reportDuplicateEmails(db *gorm.DB) error {
+	duplicates, err := repository.NewUserRepository(db).FindDuplicateEmails()
+	if err != nil {
+		return err
+	}
+
+	if len(duplicates) == 0 {
+		fmt.Println("No duplicate emails found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "EMAIL\tUSER IDS")
+	for _, d := range duplicates {
+		ids := make([]string, len(d.UserIDs))
+		for i, id := range d.UserIDs {
+			ids[i] = fmt.Sprint(id)
+		}
+		fmt.Fprintf(w, "%s\t%s\n", d.Email, strings.Join(ids, ", "))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%d emails are shared by more than one user", len(duplicates))
+}
+
+// checkSchema lists the differences between the models and the tables the
+// migrations created, and the tables no model covers. It fails when it finds
+// any, so that CI catches models and migrations drifting apart.
+func checkSchema(db *gorm.DB) error {
+	drifts, err := database.CheckDrift(db, append(model.Entities(), &ratelimit.BucketRow{})...)
+	if err != nil {
+		return err
+	}
+
+	if len(drifts) == 0 {
+		fmt.Println("Schema matches the models")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TABLE\tCOLUMN/INDEX\tDIFFERENCE")
+	for _, d := range drifts {
+		object := d.Object
+		if object == "" {
+			object = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", d.Table, object, d.Problem)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	return fmt.Errorf("schema differs from the models in %d places", len(drifts))
+}
+
+// configCommand runs "config validate", which checks the configuration without
+// connecting to anything. loadErr is the error config.Load returned. It returns
+// the exit code, non-zero when the configuration is invalid.
+func configCommand(args []string, loadErr error) int {
+	if len(args) != 1 || args[0] != "validate" {
+		fmt.Fprintln(os.Stderr, "usage: app config validate")
+		return 2
+	}
+
+	var invalid *config.ValidationError
+	switch {
+	case errors.As(loadErr, &invalid):
+		fmt.Fprintln(os.Stderr, "Configuration is invalid:")
+		for _, problem := range invalid.Problems {
+			fmt.Fprintln(os.Stderr, "  "+problem)
+		}
+		return 1
+	case loadErr != nil:
+		fmt.Fprintln(os.Stderr, loadErr)
+		return 1
+	}
+
+	fmt.Println("Configuration is valid")
+	return 0
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/raytr/go-template/internal/config"
+	"github.com/raytr/go-template/internal/database"
+	"github.com/raytr/go-template/internal/events"
+	"github.com/raytr/go-template/internal/flags"
+	"github.com/raytr/go-template/internal/handler"
+	"github.com/raytr/go-template/internal/idempotency"
+	"github.com/raytr/go-template/internal/migration"
+	"github.com/raytr/go-template/internal/repository"
+	"github.com/raytr/go-template/internal/webhook"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/pflag"
+	"gorm.io/gorm"
+)
+
+func main() {
+	// Load configuration
+	flagSet := pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	cfg, err := config.Load(flagSet, os.Args[1:])
+	if flagSet.Arg(0) == "config" {
+		os.Exit(configCommand(flagSet.Args()[1:], err))
+	}
+	// Listing the migrations needs only migration.dir, so the rest may be invalid
+	var invalid *config.ValidationError
+	if flagSet.Arg(0) == "migrate" && isMigrateList(flagSet.Args()[1:]) && (err == nil || errors.As(err, &invalid)) {
+		os.Exit(listMigrations(cfg))
+	}
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if flagSet.Arg(0) == "migrate" {
+		os.Exit(migrateCommand(flagSet.Args()[1:], cfg))
+	}
+
+	gin.SetMode(cfg.Server.GinMode)
+
+	// Connect to database
+	if err := database.Connect(cfg.Database); err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	db := database.GetDB()
+
+	// Run a one-off command instead of the server, before any migration
+	if name := flagSet.Arg(0); name != "" {
+		command, ok := commands[name]
+		if !ok {
+			log.Fatalf("Unknown command %q", name)
+		}
+		if err := command(db); err != nil {
+			log.Fatalf("%s: %v", name, err)
+		}
+		return
+	}
+
+	// Check and apply migrations
+	if err := migrateOnStart(db, cfg); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Start background workers
+	relay, err := newRelay(db, cfg)
+	if err != nil {
+		log.Fatalf("Failed to create outbox relay: %v", err)
+	}
+	go relay.Run(ctx)
+	go newDispatcher(db, cfg).Run(ctx)
+	go idempotency.NewSweeper(repository.NewIdempotencyRepository(db), cfg.Idempotency.SweepInterval).Run(ctx)
+
+	flagStore := flags.NewStore(cfg.FeatureFlags.Definitions, repository.NewFeatureFlagRepository(db), cfg.FeatureFlags.RefreshInterval)
+	go flagStore.Run(ctx)
+
+	reloader := config.NewReloader(cfg)
+	reloader.Subscribe(func(c *config.Config) {
+		database.SetLogLevel(c.Database.LogLevel)
+		flagStore.SetDefaults(c.FeatureFlags.Definitions)
+	})
+	if cfg.App.WatchConfig {
+		go reloader.Run(ctx)
+	}
+
+	router, err := handler.SetupRouter(db, cfg, reloader, flagStore)
+	if err != nil {
+		log.Fatalf("Failed to set up router: %v", err)
+	}
+
+	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
+	server := &http.Server{
+		Addr:         addr,
+		Handler:      router,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+
+	go func() {
+		log.Printf("Starting %s on %s", cfg.App.Name, addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("Shutting down...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server shutdown error: %v", err)
+	}
+}
+
+// migrateOnStart applies or verifies the migrations as MIGRATION_ON_START asks
+func migrateOnStart(db *gorm.DB, cfg *config.Config) error {
+	if cfg.Migration.OnStart == migration.OnStartSkip {
+		log.Println("Skipping migrations")
+		return nil
+	}
+	if cfg.Migration.Dir != "" {
+		log.Printf("Reading migrations from %s instead of the embedded files", cfg.Migration.Dir)
+	}
+
+	runner, err := newMigrationRunner(db, cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := runner.Close(); err != nil {
+			log.Printf("Migration runner close error: %v", err)
+		}
+	}()
+
+	if cfg.Migration.OnStart == migration.OnStartVerify {
+		return runner.Verify(cfg.Migration.WaitTimeout)
+	}
+	return runner.CheckAndRun()
+}
+
+// newMigrationRunner creates a runner for the migrations selected by MIGRATIONS_DIR
+func newMigrationRunner(db *gorm.DB, cfg *config.Config) (*migration.Runner, error) {
+	return migration.NewRunner(db, migration.Source(cfg.Migration.Dir), migration.Options{
+		Env:              cfg.App.Env,
+		Verbose:          cfg.Migration.Verbose,
+		LockTimeout:      cfg.Migration.LockTimeout,
+		StatementTimeout: cfg.Migration.StatementTimeout,
+	})
+}
+
+// newRelay creates the outbox relay for the sink selected by OUTBOX_SINK
+func newRelay(db *gorm.DB, cfg *config.Config) (*events.Relay, error) {
+	var sink events.Sink
+	switch cfg.Outbox.Sink {
+	case "http":
+		sink = events.NewHTTPSink(cfg.Outbox.WebhookURL, 10*time.Second)
+	case "broker":
+		opts, err := redis.ParseURL(cfg.Outbox.BrokerURL.Value())
+		if err != nil {
+			return nil, fmt.Errorf("invalid outbox broker URL: %w", err)
+		}
+		publisher := events.NewRedisStreamPublisher(redis.NewClient(opts), cfg.Outbox.StreamMaxLen)
+		sink = events.NewBrokerSink(publisher, cfg.Outbox.SubjectPrefix)
+	default:
+		sink = events.NewLogSink()
+	}
+
+	return events.NewRelay(
+		repository.NewOutboxRepository(db),
+		sink,
+		events.RelayConfig{
+			PollInterval: cfg.Outbox.PollInterval,
+			BatchSize:    cfg.Outbox.BatchSize,
+			MaxAttempts:  cfg.Outbox.MaxAttempts,
+			BaseBackoff:  time.Second,
+			MaxBackoff:   10 * time.Minute,
+			LeaseTimeout: cfg.Outbox.LeaseTimeout,
+		},
+	), nil
+}
+
+// newDispatcher creates the webhook delivery dispatcher
+func newDispatcher(db *gorm.DB, cfg *config.Config) *webhook.Dispatcher {
+	return webhook.NewDispatcher(
+		repository.NewWebhookRepository(db),
+		repository.NewTransactor(db),
+		webhook.DispatcherConfig{
+			PollInterval:        cfg.Webhook.PollInterval,
+			BatchSize:           20,
+			MaxAttempts:         cfg.Webhook.MaxAttempts,
+			Timeout:             cfg.Webhook.Timeout,
+			BaseBackoff:         5 * time.Second,
+			MaxBackoff:          time.Hour,
+			LeaseTimeout:        cfg.Webhook.LeaseTimeout,
+			AllowPrivateTargets: cfg.Webhook.AllowPrivateTargets,
+		},
+	)
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/raytr/go-template/internal/config"
+	"github.com/raytr/go-template/internal/database"
+	"github.com/raytr/go-template/internal/migration"
+)
+
+const migrateUsage = `usage: app migrate <command>
+  list       list the migrations of the binary, without connecting
+  status     list the applied and pending migrations
+  plan       print the SQL of the pending migrations
+  dry-run    run the pending migrations in a transaction that is rolled back, timing each
+  up         apply every pending migration
+  verify     fail unless every migration is applied
+  down N     roll back the last N migrations
+  steps N    apply N migrations, or roll back -N
+  goto V     migrate up or down to version V
+  force V    record version V after fixing a failed migration by hand (-1 for none)
+  drop       drop everything in the database (development and test only)`
+
+// errUsage reports arguments that do not match migrateUsage
+var errUsage = errors.New("invalid arguments")
+
+// readOnlyCommands leave the version unchanged, so it is not printed after them
+var readOnlyCommands = map[string]bool{"status": true, "verify": true, "plan": true, "dry-run": true}
+
+// migrateCommand runs "migrate <command>" and returns the exit code
+func migrateCommand(args []string, cfg *config.Config) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	if isMigrateList(args) {
+		return listMigrations(cfg)
+	}
+
+	if err := database.Connect(cfg.Database); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to database: %v\n", err)
+		return 1
+	}
+	defer database.Close()
+
+	runner, err := newMigrationRunner(database.GetDB(), cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer runner.Close()
+
+	err = runMigrate(runner, args)
+	switch {
+	case errors.Is(err, errUsage):
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	case errors.Is(err, migration.ErrNoChange):
+		fmt.Println("Nothing to migrate")
+	case err != nil:
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if !readOnlyCommands[args[0]] {
+		version, dirty, err := runner.Version()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("Database is at version %d%s\n", version, dirtyLabel(dirty))
+	}
+	return 0
+}
+
+// isMigrateList reports whether args, after "migrate", ask for the list command
+func isMigrateList(args []string) bool {
+	return len(args) == 1 && args[0] == "list"
+}
+
+// listMigrations prints the migrations selected by MIGRATIONS_DIR and returns the
+// exit code. It only reads migration.dir, so it runs without a database.
+func listMigrations(cfg *config.Config) int {
+	files, err := migration.List(migration.Source(cfg.Migration.Dir))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if cfg.Migration.Dir != "" {
+		fmt.Printf("Migrations in %s:\n", cfg.Migration.Dir)
+	} else {
+		fmt.Println("Embedded migrations:")
+	}
+	return printFiles(files)
+}
+
+// runMigrate runs a migrate command other than list
+func runMigrate(runner *migration.Runner, args []string) error {
+	name, arg := args[0], ""
+	switch {
+	case len(args) == 2:
+		arg = args[1]
+	case len(args) > 2:
+		return errUsage
+	}
+
+	switch name {
+	case "status":
+		if arg != "" {
+			return errUsage
+		}
+		return printStatus(runner)
+	case "plan":
+		if arg != "" {
+			return errUsage
+		}
+		return printPlan(runner)
+	case "dry-run":
+		if arg != "" {
+			return errUsage
+		}
+		return printDryRun(runner)
+	case "up":
+		if arg != "" {
+			return errUsage
+		}
+		return runner.Up()
+	case "verify":
+		if arg != "" {
+			return errUsage
+		}
+		return runner.Verify(0)
+	case "down", "steps", "force":
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			return errUsage
+		}
+		switch name {
+		case "down":
+			return runner.Down(n)
+		case "steps":
+			return runner.Steps(n)
+		default:
+			return runner.Force(n)
+		}
+	case "goto":
+		version, err := strconv.ParseUint(arg, 10, 0)
+		if err != nil {
+			return errUsage
+		}
+		return runner.Goto(uint(version))
+	case "drop":
+		if arg != "" {
+			return errUsage
+		}
+		return runner.Drop()
+	default:
+		return errUsage
+	}
+}
+
+// printStatus lists every migration with whether it is applied
+func printStatus(runner *migration.Runner) error {
+	status, err := runner.Status()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Database is at version %d%s\n", status.Version, dirtyLabel(status.Dirty))
+	if status.Unknown {
+		fmt.Println("This version has no migration here; the database was migrated by another build")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATE")
+	for _, f := range status.Applied {
+		fmt.Fprintf(w, "%d\t%s\tapplied\n", f.Version, f.Name)
+	}
+	for _, f := range status.Pending {
+		fmt.Fprintf(w, "%d\t%s\tpending\n", f.Version, f.Name)
+	}
+	return w.Flush()
+}
+
+// printPlan prints the SQL of every pending migration
+func printPlan(runner *migration.Runner) error {
+	plan, err := runner.Plan()
+	if err != nil {
+		return err
+	}
+	if len(plan) == 0 {
+		return migration.ErrNoChange
+	}
+
+	for _, step := range plan {
+		fmt.Printf("-- Migration %d: %s\n%s\n", step.Version, step.Name, strings.TrimRight(step.SQL, "\n"))
+		fmt.Println()
+	}
+	return nil
+}
+
+// printDryRun runs the pending migrations without keeping them and prints the
+// result and duration of each
+func printDryRun(runner *migration.Runner) error {
+	plan, err := runner.DryRun()
+	if len(plan) == 0 {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tRESULT\tDURATION")
+	var total time.Duration
+	for _, step := range plan {
+		result := "ok"
+		switch {
+		case !step.Ran:
+			result = "not run"
+		case step.Err != nil:
+			result = "failed"
+		}
+		total += step.Duration
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", step.Version, step.Name, result, step.Duration.Round(time.Millisecond))
+	}
+	if flushErr := w.Flush(); flushErr != nil {
+		return flushErr
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("All %d migrations applied in %s and were rolled back\n", len(plan), total.Round(time.Millisecond))
+	return nil
+}
+
+// printFiles lists migrations with whether they can be rolled back
+func printFiles(files []migration.File) int {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tDOWN")
+	for _, f := range files {
+		down := "no"
+		if f.HasDown {
+			down = "yes"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", f.Version, f.Name, down)
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func dirtyLabel(dirty bool) string {
+	if dirty {
+		return " (dirty)"
+	}
+	return ""
+}
@@ -0,0 +1,117 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strconv"
+
+	"github.com/raytr/go-template/internal/auth"
+	"github.com/raytr/go-template/internal/model"
+	"github.com/raytr/go-template/internal/requestid"
+)
+
+// MaskedValue replaces the value of sensitive fields in recorded changes
+const MaskedValue = "***"
+
+// SensitiveFields lists the JSON field names whose values are never written to the audit log
+var SensitiveFields = map[string]bool{
+	"phone":   true,
+	"address": true,
+}
+
+// ignoredFields are maintained by the database and not meaningful in a diff
+var ignoredFields = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+}
+
+// Diff compares the JSON representation of two values and returns the fields that differ.
+// Either value may be nil, for creations and deletions.
+func Diff(before, after interface{}) model.AuditChanges {
+	from := toFieldMap(before)
+	to := toFieldMap(after)
+
+	changes := model.AuditChanges{}
+	for field := range union(from, to) {
+		if ignoredFields[field] {
+			continue
+		}
+
+		oldValue, newValue := from[field], to[field]
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+
+		if SensitiveFields[field] {
+			oldValue, newValue = mask(oldValue), mask(newValue)
+		}
+
+		changes[field] = model.FieldChange{From: oldValue, To: newValue}
+	}
+
+	return changes
+}
+
+// NewEvent builds an audit event attributed to the principal and request ID in ctx
+func NewEvent(ctx context.Context, action, entityType string, entityID uint, changes model.AuditChanges) *model.AuditEventEntity {
+	actorType, actorID := Actor(ctx)
+
+	return &model.AuditEventEntity{
+		ActorType:  actorType,
+		ActorID:    actorID,
+		RequestID:  requestid.FromContext(ctx),
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   strconv.FormatUint(uint64(entityID), 10),
+		Changes:    changes,
+	}
+}
+
+// Actor describes the principal in ctx as an actor type and ID
+func Actor(ctx context.Context) (string, string) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	switch {
+	case !ok:
+		return model.AuditActorAnonymous, ""
+	case principal.System:
+		return model.AuditActorSystem, ""
+	case principal.APIKeyID != 0:
+		return model.AuditActorAPIKey, strconv.FormatUint(uint64(principal.APIKeyID), 10)
+	default:
+		return model.AuditActorUser, strconv.FormatUint(uint64(principal.UserID), 10)
+	}
+}
+
+func toFieldMap(v interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return fields
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fields
+	}
+	_ = json.Unmarshal(data, &fields)
+
+	return fields
+}
+
+func union(a, b map[string]interface{}) map[string]struct{} {
+	keys := make(map[string]struct{}, len(a)+len(b))
+	for k := range a {
+		keys[k] = struct{}{}
+	}
+	for k := range b {
+		keys[k] = struct{}{}
+	}
+	return keys
+}
+
+func mask(v interface{}) interface{} {
+	if v == nil || v == "" {
+		return v
+	}
+	return MaskedValue
+}
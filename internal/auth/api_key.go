@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// APIKeyPrefix marks strings issued by GenerateAPIKey
+const APIKeyPrefix = "gtk"
+
+// ErrInvalidAPIKey is returned when an API key is malformed, unknown, revoked or expired
+var ErrInvalidAPIKey = errors.New("invalid or expired api key")
+
+// GenerateAPIKey returns a new plaintext key of the form gtk_<prefix>_<secret>
+// together with its lookup prefix and hash
+func GenerateAPIKey() (key, prefix, hash string, err error) {
+	prefixBytes := make([]byte, 6)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return "", "", "", err
+	}
+
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", "", "", err
+	}
+
+	prefix = hex.EncodeToString(prefixBytes)
+	key = APIKeyPrefix + "_" + prefix + "_" + hex.EncodeToString(secretBytes)
+
+	return key, prefix, HashAPIKey(key), nil
+}
+
+// ParseAPIKeyPrefix extracts the lookup prefix from a plaintext key
+func ParseAPIKeyPrefix(key string) (string, error) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != APIKeyPrefix || parts[1] == "" || parts[2] == "" {
+		return "", ErrInvalidAPIKey
+	}
+	return parts[1], nil
+}
+
+// HashAPIKey returns the hex SHA-256 digest stored for a plaintext key
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/raytr/go-template/internal/repository"
+)
+
+// ErrInvalidToken is returned when a bearer token cannot be verified
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// Authenticator verifies credentials and resolves them to a principal
+type Authenticator struct {
+	jwtSecret  []byte
+	rbacRepo   *repository.RBACRepository
+	apiKeyRepo *repository.APIKeyRepository
+}
+
+// lastUsedResolution bounds how often an API key's last_used_at is written
+const lastUsedResolution = time.Minute
+
+// NewAuthenticator creates a new authenticator
+func NewAuthenticator(
+	jwtSecret string,
+	rbacRepo *repository.RBACRepository,
+	apiKeyRepo *repository.APIKeyRepository,
+) *Authenticator {
+	return &Authenticator{
+		jwtSecret:  []byte(jwtSecret),
+		rbacRepo:   rbacRepo,
+		apiKeyRepo: apiKeyRepo,
+	}
+}
+
+// AuthenticateJWT verifies an HS256 token whose subject is the user ID. It returns
+// ErrInvalidToken for tokens that do not verify and other errors when the
+// user's roles cannot be loaded.
+func (a *Authenticator) AuthenticateJWT(tokenString string) (*Principal, error) {
+	claims := &jwt.RegisteredClaims{}
+
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		return a.jwtSecret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	userID, err := strconv.ParseUint(claims.Subject, 10, 32)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	return a.LoadPrincipal(uint(userID))
+}
+
+// LoadPrincipal builds the principal for a user from their assigned roles.
+// Users without an explicit assignment get the default user role.
+func (a *Authenticator) LoadPrincipal(userID uint) (*Principal, error) {
+	roles, err := a.rbacRepo.GetRoleNamesByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load principal: %w", err)
+	}
+
+	if len(roles) == 0 {
+		roles = []string{RoleUser}
+	}
+
+	permissions, err := a.rbacRepo.GetPermissionNamesByRoles(roles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load principal: %w", err)
+	}
+
+	return NewPrincipal(userID, roles, permissions), nil
+}
+
+// AuthenticateAPIKey verifies a plaintext API key and returns a principal limited to its scopes.
+// It returns ErrInvalidAPIKey for keys that do not authenticate and other errors
+// when the key cannot be looked up.
+func (a *Authenticator) AuthenticateAPIKey(key string) (*Principal, error) {
+	prefix, err := ParseAPIKeyPrefix(key)
+	if err != nil {
+		return nil, err
+	}
+
+	entity, err := a.apiKeyRepo.GetByPrefix(prefix)
+	if errors.Is(err, repository.ErrAPIKeyNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate api key: %w", err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(entity.KeyHash), []byte(HashAPIKey(key))) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if !entity.IsActive(now) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if entity.LastUsedAt == nil || now.Sub(*entity.LastUsedAt) >= lastUsedResolution {
+		if err := a.apiKeyRepo.TouchLastUsed(entity.ID, now); err != nil {
+			log.Printf("Failed to record api key usage: %v", err)
+		}
+	}
+
+	return NewAPIKeyPrincipal(entity.ID, entity.Scopes), nil
+}
@@ -0,0 +1,245 @@
+package auth
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/raytr/go-template/internal/repository"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	testSecret      = "test-secret"
+	selectRoles     = `SELECT "roles"."name" FROM "roles" JOIN user_roles`
+	selectPerms     = `FROM "permissions" JOIN role_permissions`
+	selectAPIKey    = `SELECT \* FROM "api_keys" WHERE prefix = \$1`
+	updateAPIKeyUse = `UPDATE "api_keys" SET "last_used_at"`
+)
+
+var errDBDown = errors.New("connection refused")
+
+// newTestAuthenticator returns an authenticator whose repositories run on sqlmock
+func newTestAuthenticator(t *testing.T) (*Authenticator, sqlmock.Sqlmock) {
+	t.Helper()
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		conn.Close()
+	})
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{
+		Logger:                 logger.Discard,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	return NewAuthenticator(testSecret, repository.NewRBACRepository(db), repository.NewAPIKeyRepository(db)), mock
+}
+
+func signToken(t *testing.T, subject string, method jwt.SigningMethod, expiresAt time.Time) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, jwt.RegisteredClaims{
+		Subject:   subject,
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestAuthenticateJWT(t *testing.T) {
+	a, mock := newTestAuthenticator(t)
+	mock.ExpectQuery(selectRoles).WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("admin"))
+	mock.ExpectQuery(selectPerms).WithArgs("admin").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("users:list").AddRow("users:read"))
+
+	principal, err := a.AuthenticateJWT(signToken(t, "7", jwt.SigningMethodHS256, time.Now().Add(time.Hour)))
+	if err != nil {
+		t.Fatalf("AuthenticateJWT() failed: %v", err)
+	}
+	if principal.UserID != 7 || !reflect.DeepEqual(principal.Roles, []string{"admin"}) {
+		t.Errorf("principal = user %d with roles %v, want user 7 with [admin]", principal.UserID, principal.Roles)
+	}
+	if !principal.HasPermission(PermUsersList) || principal.HasPermission(PermUsersDelete) {
+		t.Errorf("permissions = %v, want users:list and users:read", principal.Permissions)
+	}
+}
+
+func TestAuthenticateJWTRejectsInvalidTokens(t *testing.T) {
+	a, _ := newTestAuthenticator(t)
+
+	tests := map[string]string{
+		"garbage":      "not-a-token",
+		"expired":      signToken(t, "7", jwt.SigningMethodHS256, time.Now().Add(-time.Minute)),
+		"other method": signToken(t, "7", jwt.SigningMethodHS512, time.Now().Add(time.Hour)),
+		"bad subject":  signToken(t, "seven", jwt.SigningMethodHS256, time.Now().Add(time.Hour)),
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := a.AuthenticateJWT(token); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("AuthenticateJWT() error = %v, want %v", err, ErrInvalidToken)
+			}
+		})
+	}
+}
+
+func TestLoadPrincipalDefaultsToUserRole(t *testing.T) {
+	a, mock := newTestAuthenticator(t)
+	mock.ExpectQuery(selectRoles).WithArgs(7).WillReturnRows(sqlmock.NewRows([]string{"name"}))
+	mock.ExpectQuery(selectPerms).WithArgs(RoleUser).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("users:read:own"))
+
+	principal, err := a.LoadPrincipal(7)
+	if err != nil {
+		t.Fatalf("LoadPrincipal() failed: %v", err)
+	}
+	if !principal.HasRole(RoleUser) || !principal.HasPermission(PermUsersReadOwn) {
+		t.Errorf("principal = roles %v, permissions %v, want the user role", principal.Roles, principal.Permissions)
+	}
+}
+
+func TestLoadPrincipalDatabaseErrors(t *testing.T) {
+	tests := map[string]func(mock sqlmock.Sqlmock){
+		"roles": func(mock sqlmock.Sqlmock) {
+			mock.ExpectQuery(selectRoles).WillReturnError(errDBDown)
+		},
+		"permissions": func(mock sqlmock.Sqlmock) {
+			mock.ExpectQuery(selectRoles).WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("admin"))
+			mock.ExpectQuery(selectPerms).WillReturnError(errDBDown)
+		},
+	}
+
+	for name, expect := range tests {
+		t.Run(name, func(t *testing.T) {
+			a, mock := newTestAuthenticator(t)
+			expect(mock)
+
+			_, err := a.AuthenticateJWT(signToken(t, "7", jwt.SigningMethodHS256, time.Now().Add(time.Hour)))
+			if !errors.Is(err, errDBDown) {
+				t.Errorf("AuthenticateJWT() error = %v, want it to wrap %v", err, errDBDown)
+			}
+			if errors.Is(err, ErrInvalidToken) {
+				t.Error("a database failure was reported as an invalid token")
+			}
+		})
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	key, prefix, hash, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	columns := []string{"id", "prefix", "key_hash", "scopes", "expires_at", "last_used_at", "revoked_at"}
+	recently := time.Now().Add(-time.Second)
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name   string
+		key    string
+		expect func(mock sqlmock.Sqlmock)
+		want   error
+	}{
+		{
+			name: "active",
+			key:  key,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(selectAPIKey).WithArgs(prefix, 1).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(3, prefix, hash, "{audit:read}", nil, nil, nil))
+				mock.ExpectExec(updateAPIKeyUse).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name: "used recently",
+			key:  key,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(selectAPIKey).WithArgs(prefix, 1).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(3, prefix, hash, "{audit:read}", nil, recently, nil))
+			},
+		},
+		{
+			name:   "malformed",
+			key:    "not-a-key",
+			expect: func(sqlmock.Sqlmock) {},
+			want:   ErrInvalidAPIKey,
+		},
+		{
+			name: "unknown",
+			key:  key,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(selectAPIKey).WithArgs(prefix, 1).WillReturnRows(sqlmock.NewRows(columns))
+			},
+			want: ErrInvalidAPIKey,
+		},
+		{
+			name: "wrong secret",
+			key:  key,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(selectAPIKey).WithArgs(prefix, 1).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(3, prefix, HashAPIKey(key+"x"), "{audit:read}", nil, nil, nil))
+			},
+			want: ErrInvalidAPIKey,
+		},
+		{
+			name: "revoked",
+			key:  key,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(selectAPIKey).WithArgs(prefix, 1).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(3, prefix, hash, "{audit:read}", nil, nil, past))
+			},
+			want: ErrInvalidAPIKey,
+		},
+		{
+			name: "expired",
+			key:  key,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(selectAPIKey).WithArgs(prefix, 1).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(3, prefix, hash, "{audit:read}", past, nil, nil))
+			},
+			want: ErrInvalidAPIKey,
+		},
+		{
+			name: "database down",
+			key:  key,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(selectAPIKey).WithArgs(prefix, 1).WillReturnError(errDBDown)
+			},
+			want: errDBDown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, mock := newTestAuthenticator(t)
+			tt.expect(mock)
+
+			principal, err := a.AuthenticateAPIKey(tt.key)
+			if tt.want != nil {
+				if !errors.Is(err, tt.want) {
+					t.Errorf("AuthenticateAPIKey() error = %v, want %v", err, tt.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AuthenticateAPIKey() failed: %v", err)
+			}
+			if principal.APIKeyID != 3 || principal.UserID != 0 || !principal.HasPermission(PermAuditRead) {
+				t.Errorf("principal = key %d, user %d, permissions %v", principal.APIKeyID, principal.UserID, principal.Permissions)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+)
+
+// Permission names an action that can be granted to a role
+type Permission string
+
+// Permissions seeded by the RBAC migration
+const (
+	PermUsersList      Permission = "users:list"
+	PermUsersRead      Permission = "users:read"
+	PermUsersReadOwn   Permission = "users:read:own"
+	PermUsersCreate    Permission = "users:create"
+	PermUsersUpdate    Permission = "users:update"
+	PermUsersUpdateOwn Permission = "users:update:own"
+	PermUsersDelete    Permission = "users:delete"
+	PermAPIKeysManage  Permission = "api_keys:manage"
+	PermAuditRead      Permission = "audit:read"
+	PermWebhooksManage Permission = "webhooks:manage"
+	PermFlagsManage    Permission = "flags:manage"
+	PermMetricsRead    Permission = "metrics:read"
+)
+
+// KnownPermissions lists every permission that can be granted to a role or API key
+var KnownPermissions = []Permission{
+	PermUsersList,
+	PermUsersRead,
+	PermUsersReadOwn,
+	PermUsersCreate,
+	PermUsersUpdate,
+	PermUsersUpdateOwn,
+	PermUsersDelete,
+	PermAPIKeysManage,
+	PermAuditRead,
+	PermWebhooksManage,
+	PermFlagsManage,
+	PermMetricsRead,
+}
+
+// IsKnownPermission reports whether name is one of KnownPermissions
+func IsKnownPermission(name string) bool {
+	for _, perm := range KnownPermissions {
+		if string(perm) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Own returns the permission restricted to resources owned by the caller
+func (p Permission) Own() Permission {
+	return p + ":own"
+}
+
+// Reason codes returned with authorization failures
+const (
+	ReasonUnauthenticated   = "unauthenticated"
+	ReasonMissingPermission = "missing_permission"
+	ReasonNotResourceOwner  = "not_resource_owner"
+)
+
+// DeniedError is returned when a principal is not allowed to perform an action
+type DeniedError struct {
+	Permission Permission
+	Reason     string
+}
+
+// Error implements the error interface
+func (e *DeniedError) Error() string {
+	switch e.Reason {
+	case ReasonUnauthenticated:
+		return "authentication required"
+	case ReasonNotResourceOwner:
+		return fmt.Sprintf("permission %s only applies to your own resources", e.Permission.Own())
+	default:
+		return fmt.Sprintf("missing permission %s", e.Permission)
+	}
+}
+
+// Policy evaluates permission checks against the principal stored in a context
+type Policy struct{}
+
+// NewPolicy creates a new authorization policy
+func NewPolicy() *Policy {
+	return &Policy{}
+}
+
+// Authorize checks that the caller holds the permission
+func (p *Policy) Authorize(ctx context.Context, perm Permission) error {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return &DeniedError{Permission: perm, Reason: ReasonUnauthenticated}
+	}
+
+	if !principal.HasPermission(perm) {
+		return &DeniedError{Permission: perm, Reason: ReasonMissingPermission}
+	}
+
+	return nil
+}
+
+// AuthorizeOwned checks that the caller holds the permission, or its ":own"
+// variant when the resource belongs to the caller. Principals without a user,
+// such as API keys, own nothing.
+func (p *Policy) AuthorizeOwned(ctx context.Context, perm Permission, ownerID uint) error {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return &DeniedError{Permission: perm, Reason: ReasonUnauthenticated}
+	}
+
+	if principal.HasPermission(perm) {
+		return nil
+	}
+
+	if principal.HasPermission(perm.Own()) {
+		if principal.UserID != 0 && principal.UserID == ownerID {
+			return nil
+		}
+		return &DeniedError{Permission: perm, Reason: ReasonNotResourceOwner}
+	}
+
+	return &DeniedError{Permission: perm, Reason: ReasonMissingPermission}
+}
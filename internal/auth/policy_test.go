@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestAuthorize(t *testing.T) {
+	policy := NewPolicy()
+
+	tests := []struct {
+		name      string
+		principal *Principal
+		perm      Permission
+		reason    string
+	}{
+		{"anonymous", nil, PermUsersList, ReasonUnauthenticated},
+		{"granted", NewPrincipal(1, []string{RoleAdmin}, []string{"users:list"}), PermUsersList, ""},
+		{"missing", NewPrincipal(1, []string{RoleUser}, []string{"users:read:own"}), PermUsersList, ReasonMissingPermission},
+		{"own variant is not enough", NewPrincipal(1, []string{RoleUser}, []string{"users:read:own"}), PermUsersRead, ReasonMissingPermission},
+		{"api key scope", NewAPIKeyPrincipal(3, []string{"audit:read"}), PermAuditRead, ""},
+		{"api key without the scope", NewAPIKeyPrincipal(3, []string{"audit:read"}), PermUsersList, ReasonMissingPermission},
+		{"system", SystemPrincipal(), PermUsersDelete, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = WithPrincipal(ctx, tt.principal)
+			}
+			checkDenied(t, policy.Authorize(ctx, tt.perm), tt.perm, tt.reason)
+		})
+	}
+}
+
+func TestAuthorizeOwned(t *testing.T) {
+	policy := NewPolicy()
+	user := NewPrincipal(7, []string{RoleUser}, []string{"users:read:own", "users:update:own"})
+	admin := NewPrincipal(1, []string{RoleAdmin}, []string{"users:read", "users:update"})
+
+	tests := []struct {
+		name      string
+		principal *Principal
+		perm      Permission
+		ownerID   uint
+		reason    string
+	}{
+		{"anonymous", nil, PermUsersRead, 7, ReasonUnauthenticated},
+		{"own record", user, PermUsersRead, 7, ""},
+		{"someone else's record", user, PermUsersRead, 8, ReasonNotResourceOwner},
+		{"unparsable owner", user, PermUsersUpdate, 0, ReasonNotResourceOwner},
+		{"no own variant", user, PermUsersDelete, 7, ReasonMissingPermission},
+		{"unrestricted permission", admin, PermUsersRead, 8, ""},
+		{"unrestricted permission, any owner", admin, PermUsersUpdate, 0, ""},
+		// API keys have no user ID, so ":own" scopes match nothing
+		{"api key with an own scope", NewAPIKeyPrincipal(3, []string{"users:read:own"}), PermUsersRead, 0, ReasonNotResourceOwner},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = WithPrincipal(ctx, tt.principal)
+			}
+			checkDenied(t, policy.AuthorizeOwned(ctx, tt.perm, tt.ownerID), tt.perm, tt.reason)
+		})
+	}
+}
+
+// checkDenied checks that err denies perm for reason, or is nil when reason is empty
+func checkDenied(t *testing.T, err error, perm Permission, reason string) {
+	t.Helper()
+	if reason == "" {
+		if err != nil {
+			t.Errorf("err = %v, want access", err)
+		}
+		return
+	}
+
+	var denied *DeniedError
+	if !errors.As(err, &denied) {
+		t.Fatalf("err = %v, want a DeniedError", err)
+	}
+	if denied.Reason != reason || denied.Permission != perm {
+		t.Errorf("denied %s for %s, want %s for %s", denied.Permission, denied.Reason, perm, reason)
+	}
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"context"
+)
+
+// Role names seeded by the RBAC migration
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// Principal describes the authenticated caller of a request
+type Principal struct {
+	UserID      uint
+	APIKeyID    uint
+	Roles       []string
+	Permissions map[Permission]struct{}
+	System      bool
+}
+
+// NewPrincipal creates a principal for a user with the given roles and permissions
+func NewPrincipal(userID uint, roles []string, permissions []string) *Principal {
+	perms := make(map[Permission]struct{}, len(permissions))
+	for _, name := range permissions {
+		perms[Permission(name)] = struct{}{}
+	}
+
+	return &Principal{
+		UserID:      userID,
+		Roles:       roles,
+		Permissions: perms,
+	}
+}
+
+// NewAPIKeyPrincipal creates a principal for an API key limited to its scopes
+func NewAPIKeyPrincipal(apiKeyID uint, scopes []string) *Principal {
+	p := NewPrincipal(0, nil, scopes)
+	p.APIKeyID = apiKeyID
+	return p
+}
+
+// SystemPrincipal returns a principal for internal callers such as background jobs.
+// It is granted every permission.
+func SystemPrincipal() *Principal {
+	return &Principal{System: true}
+}
+
+// HasPermission reports whether the principal was granted the permission
+func (p *Principal) HasPermission(perm Permission) bool {
+	if p.System {
+		return true
+	}
+	_, ok := p.Permissions[perm]
+	return ok
+}
+
+// HasRole reports whether the principal holds the named role
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the principal
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext extracts the principal stored by WithPrincipal
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
@@ -0,0 +1,183 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Store keeps cached values. Implementations must be safe for concurrent use.
+type Store interface {
+	// Get returns the value at key. The second return value is false on a miss.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value at key for ttl
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes keys, ignoring the ones that are not cached
+	Delete(ctx context.Context, keys ...string) error
+	// Version returns the counter at key, zero when it was never bumped
+	Version(ctx context.Context, key string) (int64, error)
+	// Bump increments the counter at key. Counters do not expire.
+	Bump(ctx context.Context, key string) error
+}
+
+// registry holds every cache by name, for AllStats
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]*Cache)
+)
+
+// Stats counts the lookups of a cache
+type Stats struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+	Errors int64 `json:"errors"`
+}
+
+// Cache stores JSON encoded values in a Store. Concurrent misses for the same
+// key share a single load. Store failures are logged and treated as misses so
+// that reads keep working when the backend is down.
+type Cache struct {
+	name   string
+	store  Store
+	ttl    time.Duration
+	group  singleflight.Group
+	hits   atomic.Int64
+	misses atomic.Int64
+	errors atomic.Int64
+}
+
+// New creates a cache whose entries live for ttl. Its stats are reported by
+// AllStats under name.
+func New(name string, store Store, ttl time.Duration) *Cache {
+	c := &Cache{
+		name:  name,
+		store: store,
+		ttl:   ttl,
+	}
+
+	registryMu.Lock()
+	registry[name] = c
+	registryMu.Unlock()
+	return c
+}
+
+// AllStats returns the lookup counters of every cache, by name
+func AllStats() map[string]Stats {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	stats := make(map[string]Stats, len(registry))
+	for name, c := range registry {
+		stats[name] = c.Stats()
+	}
+	return stats
+}
+
+// Stats returns the lookup counters of the cache
+func (c *Cache) Stats() Stats {
+	return Stats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+		Errors: c.errors.Load(),
+	}
+}
+
+// Fetch returns the value cached at key, or calls load and caches its result.
+// Errors returned by load are not cached.
+func Fetch[T any](ctx context.Context, c *Cache, key string, load func() (T, error)) (T, error) {
+	var value T
+
+	data, ok, err := c.store.Get(ctx, key)
+	if err != nil {
+		c.fail("get", key, err)
+	}
+	if ok {
+		if err := json.Unmarshal(data, &value); err == nil {
+			c.hits.Add(1)
+			return value, nil
+		}
+		c.fail("decode", key, err)
+	}
+	c.misses.Add(1)
+
+	shared, err, _ := c.group.Do(key, func() (interface{}, error) {
+		generation := c.Version(ctx, c.generationKey())
+		loaded, err := load()
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := json.Marshal(loaded)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.store.Set(ctx, key, data, c.ttl); err != nil {
+			c.fail("set", key, err)
+		}
+		// A write committed during the load may have been invalidated before the
+		// Set above, so the value could be stale: drop it again
+		if c.Version(ctx, c.generationKey()) != generation {
+			if err := c.store.Delete(ctx, key); err != nil {
+				c.fail("delete", key, err)
+			}
+		}
+		return data, nil
+	})
+	if err != nil {
+		return value, err
+	}
+
+	// Every caller decodes its own copy of the shared result
+	if err := json.Unmarshal(shared.([]byte), &value); err != nil {
+		return value, err
+	}
+	return value, nil
+}
+
+// Version returns the counter at key, or zero when the store fails
+func (c *Cache) Version(ctx context.Context, key string) int64 {
+	version, err := c.store.Version(ctx, key)
+	if err != nil {
+		c.fail("version", key, err)
+	}
+	return version
+}
+
+// Invalidate removes keys and bumps the given version counters. Loads running
+// meanwhile do not leave their result cached.
+func (c *Cache) Invalidate(ctx context.Context, keys []string, versions ...string) {
+	// The write already happened, so finish even if the request is cancelled
+	ctx = context.WithoutCancel(ctx)
+
+	// Bumped before the delete, so that a load either sees the new generation
+	// or sets its value before the delete removes it
+	if err := c.store.Bump(ctx, c.generationKey()); err != nil {
+		c.fail("bump", c.generationKey(), err)
+	}
+
+	if len(keys) > 0 {
+		if err := c.store.Delete(ctx, keys...); err != nil {
+			c.fail("delete", keys[0], err)
+		}
+	}
+	for _, key := range versions {
+		if err := c.store.Bump(ctx, key); err != nil {
+			c.fail("bump", key, err)
+		}
+	}
+}
+
+// generationKey is the counter bumped by every Invalidate of the cache
+func (c *Cache) generationKey() string {
+	return c.name + ":generation"
+}
+
+func (c *Cache) fail(op, key string, err error) {
+	c.errors.Add(1)
+	log.Printf("Cache %s %s %q: %v", c.name, op, key, err)
+}
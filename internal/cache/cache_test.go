@@ -0,0 +1,189 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type thing struct {
+	Name string `json:"name"`
+}
+
+// failingStore fails every operation
+type failingStore struct{}
+
+var errDown = errors.New("store is down")
+
+func (failingStore) Get(context.Context, string) ([]byte, bool, error) { return nil, false, errDown }
+func (failingStore) Set(context.Context, string, []byte, time.Duration) error {
+	return errDown
+}
+func (failingStore) Delete(context.Context, ...string) error        { return errDown }
+func (failingStore) Version(context.Context, string) (int64, error) { return 0, errDown }
+func (failingStore) Bump(context.Context, string) error             { return errDown }
+
+// countingStore counts the misses of a MemoryStore
+type countingStore struct {
+	*MemoryStore
+	misses atomic.Int64
+}
+
+func (s *countingStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, ok, err := s.MemoryStore.Get(ctx, key)
+	if !ok {
+		s.misses.Add(1)
+	}
+	return value, ok, err
+}
+
+func TestFetchCachesLoadedValues(t *testing.T) {
+	ctx := context.Background()
+	c := New(t.Name(), NewMemoryStore(10), time.Minute)
+
+	loads := 0
+	load := func() (thing, error) {
+		loads++
+		return thing{Name: "a"}, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		got, err := Fetch(ctx, c, "k", load)
+		if err != nil {
+			t.Fatalf("Fetch() failed: %v", err)
+		}
+		if got.Name != "a" {
+			t.Errorf("Fetch() = %+v, want name a", got)
+		}
+	}
+
+	if loads != 1 {
+		t.Errorf("load ran %d times, want 1", loads)
+	}
+	if got, want := c.Stats(), (Stats{Hits: 2, Misses: 1}); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+	if got := AllStats()[t.Name()]; got != c.Stats() {
+		t.Errorf("AllStats()[%q] = %+v, want %+v", t.Name(), got, c.Stats())
+	}
+}
+
+func TestFetchDoesNotCacheErrors(t *testing.T) {
+	ctx := context.Background()
+	c := New(t.Name(), NewMemoryStore(10), time.Minute)
+
+	errLoad := errors.New("load failed")
+	if _, err := Fetch(ctx, c, "k", func() (thing, error) { return thing{}, errLoad }); !errors.Is(err, errLoad) {
+		t.Fatalf("Fetch() error = %v, want %v", err, errLoad)
+	}
+
+	got, err := Fetch(ctx, c, "k", func() (thing, error) { return thing{Name: "a"}, nil })
+	if err != nil || got.Name != "a" {
+		t.Errorf("Fetch() after a failed load = %+v, %v, want name a", got, err)
+	}
+}
+
+func TestFetchLoadsWhenTheStoreFails(t *testing.T) {
+	ctx := context.Background()
+	c := New(t.Name(), failingStore{}, time.Minute)
+
+	got, err := Fetch(ctx, c, "k", func() (thing, error) { return thing{Name: "a"}, nil })
+	if err != nil || got.Name != "a" {
+		t.Fatalf("Fetch() = %+v, %v, want name a", got, err)
+	}
+	if stats := c.Stats(); stats.Misses != 1 || stats.Errors == 0 {
+		t.Errorf("Stats() = %+v, want a miss and the store errors", stats)
+	}
+}
+
+func TestFetchSharesConcurrentLoads(t *testing.T) {
+	ctx := context.Background()
+	store := &countingStore{MemoryStore: NewMemoryStore(10)}
+	c := New(t.Name(), store, time.Minute)
+
+	const callers = 10
+	var loads atomic.Int64
+	release := make(chan struct{})
+	load := func() (thing, error) {
+		loads.Add(1)
+		<-release
+		return thing{Name: "a"}, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]thing, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = Fetch(ctx, c, "k", load)
+		}(i)
+	}
+
+	// Let every caller miss and join the load before it returns
+	for store.misses.Load() < callers {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := loads.Load(); n != 1 {
+		t.Errorf("load ran %d times, want 1", n)
+	}
+	for i, got := range results {
+		if got.Name != "a" {
+			t.Errorf("caller %d got %+v, want name a", i, got)
+		}
+	}
+}
+
+func TestFetchDropsValuesLoadedAcrossAnInvalidation(t *testing.T) {
+	ctx := context.Background()
+	c := New(t.Name(), NewMemoryStore(10), time.Minute)
+
+	// The write commits and invalidates while the stale value is being loaded
+	got, err := Fetch(ctx, c, "k", func() (thing, error) {
+		c.Invalidate(ctx, []string{"k"})
+		return thing{Name: "stale"}, nil
+	})
+	if err != nil || got.Name != "stale" {
+		t.Fatalf("Fetch() = %+v, %v, want the loaded value", got, err)
+	}
+
+	got, err = Fetch(ctx, c, "k", func() (thing, error) { return thing{Name: "fresh"}, nil })
+	if err != nil {
+		t.Fatalf("Fetch() failed: %v", err)
+	}
+	if got.Name != "fresh" {
+		t.Errorf("Fetch() after the invalidation = %+v, want the value loaded again", got)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(10)
+	c := New(t.Name(), store, time.Minute)
+
+	if _, err := Fetch(ctx, c, "a", func() (thing, error) { return thing{Name: "a"}, nil }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Fetch(ctx, c, "b", func() (thing, error) { return thing{Name: "b"}, nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Invalidate(ctx, []string{"a"}, "list")
+
+	if _, ok, _ := store.Get(ctx, "a"); ok {
+		t.Error("a is still cached")
+	}
+	if _, ok, _ := store.Get(ctx, "b"); !ok {
+		t.Error("b was removed")
+	}
+	if v := c.Version(ctx, "list"); v != 1 {
+		t.Errorf("Version(list) = %d, want 1", v)
+	}
+}
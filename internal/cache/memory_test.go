@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(2)
+
+	_ = s.Set(ctx, "a", []byte("1"), time.Minute)
+	_ = s.Set(ctx, "b", []byte("2"), time.Minute)
+	// Reading a makes b the least recently used
+	if _, ok, _ := s.Get(ctx, "a"); !ok {
+		t.Fatal("a is missing")
+	}
+	_ = s.Set(ctx, "c", []byte("3"), time.Minute)
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok, _ := s.Get(ctx, key); ok != want {
+			t.Errorf("Get(%q) found = %v, want %v", key, ok, want)
+		}
+	}
+}
+
+func TestMemoryStoreSetReplaces(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(2)
+
+	_ = s.Set(ctx, "a", []byte("1"), time.Minute)
+	_ = s.Set(ctx, "b", []byte("2"), time.Minute)
+	_ = s.Set(ctx, "a", []byte("3"), time.Minute)
+
+	if got, _, _ := s.Get(ctx, "a"); string(got) != "3" {
+		t.Errorf("Get(a) = %q, want 3", got)
+	}
+	if _, ok, _ := s.Get(ctx, "b"); !ok {
+		t.Error("replacing a evicted b")
+	}
+}
+
+func TestMemoryStoreExpires(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(2)
+
+	_ = s.Set(ctx, "a", []byte("1"), -time.Second)
+	if _, ok, _ := s.Get(ctx, "a"); ok {
+		t.Error("expired entry was returned")
+	}
+	if n := s.order.Len(); n != 0 {
+		t.Errorf("store holds %d entries, want the expired one removed", n)
+	}
+}
+
+func TestMemoryStoreDelete(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(3)
+
+	_ = s.Set(ctx, "a", []byte("1"), time.Minute)
+	_ = s.Set(ctx, "b", []byte("2"), time.Minute)
+	_ = s.Delete(ctx, "a", "missing")
+
+	if _, ok, _ := s.Get(ctx, "a"); ok {
+		t.Error("a is still stored")
+	}
+	if _, ok, _ := s.Get(ctx, "b"); !ok {
+		t.Error("b was removed")
+	}
+}
+
+func TestMemoryStoreVersionsAreNotEvicted(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(1)
+
+	_ = s.Bump(ctx, "v")
+	_ = s.Bump(ctx, "v")
+	_ = s.Set(ctx, "a", []byte("1"), time.Minute)
+	_ = s.Set(ctx, "b", []byte("2"), time.Minute)
+
+	if v, _ := s.Version(ctx, "v"); v != 2 {
+		t.Errorf("Version(v) = %d, want 2", v)
+	}
+	if v, _ := s.Version(ctx, "other"); v != 0 {
+		t.Errorf("Version(other) = %d, want 0", v)
+	}
+}
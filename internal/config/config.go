@@ -1,85 +1,320 @@
 package config
 
 import (
-	"fmt"
-	"log"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/raytr/go-template/internal/flags"
+	"github.com/raytr/go-template/internal/ratelimit"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Database DatabaseConfig
-	Server   ServerConfig
-	App      AppConfig
+	Database     DatabaseConfig     `mapstructure:"database"`
+	Server       ServerConfig       `mapstructure:"server"`
+	App          AppConfig          `mapstructure:"app"`
+	Auth         AuthConfig         `mapstructure:"auth"`
+	RateLimit    RateLimitConfig    `mapstructure:"rate_limit"`
+	Outbox       OutboxConfig       `mapstructure:"outbox"`
+	Webhook      WebhookConfig      `mapstructure:"webhook"`
+	Validation   ValidationConfig   `mapstructure:"validation"`
+	Users        UsersConfig        `mapstructure:"users"`
+	Idempotency  IdempotencyConfig  `mapstructure:"idempotency"`
+	Cache        CacheConfig        `mapstructure:"cache"`
+	FeatureFlags FeatureFlagsConfig `mapstructure:"feature_flags"`
+	Migration    MigrationConfig    `mapstructure:"migration"`
+
+	// source and files let a Reloader build the configuration again
+	source *source
+	files  []string
 }
 
 type DatabaseConfig struct {
-	URL string
+	// URL is a complete connection URL. The components below are an alternative
+	// that keeps the password out of any URL string.
+	URL      Secret `mapstructure:"url" validate:"required_without=Host,excluded_with=Host,postgres_url"`
+	Host     string `mapstructure:"host" validate:"required_without=URL"`
+	Port     int    `mapstructure:"port" validate:"min=1,max=65535"`
+	User     string `mapstructure:"user" validate:"required_with=Host"`
+	Password Secret `mapstructure:"password"`
+	Name     string `mapstructure:"name" validate:"required_with=Host"`
+	SSLMode  string `mapstructure:"sslmode" validate:"omitempty,oneof=disable allow prefer require verify-ca verify-full"`
+	LogLevel string `mapstructure:"log_level" reload:"true" validate:"oneof=silent error warn info"`
+}
+
+// DSN returns the connection string for the database, built from the URL
+// components when no URL is set
+func (c DatabaseConfig) DSN() Secret {
+	if c.URL != "" {
+		return c.URL
+	}
+
+	u := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(c.User, c.Password.Value()),
+		Host:   net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+		Path:   "/" + c.Name,
+	}
+	if c.Password == "" {
+		u.User = url.User(c.User)
+	}
+	if c.SSLMode != "" {
+		u.RawQuery = url.Values{"sslmode": {c.SSLMode}}.Encode()
+	}
+	return Secret(u.String())
 }
 
 type ServerConfig struct {
-	Port    int
-	Host    string
-	GinMode string
+	Port            int           `mapstructure:"port" validate:"required,min=1,max=65535"`
+	Host            string        `mapstructure:"host" validate:"required"`
+	GinMode         string        `mapstructure:"gin_mode" env:"GIN_MODE" validate:"oneof=debug release test"`
+	CORSOrigins     []string      `mapstructure:"cors_origins" reload:"true" validate:"dive,origin"`
+	ReadTimeout     time.Duration `mapstructure:"read_timeout" validate:"gt=0"`
+	WriteTimeout    time.Duration `mapstructure:"write_timeout" validate:"gt=0"`
+	IdleTimeout     time.Duration `mapstructure:"idle_timeout" validate:"gt=0"`
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout" validate:"gt=0"`
+	// MaxBodyBytes is the largest request body accepted, larger ones get a 413
+	MaxBodyBytes int64 `mapstructure:"max_body_bytes" validate:"gt=0"`
 }
 
 type AppConfig struct {
-	Name string
-	Env  string
+	Name string `mapstructure:"name"`
+	Env  string `mapstructure:"env" validate:"omitempty,oneof=development test staging production"`
+	// WatchConfig reloads the settings tagged reload:"true" when the config file changes
+	WatchConfig bool `mapstructure:"watch_config"`
+}
+
+type AuthConfig struct {
+	JWTSecret Secret `mapstructure:"jwt_secret" env:"JWT_SECRET" validate:"required"`
+}
+
+type RateLimitConfig struct {
+	Enabled  bool                      `mapstructure:"enabled"`
+	Backend  string                    `mapstructure:"backend" validate:"oneof=memory postgres redis"`
+	RedisURL Secret                    `mapstructure:"redis_url" validate:"required_if=Backend redis,redis_url"`
+	Default  *ratelimit.Rule           `mapstructure:"default" reload:"true"`
+	Routes   map[string]ratelimit.Rule `mapstructure:"routes" reload:"true"`
+	IdleTTL  time.Duration             `mapstructure:"idle_ttl" validate:"gt=0"`
+}
+
+type OutboxConfig struct {
+	Sink       string `mapstructure:"sink" validate:"oneof=log http broker"`
+	WebhookURL string `mapstructure:"webhook_url" validate:"required_if=Sink http,http_url"`
+	// BrokerURL is the Redis server whose streams the broker sink appends to
+	BrokerURL Secret `mapstructure:"broker_url" validate:"required_if=Sink broker,redis_url"`
+	// SubjectPrefix names the stream of each event type, as in <prefix>.user.created
+	SubjectPrefix string `mapstructure:"subject_prefix" validate:"required_if=Sink broker"`
+	// StreamMaxLen trims each stream to about this many events, 0 for no limit
+	StreamMaxLen int64         `mapstructure:"stream_max_len" validate:"gte=0"`
+	PollInterval time.Duration `mapstructure:"poll_interval" validate:"gt=0"`
+	BatchSize    int           `mapstructure:"batch_size" validate:"min=1,max=1000"`
+	MaxAttempts  int           `mapstructure:"max_attempts" validate:"min=1"`
+	// LeaseTimeout is how long a claimed batch is hidden from other relays
+	LeaseTimeout time.Duration `mapstructure:"lease_timeout" validate:"gt=0"`
+}
+
+type WebhookConfig struct {
+	PollInterval time.Duration `mapstructure:"poll_interval" validate:"gt=0"`
+	MaxAttempts  int           `mapstructure:"max_attempts" validate:"min=1"`
+	Timeout      time.Duration `mapstructure:"timeout" validate:"gt=0"`
+	// LeaseTimeout is how long a claimed batch is hidden from other dispatchers
+	LeaseTimeout time.Duration `mapstructure:"lease_timeout" validate:"gt=0"`
+	// AllowPrivateTargets accepts loopback, link-local and private webhook URLs,
+	// e.g. for a receiver on localhost during development
+	AllowPrivateTargets bool `mapstructure:"allow_private_targets"`
+}
+
+type ValidationConfig struct {
+	Requests  bool `mapstructure:"requests" env:"OPENAPI_VALIDATE_REQUESTS"`
+	Responses bool `mapstructure:"responses" env:"OPENAPI_VALIDATE_RESPONSES"`
+}
+
+type UsersConfig struct {
+	PhoneRegion         string   `mapstructure:"phone_region" env:"USER_PHONE_REGION" validate:"phone_region"`
+	CodePattern         string   `mapstructure:"code_pattern" env:"USER_CODE_PATTERN" validate:"regexp"`
+	AllowedEmailDomains []string `mapstructure:"allowed_email_domains" env:"USER_EMAIL_ALLOWED_DOMAINS" validate:"dive,fqdn"`
+	DeniedEmailDomains  []string `mapstructure:"denied_email_domains" env:"USER_EMAIL_DENIED_DOMAINS" validate:"dive,fqdn"`
+}
+
+type IdempotencyConfig struct {
+	TTL           time.Duration `mapstructure:"ttl" validate:"gt=0"`
+	LockTimeout   time.Duration `mapstructure:"lock_timeout" validate:"gt=0"`
+	SweepInterval time.Duration `mapstructure:"sweep_interval" validate:"gt=0"`
+}
+
+type CacheConfig struct {
+	Backend  string        `mapstructure:"backend" validate:"oneof=none memory redis"`
+	RedisURL Secret        `mapstructure:"redis_url" validate:"required_if=Backend redis,redis_url"`
+	TTL      time.Duration `mapstructure:"ttl" validate:"required_unless=Backend none,omitempty,gt=0"`
+	Size     int           `mapstructure:"size" validate:"required_if=Backend memory,omitempty,min=1"`
+}
+
+type FeatureFlagsConfig struct {
+	// Definitions are the flags by key. Flags stored in the database replace them.
+	Definitions     map[string]flags.Flag `mapstructure:"definitions" reload:"true" validate:"dive,keys,flag_key,endkeys,required"`
+	RefreshInterval time.Duration         `mapstructure:"refresh_interval" validate:"gt=0"`
+	// HeaderOverrides lets any caller override flags with X-Feature-Flags
+	HeaderOverrides bool `mapstructure:"header_overrides"`
+}
+
+type MigrationConfig struct {
+	// Dir reads migrations from a directory instead of the files embedded in the binary
+	Dir string `mapstructure:"dir" env:"MIGRATIONS_DIR" validate:"omitempty,dir"`
+	// Verbose logs every step of golang-migrate
+	Verbose bool `mapstructure:"verbose"`
+	// OnStart applies pending migrations (migrate), refuses to start while any
+	// are pending (verify) or ignores them (skip)
+	OnStart string `mapstructure:"on_start" validate:"oneof=migrate verify skip"`
+	// LockTimeout bounds the wait while another instance migrates
+	LockTimeout time.Duration `mapstructure:"lock_timeout" validate:"gt=0"`
+	// StatementTimeout cancels a migration running longer, 0 for no limit
+	StatementTimeout time.Duration `mapstructure:"statement_timeout" validate:"gte=0"`
+	// WaitTimeout is how long verify waits for another instance to migrate
+	WaitTimeout time.Duration `mapstructure:"wait_timeout" validate:"gte=0"`
 }
 
 var cfg *Config
 
-// Load reads configuration from .env file using Viper
-func Load() (*Config, error) {
-	v := viper.New()
+// setDefaults registers the built-in defaults, the lowest layer
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("database.port", 5432)
+	v.SetDefault("database.log_level", "warn")
+	v.SetDefault("server.gin_mode", "debug")
+	v.SetDefault("server.read_timeout", "15s")
+	v.SetDefault("server.write_timeout", "30s")
+	v.SetDefault("server.idle_timeout", "1m")
+	v.SetDefault("server.shutdown_timeout", "10s")
+	v.SetDefault("server.max_body_bytes", 1<<20)
+	v.SetDefault("rate_limit.backend", "memory")
+	v.SetDefault("rate_limit.idle_ttl", "10m")
+	v.SetDefault("outbox.sink", "log")
+	v.SetDefault("outbox.subject_prefix", "events")
+	v.SetDefault("outbox.stream_max_len", 100000)
+	v.SetDefault("outbox.poll_interval", "2s")
+	v.SetDefault("outbox.batch_size", 50)
+	v.SetDefault("outbox.max_attempts", 10)
+	v.SetDefault("outbox.lease_timeout", "5m")
+	v.SetDefault("webhook.poll_interval", "2s")
+	v.SetDefault("webhook.max_attempts", 8)
+	v.SetDefault("webhook.timeout", "10s")
+	v.SetDefault("webhook.lease_timeout", "5m")
+	v.SetDefault("webhook.allow_private_targets", false)
+	v.SetDefault("validation.requests", true)
+	v.SetDefault("idempotency.ttl", "24h")
+	v.SetDefault("idempotency.lock_timeout", "1m")
+	v.SetDefault("idempotency.sweep_interval", "1h")
+	v.SetDefault("cache.backend", "memory")
+	v.SetDefault("cache.ttl", "1m")
+	v.SetDefault("cache.size", 10000)
+	v.SetDefault("feature_flags.refresh_interval", "30s")
+	v.SetDefault("migration.on_start", "migrate")
+	v.SetDefault("migration.lock_timeout", "1m")
+}
 
-	// Set the file name and path
-	v.SetConfigFile(".env")
-	v.SetConfigType("env")
+// Load builds the configuration from these layers, each overriding the previous:
+// built-in defaults, the config file, its config.{APP_ENV} override, environment
+// variables (a .env file fills in the ones that are not set, and <VAR>_FILE reads
+// a variable from a file) and the flags in args.
+// Flags are registered on flags, which holds the remaining arguments afterwards.
+// Flags end at the first argument that is not one, so that a command such as
+// "migrate steps -1" keeps its own arguments.
+// When only the validation fails, the configuration is returned with the
+// *ValidationError, for commands such as "migrate list" that need few settings.
+func Load(flags *pflag.FlagSet, args []string) (*Config, error) {
+	flags.SetInterspersed(false)
+	keys := settingKeys(reflect.TypeOf(Config{}), "")
+	configFile := flags.String("config", "", "config file (default config.yaml, .yml or .toml; env CONFIG_FILE)")
+	for _, key := range keys {
+		flags.String(key.name, "", "overrides "+key.env)
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
 
-	// AutomaticEnv will check for an environment variable any time
-	// a viper.Get request is made
-	v.AutomaticEnv()
+	if err := loadDotEnv(".env"); err != nil {
+		return nil, err
+	}
 
-	// Replace . with _ in environment variable names
-	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	path := *configFile
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
 
-	// Read the config file
-	if err := v.ReadInConfig(); err != nil {
-		log.Printf("Error reading config file: %v", err)
+	loaded, err := (&source{flags: flags, keys: keys, path: path}).build()
+	if err != nil {
+		return loaded, err
 	}
 
-	cfg = &Config{
-		Database: DatabaseConfig{
-			URL: v.GetString("DATABASE_URL"),
-		},
-		Server: ServerConfig{
-			Port:    v.GetInt("SERVER_PORT"),
-			Host:    v.GetString("SERVER_HOST"),
-			GinMode: v.GetString("GIN_MODE"),
-		},
-		App: AppConfig{
-			Name: v.GetString("APP_NAME"),
-			Env:  v.GetString("APP_ENV"),
-		},
+	cfg = loaded
+	return cfg, nil
+}
+
+// source rebuilds the configuration from its layers. Flags are parsed once by
+// Load; files and variables are read again on every build.
+type source struct {
+	flags *pflag.FlagSet
+	keys  []settingKey
+	// path is the config file given explicitly, empty to look for the defaults
+	path string
+}
+
+// build reads every layer into a new Config
+func (s *source) build() (*Config, error) {
+	v := viper.New()
+	setDefaults(v)
+
+	for _, key := range s.keys {
+		if err := v.BindEnv(key.name, key.env); err != nil {
+			return nil, err
+		}
+		if err := v.BindPFlag(key.name, s.flags.Lookup(key.name)); err != nil {
+			return nil, err
+		}
 	}
 
-	// Validate required configurations
-	if cfg.Database.URL == "" {
-		return nil, fmt.Errorf("DATABASE_URL is required")
+	files, err := readConfigFiles(v, s.path)
+	if err != nil {
+		return nil, err
+	}
+	if err := readEnvFiles(v, s.keys); err != nil {
+		return nil, err
 	}
 
-	if cfg.Server.Port == 0 {
-		return nil, fmt.Errorf("SERVER_PORT is required")
+	// Values that cannot be decoded are reported together with the rule violations
+	c := &Config{source: s, files: files}
+	decodeErr := v.Unmarshal(c, viper.DecodeHook(decodeHook))
+
+	// Response validation only logs, so it defaults to on outside production
+	if !v.IsSet("validation.responses") {
+		c.Validation.Responses = c.App.Env == "development" || c.App.Env == "test"
+	}
+	// Header overrides are meant for testing, so they default to on there too
+	if !v.IsSet("feature_flags.header_overrides") {
+		c.FeatureFlags.HeaderOverrides = c.App.Env == "development" || c.App.Env == "test"
 	}
 
-	if cfg.Server.Host == "" {
-		return nil, fmt.Errorf("SERVER_HOST is required")
+	if err := collectProblems(c, decodeErr); err != nil {
+		return c, err
 	}
 
-	return cfg, nil
+	return c, nil
+}
+
+// splitList parses a comma-separated list, skipping empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
 
 // // Get returns the loaded configuration
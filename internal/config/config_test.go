@@ -0,0 +1,142 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+func TestLoadLeavesCommandArguments(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"negative steps", []string{"migrate", "steps", "-1"}, []string{"migrate", "steps", "-1"}},
+		{"force none", []string{"migrate", "force", "-1"}, []string{"migrate", "force", "-1"}},
+		{"flags before the command", []string{"--server.port", "9090", "migrate", "steps", "-2"}, []string{"migrate", "steps", "-2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DATABASE_URL", "postgres://user@localhost:5432/app")
+			t.Setenv("JWT_SECRET", "test-secret")
+			t.Setenv("SERVER_HOST", "127.0.0.1")
+			t.Setenv("SERVER_PORT", "8080")
+
+			flags := pflag.NewFlagSet("app", pflag.ContinueOnError)
+			if _, err := Load(flags, tt.args); err != nil {
+				t.Fatalf("Load(%q) failed: %v", tt.args, err)
+			}
+
+			got := flags.Args()
+			if len(got) != len(tt.want) {
+				t.Fatalf("Load(%q) left %q, want %q", tt.args, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Load(%q) left %q, want %q", tt.args, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadReturnsInvalidConfiguration(t *testing.T) {
+	t.Setenv("DATABASE_URL", "")
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("SERVER_HOST", "127.0.0.1")
+	t.Setenv("SERVER_PORT", "8080")
+	dir := t.TempDir()
+	t.Setenv("MIGRATIONS_DIR", dir)
+
+	cfg, err := Load(pflag.NewFlagSet("app", pflag.ContinueOnError), []string{"migrate", "list"})
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("Load returned %v, want a *ValidationError", err)
+	}
+	if cfg == nil || cfg.Migration.Dir != dir {
+		t.Fatalf("Load returned %+v, want the configuration with migration.dir %q", cfg, dir)
+	}
+}
+
+func TestReloadLetsSubscribersReadCurrent(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://user@localhost:5432/app")
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("SERVER_HOST", "127.0.0.1")
+	t.Setenv("SERVER_PORT", "8080")
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "database:\n  log_level: warn\n")
+
+	cfg, err := Load(pflag.NewFlagSet("app", pflag.ContinueOnError), []string{"--config", path})
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	reloader := NewReloader(cfg)
+	seen := make(chan string, 1)
+	reloader.Subscribe(func(*Config) {
+		seen <- reloader.Current().Database.LogLevel
+	})
+
+	writeFile(t, path, "database:\n  log_level: info\n")
+	done := make(chan error, 1)
+	go func() { done <- reloader.Reload() }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Reload failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Reload did not return, a subscriber calling Current deadlocked")
+	}
+	if got := <-seen; got != "info" {
+		t.Fatalf("subscriber saw log_level %q, want %q", got, "info")
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func TestCacheSettingsDependOnBackend(t *testing.T) {
+	tests := []struct {
+		backend, ttl, size string
+		wantErr            bool
+	}{
+		{"none", "0s", "0", false},
+		{"memory", "1m", "100", false},
+		{"memory", "0s", "100", true},
+		{"memory", "-1m", "100", true},
+		{"memory", "1m", "0", true},
+		{"redis", "1m", "0", false},
+		{"redis", "0s", "0", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.backend+" "+tt.ttl+" "+tt.size, func(t *testing.T) {
+			t.Setenv("DATABASE_URL", "postgres://user@localhost:5432/app")
+			t.Setenv("JWT_SECRET", "test-secret")
+			t.Setenv("SERVER_HOST", "127.0.0.1")
+			t.Setenv("SERVER_PORT", "8080")
+			t.Setenv("CACHE_BACKEND", tt.backend)
+			t.Setenv("CACHE_REDIS_URL", "redis://localhost:6379/0")
+			t.Setenv("CACHE_TTL", tt.ttl)
+			t.Setenv("CACHE_SIZE", tt.size)
+
+			_, err := Load(pflag.NewFlagSet("app", pflag.ContinueOnError), nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
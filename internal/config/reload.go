@@ -0,0 +1,161 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay lets editors finish writing a file before it is read
+const reloadDelay = 200 * time.Millisecond
+
+// Reloader builds the configuration again when its files change and passes the
+// settings tagged reload:"true" to subscribers. A configuration that fails to
+// load or validate is rejected and the current one is kept.
+type Reloader struct {
+	// reloading serializes reloads so subscribers see them in order
+	reloading   sync.Mutex
+	mu          sync.Mutex
+	current     *Config
+	subscribers []func(*Config)
+}
+
+// NewReloader creates a reloader starting from a configuration returned by Load
+func NewReloader(current *Config) *Reloader {
+	return &Reloader{
+		current: current,
+	}
+}
+
+// Subscribe registers fn to be called with the new configuration after each
+// reload that changes a reloadable setting
+func (r *Reloader) Subscribe(fn func(*Config)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.subscribers = append(r.subscribers, fn)
+}
+
+// Current returns the configuration in effect
+func (r *Reloader) Current() *Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.current
+}
+
+// Reload builds the configuration again and publishes the reloadable settings
+// that changed. Changes to the other settings are logged and left for a restart.
+// Subscribers are called without the lock held, so they may call Current.
+func (r *Reloader) Reload() error {
+	r.reloading.Lock()
+	defer r.reloading.Unlock()
+
+	current := r.Current()
+	if current.source == nil {
+		return fmt.Errorf("configuration was not created by Load")
+	}
+
+	next, err := current.source.build()
+	if err != nil {
+		return err
+	}
+
+	merged := *current
+	var applied, ignored []string
+	for _, key := range current.source.keys {
+		old := reflect.ValueOf(current).Elem().FieldByIndex(key.index)
+		changed := reflect.ValueOf(next).Elem().FieldByIndex(key.index)
+		if reflect.DeepEqual(old.Interface(), changed.Interface()) {
+			continue
+		}
+
+		if !key.reload {
+			ignored = append(ignored, key.name)
+			continue
+		}
+		reflect.ValueOf(&merged).Elem().FieldByIndex(key.index).Set(changed)
+		applied = append(applied, key.name)
+	}
+
+	if len(ignored) > 0 {
+		log.Printf("Config changes that need a restart were not applied: %s", strings.Join(ignored, ", "))
+	}
+	if len(applied) == 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	r.current = &merged
+	subscribers := append([]func(*Config){}, r.subscribers...)
+	r.mu.Unlock()
+
+	log.Printf("Config reloaded: %s", strings.Join(applied, ", "))
+	for _, fn := range subscribers {
+		fn(&merged)
+	}
+
+	return nil
+}
+
+// Run reloads the configuration whenever one of its files changes, until ctx
+// is done. It returns at once when no config file was read.
+func (r *Reloader) Run(ctx context.Context) {
+	files := r.Current().files
+	if len(files) == 0 {
+		log.Println("Config watch enabled but no config file was read")
+		return
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("Config watch error: %v", err)
+		return
+	}
+	defer watcher.Close()
+
+	// Watch the directories so that files replaced by a rename are still seen.
+	// Kubernetes updates mounted ConfigMaps by swapping their ..data link.
+	watched := make(map[string]bool)
+	for _, file := range files {
+		watched[filepath.Clean(file)] = true
+		if err := watcher.Add(filepath.Dir(file)); err != nil {
+			log.Printf("Config watch error: %v", err)
+			return
+		}
+	}
+
+	timer := time.NewTimer(reloadDelay)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if watched[filepath.Clean(event.Name)] || strings.Contains(event.Name, "..data") {
+				timer.Reset(reloadDelay)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Config watch error: %v", err)
+		case <-timer.C:
+			if err := r.Reload(); err != nil {
+				log.Printf("Config reload rejected, keeping the current configuration: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"encoding/json"
+	"log/slog"
+)
+
+// redacted replaces the value of a secret in output
+const redacted = "[REDACTED]"
+
+// Secret is a string setting that must not be printed. fmt, JSON and slog
+// output show [REDACTED] instead; Value returns the secret itself.
+type Secret string
+
+// Value returns the secret
+func (s Secret) Value() string {
+	return string(s)
+}
+
+// String redacts the secret. An empty secret prints as an empty string so that
+// missing settings remain visible.
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// GoString redacts the secret in %#v output
+func (s Secret) GoString() string {
+	return `config.Secret("` + s.String() + `")`
+}
+
+// MarshalJSON redacts the secret in JSON output
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// LogValue redacts the secret in slog output
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.String())
+}
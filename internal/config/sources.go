@@ -0,0 +1,223 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/raytr/go-template/internal/flags"
+	"github.com/raytr/go-template/internal/ratelimit"
+	"github.com/spf13/viper"
+	"github.com/subosito/gotenv"
+)
+
+// defaultConfigFiles are looked up in the working directory when no config file is given
+var defaultConfigFiles = []string{"config.yaml", "config.yml", "config.toml"}
+
+// settingKey is a leaf of the Config tree
+type settingKey struct {
+	// name is the dotted key used in config files and as the flag name
+	name string
+	// env is the environment variable that sets the key
+	env string
+	// index locates the field in Config
+	index []int
+	// reload is set for the settings that a Reloader may change at runtime
+	reload bool
+}
+
+// settingKeys lists the leaves of a config struct. Keys are built from the
+// mapstructure tags; the variable defaults to the upper-cased key with dots
+// replaced by underscores unless an env tag names it. Fields tagged
+// reload:"true" can be reloaded.
+func settingKeys(t reflect.Type, prefix string, index ...int) []settingKey {
+	var keys []settingKey
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("mapstructure")
+		if name == "" || name == "-" {
+			continue
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+
+		fieldIndex := append(append([]int(nil), index...), i)
+		if field.Type.Kind() == reflect.Struct {
+			keys = append(keys, settingKeys(field.Type, name, fieldIndex...)...)
+			continue
+		}
+
+		env := field.Tag.Get("env")
+		if env == "" {
+			env = strings.ToUpper(strings.ReplaceAll(name, ".", "_"))
+		}
+		keys = append(keys, settingKey{name: name, env: env, index: fieldIndex, reload: field.Tag.Get("reload") == "true"})
+	}
+
+	return keys
+}
+
+// loadDotEnv exports the variables of a .env file that are not already set
+func loadDotEnv(path string) error {
+	if err := gotenv.Load(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return nil
+}
+
+// readConfigFiles reads the config file and merges its config.{APP_ENV} override
+// next to it. An explicit path must exist; without one the default files are
+// optional, as is the override. It returns the paths of both files, whether or
+// not the override exists, or none when there is no config file.
+func readConfigFiles(v *viper.Viper, path string) ([]string, error) {
+	if path == "" {
+		for _, name := range defaultConfigFiles {
+			if _, err := os.Stat(name); err == nil {
+				path = name
+				break
+			}
+		}
+	}
+	if path == "" {
+		return nil, nil
+	}
+
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	// APP_ENV may come from any layer, including the file just read
+	env := v.GetString("app.env")
+	if env == "" {
+		return []string{path}, nil
+	}
+
+	ext := filepath.Ext(path)
+	override := strings.TrimSuffix(path, ext) + "." + env + ext
+	files := []string{path, override}
+	if _, err := os.Stat(override); errors.Is(err, fs.ErrNotExist) {
+		return files, nil
+	}
+
+	v.SetConfigFile(override)
+	if err := v.MergeInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", override, err)
+	}
+
+	return files, nil
+}
+
+// readEnvFiles applies the <VAR>_FILE variables, which name a file holding the
+// value of <VAR>, as mounted by container secret stores. Setting both is an error.
+// It must run after the config files are read, as they reset the values.
+func readEnvFiles(v *viper.Viper, keys []settingKey) error {
+	values := make(map[string]interface{})
+
+	for _, key := range keys {
+		path := os.Getenv(key.env + "_FILE")
+		if path == "" {
+			continue
+		}
+		if os.Getenv(key.env) != "" {
+			return fmt.Errorf("set only one of %s and %s_FILE", key.env, key.env)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("%s_FILE: %w", key.env, err)
+		}
+
+		// Nest the value under its dotted key, as in a config file
+		section := values
+		parts := strings.Split(key.name, ".")
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := section[part].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				section[part] = child
+			}
+			section = child
+		}
+		section[parts[len(parts)-1]] = strings.TrimRight(string(data), "\r\n")
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+	return v.MergeConfigMap(values)
+}
+
+var (
+	durationType   = reflect.TypeOf(time.Duration(0))
+	stringsType    = reflect.TypeOf([]string(nil))
+	ruleType       = reflect.TypeOf(ratelimit.Rule{})
+	rulePtrType    = reflect.TypeOf(&ratelimit.Rule{})
+	routeRulesType = reflect.TypeOf(map[string]ratelimit.Rule(nil))
+	flagsType      = reflect.TypeOf(map[string]flags.Flag(nil))
+)
+
+// decodeHook converts the strings found in environment variables, flags and
+// files into the types of Config. Empty strings decode to zero values, and
+// durations must be strings with a unit.
+var decodeHook = mapstructure.DecodeHookFuncType(func(from, to reflect.Type, data interface{}) (interface{}, error) {
+	// Viper lower-cases map keys, so restore the method of route rules
+	if routes, ok := data.(map[string]interface{}); ok && to == routeRulesType {
+		restored := make(map[string]interface{}, len(routes))
+		for route, rule := range routes {
+			method, path, _ := strings.Cut(strings.TrimSpace(route), " ")
+			restored[strings.ToUpper(method)+" "+strings.TrimSpace(path)] = rule
+		}
+		return restored, nil
+	}
+
+	// A bare number in a file would otherwise be read as nanoseconds
+	if to == durationType && from.Kind() != reflect.String {
+		return nil, fmt.Errorf("%v is not a duration: use a unit, e.g. \"30s\"", data)
+	}
+
+	s, ok := data.(string)
+	if !ok || from.Kind() != reflect.String {
+		return data, nil
+	}
+
+	switch to {
+	case durationType:
+		if s == "" {
+			return time.Duration(0), nil
+		}
+		return time.ParseDuration(s)
+	case stringsType:
+		return splitList(s), nil
+	case rulePtrType:
+		if strings.TrimSpace(s) == "" {
+			return nil, nil
+		}
+		return data, nil
+	case ruleType:
+		return ratelimit.ParseRule(s)
+	case routeRulesType:
+		return ratelimit.ParseRouteRules(s)
+	case flagsType:
+		// Flags are nested too deeply for a list, so variables hold JSON
+		if strings.TrimSpace(s) == "" {
+			return map[string]flags.Flag{}, nil
+		}
+		var definitions map[string]flags.Flag
+		if err := json.Unmarshal([]byte(s), &definitions); err != nil {
+			return nil, fmt.Errorf("invalid feature flags JSON: %w", err)
+		}
+		return definitions, nil
+	}
+
+	return data, nil
+})
@@ -0,0 +1,197 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/mitchellh/mapstructure"
+	"github.com/raytr/go-template/internal/flags"
+	"github.com/raytr/go-template/internal/phone"
+	"github.com/redis/go-redis/v9"
+)
+
+// ValidationError lists every problem found in a configuration
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// validate checks the rules declared in the validate tags of Config
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+
+	// Name fields by their config key
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return field.Tag.Get("mapstructure")
+	})
+
+	mustRegister(v, "postgres_url", func(fl validator.FieldLevel) bool {
+		if fl.Field().String() == "" {
+			return true
+		}
+		u, err := url.Parse(fl.Field().String())
+		return err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") && u.Host != ""
+	})
+	mustRegister(v, "redis_url", func(fl validator.FieldLevel) bool {
+		if fl.Field().String() == "" {
+			return true
+		}
+		_, err := redis.ParseURL(fl.Field().String())
+		return err == nil
+	})
+	mustRegister(v, "http_url", func(fl validator.FieldLevel) bool {
+		if fl.Field().String() == "" {
+			return true
+		}
+		u, err := url.Parse(fl.Field().String())
+		return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+	})
+	mustRegister(v, "origin", func(fl validator.FieldLevel) bool {
+		origin := fl.Field().String()
+		if origin == "*" {
+			return true
+		}
+		u, err := url.Parse(origin)
+		return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && u.Path == "" && u.RawQuery == ""
+	})
+	mustRegister(v, "flag_key", func(fl validator.FieldLevel) bool {
+		return flags.ValidKey(fl.Field().String())
+	})
+	mustRegister(v, "phone_region", func(fl validator.FieldLevel) bool {
+		return fl.Field().String() == "" || phone.SupportedRegion(strings.ToUpper(fl.Field().String()))
+	})
+	mustRegister(v, "regexp", func(fl validator.FieldLevel) bool {
+		_, err := regexp.Compile(fl.Field().String())
+		return err == nil
+	})
+
+	return v
+}
+
+func mustRegister(v *validator.Validate, tag string, fn validator.Func) {
+	if err := v.RegisterValidation(tag, fn); err != nil {
+		panic(err)
+	}
+}
+
+// Validate checks the whole configuration and reports every problem at once
+func (c *Config) Validate() error {
+	return collectProblems(c, nil)
+}
+
+// decodeKey finds the key named in a mapstructure error such as
+// "error decoding 'server.port': ..." or "cannot parse 'server.port' as int: ..."
+var decodeKey = regexp.MustCompile(`'([^']+)'`)
+
+// collectProblems reports the values that could not be decoded (decodeErr, from
+// viper) and the rule violations of c, in the order of the Config fields. A key
+// that failed to decode is not checked against its rules.
+func collectProblems(c *Config, decodeErr error) error {
+	found := make(map[string][]string)
+	var other []string
+
+	var mapErr *mapstructure.Error
+	switch {
+	case errors.As(decodeErr, &mapErr):
+		for _, msg := range mapErr.Errors {
+			match := decodeKey.FindStringSubmatch(msg)
+			if match == nil {
+				other = append(other, msg)
+				continue
+			}
+			msg = strings.TrimPrefix(msg, "error decoding "+match[0]+": ")
+			found[match[1]] = append(found[match[1]], msg)
+		}
+	case decodeErr != nil:
+		other = append(other, decodeErr.Error())
+	}
+
+	var fieldErrs validator.ValidationErrors
+	if err := validate.Struct(c); errors.As(err, &fieldErrs) {
+		for _, fe := range fieldErrs {
+			field := strings.TrimPrefix(fe.Namespace(), "Config.")
+			key, _, _ := strings.Cut(field, "[")
+			if _, failed := found[key]; failed && field == key {
+				continue
+			}
+			found[key] = append(found[key], field+": "+ruleMessage(fe))
+		}
+	} else if err != nil {
+		other = append(other, err.Error())
+	}
+
+	problems := other
+	for _, key := range settingKeys(reflect.TypeOf(Config{}), "") {
+		for _, msg := range found[key.name] {
+			if !strings.HasPrefix(msg, key.name) {
+				msg = key.name + ": " + msg
+			}
+			field, rest, _ := strings.Cut(msg, ": ")
+			problems = append(problems, fmt.Sprintf("%s (%s): %s", field, key.env, rest))
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: problems}
+}
+
+func ruleMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "required_if":
+		field, value, _ := strings.Cut(fe.Param(), " ")
+		return fmt.Sprintf("is required when %s is %s", strings.ToLower(field), value)
+	case "required_unless":
+		field, value, _ := strings.Cut(fe.Param(), " ")
+		return fmt.Sprintf("is required unless %s is %s", strings.ToLower(field), value)
+	case "required_without":
+		return "is required unless " + strings.ToLower(fe.Param()) + " is set"
+	case "required_with":
+		return "is required when " + strings.ToLower(fe.Param()) + " is set"
+	case "excluded_with":
+		return "cannot be combined with " + strings.ToLower(fe.Param())
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "min", "gte":
+		return "must be at least " + fe.Param()
+	case "max", "lte":
+		return "must be at most " + fe.Param()
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "len":
+		return fmt.Sprintf("must be %s characters long", fe.Param())
+	case "postgres_url":
+		return "must be a postgres:// or postgresql:// URL with a host"
+	case "redis_url":
+		return "must be a redis:// or rediss:// URL"
+	case "http_url":
+		return "must be an http:// or https:// URL with a host"
+	case "origin":
+		return "must be * or an origin such as https://app.example.com"
+	case "regexp":
+		return "must be a valid regular expression"
+	case "phone_region":
+		return "must be a region with phone number metadata, such as VN or US"
+	case "flag_key":
+		return "must be a flag key matching " + flags.KeyPattern
+	case "dir":
+		return "must be an existing directory"
+	case "fqdn":
+		return "must be a domain name"
+	default:
+		return "is invalid (" + fe.Tag() + ")"
+	}
+}
@@ -1,9 +1,15 @@
 package database
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/raytr/go-template/internal/config"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -11,18 +17,71 @@ import (
 
 var db *gorm.DB
 
+// logLevels maps database.log_level to GORM log levels. Info logs every
+// statement with its arguments.
+var logLevels = map[string]logger.LogLevel{
+	"silent": logger.Silent,
+	"error":  logger.Error,
+	"warn":   logger.Warn,
+	"info":   logger.Info,
+}
+
+// dbLogger is the GORM logger of the connection. Its level can change at runtime.
+var dbLogger = &levelLogger{}
+
+// SetLogLevel changes the GORM log level, one of the keys of logLevels
+func SetLogLevel(level string) {
+	dbLogger.level.Store(int32(logLevels[level]))
+}
+
+// levelLogger passes GORM logs to the default logger at the current level
+type levelLogger struct {
+	level atomic.Int32
+}
+
+func (l *levelLogger) current() logger.Interface {
+	return logger.Default.LogMode(logger.LogLevel(l.level.Load()))
+}
+
+// LogMode returns a logger fixed at level, as used by db.Debug()
+func (l *levelLogger) LogMode(level logger.LogLevel) logger.Interface {
+	return logger.Default.LogMode(level)
+}
+
+func (l *levelLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	l.current().Info(ctx, msg, data...)
+}
+
+func (l *levelLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	l.current().Warn(ctx, msg, data...)
+}
+
+func (l *levelLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	l.current().Error(ctx, msg, data...)
+}
+
+func (l *levelLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	l.current().Trace(ctx, begin, fc, err)
+}
+
 // Connect establishes a connection to the database using GORM
-func Connect(databaseURL string) error {
+func Connect(cfg config.DatabaseConfig) error {
 	var err error
 
 	// Configure GORM
-	config := &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+	SetLogLevel(cfg.LogLevel)
+	gormConfig := &gorm.Config{
+		Logger: dbLogger,
+		// TIMESTAMP columns keep no time zone, so every time is written in UTC
+		NowFunc: func() time.Time {
+			return time.Now().UTC()
+		},
 	}
 
-	db, err = gorm.Open(postgres.Open(databaseURL), config)
+	dsn := cfg.DSN()
+	db, err = gorm.Open(postgres.Open(dsn.Value()), gormConfig)
 	if err != nil {
-		return fmt.Errorf("failed to connect to database: %w", err)
+		return fmt.Errorf("failed to connect to database: %w", redact(err, dsn, cfg.Password))
 	}
 
 	// Get underlying SQL database to configure connection pool
@@ -33,7 +92,7 @@ func Connect(databaseURL string) error {
 
 	// Verify connection
 	if err := sqlDB.Ping(); err != nil {
-		return fmt.Errorf("failed to ping database: %w", err)
+		return fmt.Errorf("failed to ping database: %w", redact(err, dsn, cfg.Password))
 	}
 
 	// Configure connection pool
@@ -71,3 +130,17 @@ func AutoMigrate(models ...interface{}) error {
 	}
 	return db.AutoMigrate(models...)
 }
+
+// redact removes secrets from a driver error, which may quote the connection string
+func redact(err error, secrets ...config.Secret) error {
+	msg := err.Error()
+	for _, secret := range secrets {
+		if secret != "" {
+			msg = strings.ReplaceAll(msg, secret.Value(), secret.String())
+		}
+	}
+	if msg == err.Error() {
+		return err
+	}
+	return errors.New(msg)
+}
@@ -0,0 +1,438 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// typeAliases maps the type names of GORM tags and migrations to the udt_name
+// Postgres reports in information_schema
+var typeAliases = map[string]string{
+	"serial":                      "int4",
+	"bigserial":                   "int8",
+	"smallserial":                 "int2",
+	"int":                         "int4",
+	"integer":                     "int4",
+	"bigint":                      "int8",
+	"smallint":                    "int2",
+	"boolean":                     "bool",
+	"character varying":           "varchar",
+	"character":                   "bpchar",
+	"char":                        "bpchar",
+	"timestamp without time zone": "timestamp",
+	"timestamp with time zone":    "timestamptz",
+	"time without time zone":      "time",
+	"double precision":            "float8",
+	"float":                       "float8",
+	"real":                        "float4",
+	"decimal":                     "numeric",
+}
+
+// defaultTimePrecision is the precision of time types declared without one
+const defaultTimePrecision = "6"
+
+var spaces = regexp.MustCompile(`\s+`)
+
+// Drift is one difference between a model and the schema of the database
+type Drift struct {
+	Table string
+	// Object is the column or index that differs, empty when the table is missing
+	Object  string
+	Problem string
+}
+
+// liveColumn is a row of information_schema.columns
+type liveColumn struct {
+	ColumnName             string
+	UdtName                string
+	CharacterMaximumLength *int
+	NumericPrecision       *int
+	NumericScale           *int
+	DatetimePrecision      *int
+	IsNullable             string
+}
+
+// liveIndex is an index of pg_indexes with the columns parsed from its definition
+type liveIndex struct {
+	Name    string
+	Unique  bool
+	Columns []string
+}
+
+// ignoredTables are tables of the schema that no model is expected to cover
+var ignoredTables = map[string]bool{
+	// Kept by golang-migrate
+	"schema_migrations": true,
+}
+
+// CheckDrift compares the tables of the current schema with the models, as
+// AutoMigrate would create them, and returns every missing or extra column,
+// type, nullability and index difference. Many-to-many join tables are
+// checked too, and tables that no model covers are reported. It returns no
+// drift when they agree.
+func CheckDrift(db *gorm.DB, models ...interface{}) ([]Drift, error) {
+	tables, err := modelTables(db, models)
+	if err != nil {
+		return nil, err
+	}
+
+	var drifts []Drift
+	for _, table := range tables {
+		tableDrifts, err := checkTable(db, table)
+		if err != nil {
+			return nil, err
+		}
+		drifts = append(drifts, tableDrifts...)
+	}
+
+	var live []string
+	err = db.Raw(`SELECT table_name FROM information_schema.tables
+		WHERE table_schema = current_schema() AND table_type = 'BASE TABLE'
+		ORDER BY table_name`).Scan(&live).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tables: %w", err)
+	}
+	return append(drifts, checkTables(tables, live)...), nil
+}
+
+// modelTables parses the models and returns their tables, followed by their
+// many-to-many join tables, each once
+func modelTables(db *gorm.DB, models []interface{}) ([]*schema.Schema, error) {
+	var tables []*schema.Schema
+	seen := make(map[string]bool)
+
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, fmt.Errorf("failed to parse model %T: %w", model, err)
+		}
+
+		candidates := []*schema.Schema{stmt.Schema}
+		for _, rel := range stmt.Schema.Relationships.Many2Many {
+			candidates = append(candidates, rel.JoinTable)
+		}
+		for _, table := range candidates {
+			if !seen[table.Table] {
+				seen[table.Table] = true
+				tables = append(tables, table)
+			}
+		}
+	}
+	return tables, nil
+}
+
+// checkTables returns a drift for every live table that no model covers
+func checkTables(tables []*schema.Schema, live []string) []Drift {
+	modelled := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		modelled[table.Table] = true
+	}
+
+	var drifts []Drift
+	for _, name := range live {
+		if !modelled[name] && !ignoredTables[name] {
+			drifts = append(drifts, Drift{Table: name, Problem: "table is not in the model"})
+		}
+	}
+	return drifts
+}
+
+// checkTable compares one table with its model
+func checkTable(db *gorm.DB, table *schema.Schema) ([]Drift, error) {
+	var columns []liveColumn
+	err := db.Raw(`SELECT column_name, udt_name, character_maximum_length, numeric_precision,
+			numeric_scale, datetime_precision, is_nullable
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = ?
+		ORDER BY ordinal_position`, table.Table).Scan(&columns).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", table.Table, err)
+	}
+	if len(columns) == 0 {
+		return []Drift{{Table: table.Table, Problem: "table is missing"}}, nil
+	}
+
+	drifts := checkColumns(db, table, columns)
+
+	indexes, err := readIndexes(db, table.Table)
+	if err != nil {
+		return nil, err
+	}
+	return append(drifts, checkIndexes(table, indexes)...), nil
+}
+
+// checkColumns compares the columns of a table with the fields of its model
+func checkColumns(db *gorm.DB, table *schema.Schema, columns []liveColumn) []Drift {
+	var drifts []Drift
+	live := make(map[string]liveColumn, len(columns))
+	for _, c := range columns {
+		live[c.ColumnName] = c
+	}
+
+	for _, name := range table.DBNames {
+		field := table.FieldsByDBName[name]
+		if field.IgnoreMigration {
+			continue
+		}
+
+		column, ok := live[name]
+		if !ok {
+			drifts = append(drifts, Drift{Table: table.Table, Object: name, Problem: "column is missing"})
+			continue
+		}
+		delete(live, name)
+
+		want, got := canonicalType(db.Dialector.DataTypeOf(field)), column.canonicalType()
+		if want != got {
+			drifts = append(drifts, Drift{Table: table.Table, Object: name,
+				Problem: fmt.Sprintf("type is %s in the model, %s in the database", want, got)})
+		}
+
+		notNull := field.NotNull || field.PrimaryKey
+		if liveNotNull := column.IsNullable == "NO"; notNull != liveNotNull {
+			drifts = append(drifts, Drift{Table: table.Table, Object: name,
+				Problem: fmt.Sprintf("is %s in the model, %s in the database", nullability(notNull), nullability(liveNotNull))})
+		}
+	}
+
+	for _, c := range columns {
+		if _, extra := live[c.ColumnName]; extra {
+			drifts = append(drifts, Drift{Table: table.Table, Object: c.ColumnName, Problem: "column is not in the model"})
+		}
+	}
+	return drifts
+}
+
+// readIndexes returns the indexes of a table, except its primary key
+func readIndexes(db *gorm.DB, table string) ([]liveIndex, error) {
+	var rows []struct {
+		Indexname string
+		Indexdef  string
+	}
+	err := db.Raw(`SELECT i.indexname, i.indexdef
+		FROM pg_indexes i
+		WHERE i.schemaname = current_schema() AND i.tablename = ?
+			AND NOT EXISTS (
+				SELECT 1 FROM pg_constraint c
+				JOIN pg_namespace n ON n.oid = c.connamespace
+				WHERE c.contype = 'p' AND c.conname = i.indexname AND n.nspname = i.schemaname
+			)
+		ORDER BY i.indexname`, table).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to read indexes of %s: %w", table, err)
+	}
+
+	indexes := make([]liveIndex, len(rows))
+	for i, row := range rows {
+		indexes[i] = liveIndex{
+			Name:    row.Indexname,
+			Unique:  strings.HasPrefix(row.Indexdef, "CREATE UNIQUE INDEX"),
+			Columns: indexColumns(row.Indexdef),
+		}
+	}
+	return indexes, nil
+}
+
+// checkIndexes compares the indexes of a table with the index and unique tags
+// of its model. Indexes are matched by name, unique columns by their columns.
+func checkIndexes(table *schema.Schema, indexes []liveIndex) []Drift {
+	var drifts []Drift
+	live := make(map[string]liveIndex, len(indexes))
+	for _, index := range indexes {
+		live[index.Name] = index
+	}
+
+	expected := table.ParseIndexes()
+	names := make([]string, 0, len(expected))
+	for name := range expected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		index := expected[name]
+		unique := index.Class == "UNIQUE"
+		columns := make([]string, len(index.Fields))
+		for i, opt := range index.Fields {
+			column := opt.Expression
+			if column == "" {
+				column = opt.DBName
+			}
+			if opt.Sort != "" {
+				column += " " + opt.Sort
+			}
+			columns[i] = normalizeIndexColumn(column)
+		}
+
+		got, ok := live[name]
+		if !ok {
+			drifts = append(drifts, Drift{Table: table.Table, Object: name,
+				Problem: fmt.Sprintf("%s on (%s) is missing", indexKind(unique), strings.Join(columns, ", "))})
+			continue
+		}
+		delete(live, name)
+
+		if unique != got.Unique {
+			drifts = append(drifts, Drift{Table: table.Table, Object: name,
+				Problem: fmt.Sprintf("is %s in the model, %s in the database", uniqueness(unique), uniqueness(got.Unique))})
+		}
+		if want, have := strings.Join(columns, ", "), strings.Join(got.Columns, ", "); want != have {
+			drifts = append(drifts, Drift{Table: table.Table, Object: name,
+				Problem: fmt.Sprintf("covers (%s) in the model, (%s) in the database", want, have)})
+		}
+	}
+
+	// Unique columns get a constraint named by Postgres
+	for _, name := range table.DBNames {
+		if !table.FieldsByDBName[name].Unique {
+			continue
+		}
+		found := false
+		for _, index := range indexes {
+			if _, unused := live[index.Name]; unused && index.Unique && len(index.Columns) == 1 && index.Columns[0] == name {
+				delete(live, index.Name)
+				found = true
+				break
+			}
+		}
+		if !found {
+			drifts = append(drifts, Drift{Table: table.Table, Object: name, Problem: "is unique in the model, not in the database"})
+		}
+	}
+
+	for _, index := range indexes {
+		if _, extra := live[index.Name]; extra {
+			drifts = append(drifts, Drift{Table: table.Table, Object: index.Name,
+				Problem: fmt.Sprintf("%s on (%s) is not in the model", indexKind(index.Unique), strings.Join(index.Columns, ", "))})
+		}
+	}
+	return drifts
+}
+
+// canonicalType returns the type of the column in the form of canonicalType
+func (c liveColumn) canonicalType() string {
+	t := c.UdtName
+	switch {
+	case (t == "varchar" || t == "bpchar") && c.CharacterMaximumLength != nil:
+		t = fmt.Sprintf("%s(%d)", t, *c.CharacterMaximumLength)
+	case t == "numeric" && c.NumericPrecision != nil:
+		t = fmt.Sprintf("numeric(%d,%d)", *c.NumericPrecision, derefOr(c.NumericScale, 0))
+	case (t == "timestamp" || t == "timestamptz" || t == "time") && c.DatetimePrecision != nil:
+		t = fmt.Sprintf("%s(%d)", t, *c.DatetimePrecision)
+	}
+	return canonicalType(t)
+}
+
+// canonicalType rewrites a Postgres type so that aliases compare equal, as in
+// VARCHAR(50) and character varying(50), or serial and integer
+func canonicalType(t string) string {
+	t = spaces.ReplaceAllString(strings.ToLower(strings.TrimSpace(t)), " ")
+	t = strings.ReplaceAll(t, ", ", ",")
+
+	if base, ok := strings.CutSuffix(t, "[]"); ok {
+		// Arrays are reported by element type only
+		base, _, _ = strings.Cut(canonicalType(base), "(")
+		return "_" + base
+	}
+
+	name, args, _ := strings.Cut(t, "(")
+	name = strings.TrimSpace(name)
+	args = strings.TrimSuffix(args, ")")
+	if alias, ok := typeAliases[name]; ok {
+		name = alias
+	}
+
+	switch name {
+	case "timestamp", "timestamptz", "time":
+		if args == defaultTimePrecision {
+			args = ""
+		}
+	case "bpchar":
+		if args == "" {
+			args = "1"
+		}
+	case "numeric":
+		if args != "" && !strings.Contains(args, ",") {
+			args += ",0"
+		}
+	}
+
+	if args == "" {
+		return name
+	}
+	return name + "(" + args + ")"
+}
+
+// indexColumns returns the column list of a CREATE INDEX statement from pg_indexes
+func indexColumns(def string) []string {
+	using := strings.Index(def, " USING ")
+	if using < 0 {
+		return nil
+	}
+	rest := def[using:]
+	open := strings.IndexByte(rest, '(')
+	if open < 0 {
+		return nil
+	}
+
+	var columns []string
+	depth, start := 0, open+1
+	for i := open; i < len(rest); i++ {
+		switch rest[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return append(columns, normalizeIndexColumn(rest[start:i]))
+			}
+		case ',':
+			if depth == 1 {
+				columns = append(columns, normalizeIndexColumn(rest[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return columns
+}
+
+// normalizeIndexColumn lowercases an index column and drops quotes and the
+// default ASC order
+func normalizeIndexColumn(column string) string {
+	column = spaces.ReplaceAllString(strings.ToLower(strings.TrimSpace(column)), " ")
+	column = strings.ReplaceAll(column, `"`, "")
+	return strings.TrimSuffix(column, " asc")
+}
+
+func indexKind(unique bool) string {
+	if unique {
+		return "unique index"
+	}
+	return "index"
+}
+
+func uniqueness(unique bool) string {
+	if unique {
+		return "unique"
+	}
+	return "not unique"
+}
+
+func nullability(notNull bool) string {
+	if notNull {
+		return "NOT NULL"
+	}
+	return "nullable"
+}
+
+func derefOr(p *int, fallback int) int {
+	if p == nil {
+		return fallback
+	}
+	return *p
+}
@@ -0,0 +1,247 @@
+package database
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/raytr/go-template/internal/model"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// widget covers each kind of index tag
+type widget struct {
+	ID        uint      `gorm:"primaryKey"`
+	Name      string    `gorm:"index"`
+	Slug      string    `gorm:"unique"`
+	Code      string    `gorm:"uniqueIndex"`
+	CreatedAt time.Time `gorm:"index:,sort:desc"`
+}
+
+func (widget) TableName() string {
+	return "widgets"
+}
+
+func parseTable(t *testing.T, m interface{}) *schema.Schema {
+	t.Helper()
+	s, err := schema.Parse(m, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("failed to parse %T: %v", m, err)
+	}
+	return s
+}
+
+// postgresDB returns a handle that renders Postgres types without connecting
+func postgresDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1 port=1"}), &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	return db
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestCanonicalType(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"VARCHAR(50)", "varchar(50)"},
+		{"character varying(50)", "varchar(50)"},
+		{"text", "text"},
+		{"serial", "int4"},
+		{"integer", "int4"},
+		{"bigserial", "int8"},
+		{"BIGINT", "int8"},
+		{"boolean", "bool"},
+		{"double precision", "float8"},
+		{"timestamp", "timestamp"},
+		{"timestamp(6)", "timestamp"},
+		{"timestamp(3)", "timestamp(3)"},
+		{"timestamp without time zone", "timestamp"},
+		{"timestamp with time zone", "timestamptz"},
+		{"timestamptz(6)", "timestamptz"},
+		{"char", "bpchar(1)"},
+		{"bpchar", "bpchar(1)"},
+		{"character(10)", "bpchar(10)"},
+		{"numeric", "numeric"},
+		{"numeric(10)", "numeric(10,0)"},
+		{"decimal(10, 2)", "numeric(10,2)"},
+		{"text[]", "_text"},
+		{"bigint[]", "_int8"},
+		{"varchar(20)[]", "_varchar"},
+		{"  Character   Varying(20) ", "varchar(20)"},
+	}
+
+	for _, tt := range tests {
+		if got := canonicalType(tt.in); got != tt.want {
+			t.Errorf("canonicalType(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLiveColumnCanonicalType(t *testing.T) {
+	tests := []struct {
+		name   string
+		column liveColumn
+		want   string
+	}{
+		{"varchar", liveColumn{UdtName: "varchar", CharacterMaximumLength: intPtr(50)}, "varchar(50)"},
+		{"unbounded varchar", liveColumn{UdtName: "varchar"}, "varchar"},
+		{"bpchar", liveColumn{UdtName: "bpchar", CharacterMaximumLength: intPtr(1)}, "bpchar(1)"},
+		{"numeric", liveColumn{UdtName: "numeric", NumericPrecision: intPtr(10), NumericScale: intPtr(2)}, "numeric(10,2)"},
+		{"numeric without scale", liveColumn{UdtName: "numeric", NumericPrecision: intPtr(10)}, "numeric(10,0)"},
+		{"integer precision is ignored", liveColumn{UdtName: "int4", NumericPrecision: intPtr(32), NumericScale: intPtr(0)}, "int4"},
+		{"default timestamp precision", liveColumn{UdtName: "timestamp", DatetimePrecision: intPtr(6)}, "timestamp"},
+		{"timestamptz precision", liveColumn{UdtName: "timestamptz", DatetimePrecision: intPtr(3)}, "timestamptz(3)"},
+		{"array", liveColumn{UdtName: "_int8"}, "_int8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.column.canonicalType(); got != tt.want {
+				t.Errorf("canonicalType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexColumns(t *testing.T) {
+	tests := []struct {
+		def  string
+		want []string
+	}{
+		{"CREATE INDEX idx_users_code ON public.users USING btree (code)", []string{"code"}},
+		{"CREATE INDEX idx ON public.t USING btree (a, b DESC)", []string{"a", "b desc"}},
+		{`CREATE UNIQUE INDEX idx ON public.t USING btree ("Code")`, []string{"code"}},
+		{"CREATE INDEX idx ON public.t USING btree (created_at DESC NULLS LAST)", []string{"created_at desc nulls last"}},
+		{"CREATE UNIQUE INDEX users_email_lower_key ON public.users USING btree (lower((email)::text))", []string{"lower((email)::text)"}},
+		{"CREATE INDEX idx ON public.t USING btree (lower((a)::text), b)", []string{"lower((a)::text)", "b"}},
+		{"CREATE INDEX idx ON public.t USING gin (tags)", []string{"tags"}},
+		{"CREATE INDEX idx ON public.t USING btree (a) WHERE (b IS NULL)", []string{"a"}},
+		{"not an index", nil},
+	}
+
+	for _, tt := range tests {
+		if got := indexColumns(tt.def); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("indexColumns(%q) = %q, want %q", tt.def, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeIndexColumn(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"code", "code"},
+		{" Code ASC", "code"},
+		{`"Name"   DESC`, "name desc"},
+		{"lower((email)::text)", "lower((email)::text)"},
+		{"created_at DESC NULLS LAST", "created_at desc nulls last"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeIndexColumn(tt.in); got != tt.want {
+			t.Errorf("normalizeIndexColumn(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCheckIndexes(t *testing.T) {
+	table := parseTable(t, &widget{})
+	matching := []liveIndex{
+		{Name: "idx_widgets_code", Unique: true, Columns: []string{"code"}},
+		{Name: "idx_widgets_created_at", Columns: []string{"created_at desc"}},
+		{Name: "idx_widgets_name", Columns: []string{"name"}},
+		{Name: "widgets_slug_key", Unique: true, Columns: []string{"slug"}},
+	}
+
+	tests := []struct {
+		name    string
+		indexes []liveIndex
+		want    []Drift
+	}{
+		{"matching", matching, nil},
+		{
+			"missing index",
+			matching[1:],
+			[]Drift{{Table: "widgets", Object: "idx_widgets_code", Problem: "unique index on (code) is missing"}},
+		},
+		{
+			"not unique",
+			replaceIndex(matching, 0, liveIndex{Name: "idx_widgets_code", Columns: []string{"code"}}),
+			[]Drift{{Table: "widgets", Object: "idx_widgets_code", Problem: "is unique in the model, not unique in the database"}},
+		},
+		{
+			"ascending instead of descending",
+			replaceIndex(matching, 1, liveIndex{Name: "idx_widgets_created_at", Columns: []string{"created_at"}}),
+			[]Drift{{Table: "widgets", Object: "idx_widgets_created_at", Problem: "covers (created_at desc) in the model, (created_at) in the database"}},
+		},
+		{
+			"missing unique constraint",
+			matching[:3],
+			[]Drift{{Table: "widgets", Object: "slug", Problem: "is unique in the model, not in the database"}},
+		},
+		{
+			"unique constraint under another name",
+			replaceIndex(matching, 3, liveIndex{Name: "widgets_slug_idx", Unique: true, Columns: []string{"slug"}}),
+			nil,
+		},
+		{
+			"extra expression index",
+			append(append([]liveIndex{}, matching...), liveIndex{Name: "widgets_name_lower_key", Unique: true, Columns: []string{"lower((name)::text)"}}),
+			[]Drift{{Table: "widgets", Object: "widgets_name_lower_key", Problem: "unique index on (lower((name)::text)) is not in the model"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkIndexes(table, tt.indexes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkIndexes() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestUsersDrift pins the known differences between UserEntity and the users
+// table of the migrations. It changes when either of them is fixed.
+func TestUsersDrift(t *testing.T) {
+	table := parseTable(t, &model.UserEntity{})
+
+	columns, indexes := migrationSchema(t)
+
+	want := []Drift{
+		// serial in the migration, bigserial for the uint ID
+		{Table: "users", Object: "id", Problem: "type is int8 in the model, int4 in the database"},
+		// timestamp in the migration, timestamptz for time.Time
+		{Table: "users", Object: "created_at", Problem: "type is timestamptz in the model, timestamp in the database"},
+		{Table: "users", Object: "created_at", Problem: "is nullable in the model, NOT NULL in the database"},
+		{Table: "users", Object: "updated_at", Problem: "type is timestamptz in the model, timestamp in the database"},
+		{Table: "users", Object: "updated_at", Problem: "is nullable in the model, NOT NULL in the database"},
+		// uniqueIndex names idx_users_code, while the migration made it a plain
+		// index and the UNIQUE constraint users_code_key
+		{Table: "users", Object: "idx_users_code", Problem: "is unique in the model, not unique in the database"},
+		{Table: "users", Object: "idx_users_created_at", Problem: "index on (created_at desc) is not in the model"},
+		{Table: "users", Object: "users_code_key", Problem: "unique index on (code) is not in the model"},
+		{Table: "users", Object: "users_email_lower_key", Problem: "unique index on (lower((email)::text)) is not in the model"},
+	}
+
+	got := append(checkColumns(postgresDB(t), table, columns["users"]), checkIndexes(table, indexes["users"])...)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("users drift =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func replaceIndex(indexes []liveIndex, i int, index liveIndex) []liveIndex {
+	replaced := append([]liveIndex{}, indexes...)
+	replaced[i] = index
+	return replaced
+}
@@ -0,0 +1,226 @@
+package database
+
+import (
+	"io/fs"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/raytr/go-template/internal/model"
+	"github.com/raytr/go-template/internal/ratelimit"
+	"github.com/raytr/go-template/migrations"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	createTable  = regexp.MustCompile(`(?is)^CREATE TABLE (?:IF NOT EXISTS )?(\w+) \((.*)\)$`)
+	createIndex  = regexp.MustCompile(`(?is)^CREATE (UNIQUE )?INDEX (?:IF NOT EXISTS )?(\w+) ON (\w+) ?(\(.*)$`)
+	dropIndex    = regexp.MustCompile(`(?is)^DROP INDEX (?:IF EXISTS )?(\w+)$`)
+	uniqueColumn = regexp.MustCompile(`\bUNIQUE\b`)
+	alterSchema  = regexp.MustCompile(`(?is)^(ALTER TABLE|DROP TABLE|ALTER INDEX|CREATE TYPE)`)
+	// Postgres casts varchar arguments of lower() to text in pg_indexes
+	lowerColumn = regexp.MustCompile(`lower\((\w+)\)`)
+)
+
+// columnKeywords end the type of a column definition
+var columnKeywords = map[string]bool{
+	"NOT": true, "NULL": true, "PRIMARY": true, "UNIQUE": true,
+	"DEFAULT": true, "REFERENCES": true, "CHECK": true,
+}
+
+// migrationSchema replays the CREATE TABLE, CREATE INDEX and DROP INDEX
+// statements of the up migrations and returns the columns and indexes they
+// leave, by table, in the form information_schema and pg_indexes report them.
+// Primary keys are left out of the indexes, as readIndexes does.
+func migrationSchema(t *testing.T) (map[string][]liveColumn, map[string][]liveIndex) {
+	t.Helper()
+	files, err := fs.Glob(migrations.FS, "*.up.sql")
+	if err != nil {
+		t.Fatalf("failed to list migrations: %v", err)
+	}
+	sort.Strings(files)
+
+	columns := make(map[string][]liveColumn)
+	indexes := make(map[string][]liveIndex)
+
+	for _, file := range files {
+		data, err := fs.ReadFile(migrations.FS, file)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", file, err)
+		}
+
+		for _, stmt := range splitStatements(string(data)) {
+			switch {
+			case createTable.MatchString(stmt):
+				m := createTable.FindStringSubmatch(stmt)
+				columns[m[1]], indexes[m[1]] = parseCreateTable(m[1], m[2])
+			case createIndex.MatchString(stmt):
+				m := createIndex.FindStringSubmatch(stmt)
+				def := "CREATE INDEX " + m[2] + " ON " + m[3] + " USING btree " + lowerColumn.ReplaceAllString(m[4], "lower(($1)::text)")
+				indexes[m[3]] = append(indexes[m[3]], liveIndex{Name: m[2], Unique: m[1] != "", Columns: indexColumns(def)})
+			case dropIndex.MatchString(stmt):
+				name := dropIndex.FindStringSubmatch(stmt)[1]
+				for table, list := range indexes {
+					indexes[table] = removeIndex(list, name)
+				}
+			case alterSchema.MatchString(stmt):
+				t.Fatalf("%s: migrationSchema does not replay %q yet", file, stmt)
+			}
+		}
+	}
+
+	// As readIndexes orders them
+	for _, list := range indexes {
+		sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	}
+	return columns, indexes
+}
+
+// splitStatements splits SQL on semicolons outside $$ quoted bodies and drops comments
+func splitStatements(sql string) []string {
+	var lines []string
+	for _, line := range strings.Split(sql, "\n") {
+		line, _, _ = strings.Cut(line, "--")
+		lines = append(lines, line)
+	}
+	sql = strings.Join(lines, "\n")
+
+	var stmts []string
+	quoted, start := false, 0
+	for i := 0; i < len(sql); i++ {
+		switch {
+		case strings.HasPrefix(sql[i:], "$$"):
+			quoted = !quoted
+			i++
+		case sql[i] == ';' && !quoted:
+			stmts = append(stmts, spaces.ReplaceAllString(strings.TrimSpace(sql[start:i]), " "))
+			start = i + 1
+		}
+	}
+	return stmts
+}
+
+// parseCreateTable returns the columns and the unique constraints of a CREATE TABLE body
+func parseCreateTable(table, body string) ([]liveColumn, []liveIndex) {
+	var columns []liveColumn
+	var indexes []liveIndex
+	primaryKey := make(map[string]bool)
+
+	for _, item := range splitTopLevel(body) {
+		upper := strings.ToUpper(item)
+		switch {
+		case strings.HasPrefix(upper, "PRIMARY KEY"):
+			for _, c := range indexColumns(" USING " + item[len("PRIMARY KEY"):]) {
+				primaryKey[c] = true
+			}
+		case strings.HasPrefix(upper, "UNIQUE"):
+			cols := indexColumns(" USING " + item[len("UNIQUE"):])
+			indexes = append(indexes, liveIndex{Name: table + "_" + strings.Join(cols, "_") + "_key", Unique: true, Columns: cols})
+		default:
+			fields := strings.Fields(item)
+			name, typ := fields[0], ""
+			for _, f := range fields[1:] {
+				if columnKeywords[strings.ToUpper(f)] {
+					break
+				}
+				typ += " " + f
+			}
+
+			column := liveColumn{ColumnName: name, UdtName: canonicalType(typ), IsNullable: "YES"}
+			if strings.Contains(upper, "NOT NULL") {
+				column.IsNullable = "NO"
+			}
+			if strings.Contains(upper, "PRIMARY KEY") {
+				primaryKey[name] = true
+			} else if uniqueColumn.MatchString(upper) {
+				indexes = append(indexes, liveIndex{Name: table + "_" + name + "_key", Unique: true, Columns: []string{name}})
+			}
+			columns = append(columns, column)
+		}
+	}
+
+	for i := range columns {
+		if primaryKey[columns[i].ColumnName] {
+			columns[i].IsNullable = "NO"
+		}
+	}
+	return columns, indexes
+}
+
+// splitTopLevel splits a list on the commas outside parentheses
+func splitTopLevel(list string) []string {
+	var items []string
+	depth, start := 0, 0
+	for i, r := range list {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				items = append(items, strings.TrimSpace(list[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(items, strings.TrimSpace(list[start:]))
+}
+
+func removeIndex(indexes []liveIndex, name string) []liveIndex {
+	kept := indexes[:0]
+	for _, index := range indexes {
+		if index.Name != name {
+			kept = append(kept, index)
+		}
+	}
+	return kept
+}
+
+// TestModelsMatchMigrations checks every model, and every table, against the
+// schema the migrations create. users is left to TestUsersDrift.
+func TestModelsMatchMigrations(t *testing.T) {
+	columns, indexes := migrationSchema(t)
+	db := postgresDB(t)
+
+	tables, err := modelTables(db, append(model.Entities(), &ratelimit.BucketRow{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var drifts []Drift
+	for _, table := range tables {
+		if table.Table == "users" {
+			continue
+		}
+		live, ok := columns[table.Table]
+		if !ok {
+			drifts = append(drifts, Drift{Table: table.Table, Problem: "table is missing"})
+			continue
+		}
+		drifts = append(drifts, checkColumns(db, table, live)...)
+		drifts = append(drifts, checkIndexes(table, indexes[table.Table])...)
+	}
+
+	live := make([]string, 0, len(columns))
+	for name := range columns {
+		live = append(live, name)
+	}
+	sort.Strings(live)
+	drifts = append(drifts, checkTables(tables, live)...)
+
+	for _, d := range drifts {
+		t.Errorf("%s %s: %s", d.Table, d.Object, d.Problem)
+	}
+}
+
+func TestCheckTables(t *testing.T) {
+	tables := []*schema.Schema{parseTable(t, &widget{})}
+	got := checkTables(tables, []string{"gadgets", "schema_migrations", "widgets"})
+	want := []Drift{{Table: "gadgets", Problem: "table is not in the model"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("checkTables() = %+v, want %+v", got, want)
+	}
+}
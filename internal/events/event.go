@@ -0,0 +1,124 @@
+package events
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/raytr/go-template/internal/model"
+)
+
+// Domain event types for the user lifecycle
+const (
+	TypeUserCreated = "user.created"
+	TypeUserUpdated = "user.updated"
+	TypeUserDeleted = "user.deleted"
+)
+
+// Types lists every domain event type
+var Types = []string{TypeUserCreated, TypeUserUpdated, TypeUserDeleted}
+
+// IsKnownType reports whether eventType is one of Types
+func IsKnownType(eventType string) bool {
+	for _, t := range Types {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// AggregateUser is the aggregate type of user events
+const AggregateUser = "user"
+
+// Event is the envelope published to sinks
+type Event struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// UserDeletedData is the payload of a user.deleted event
+type UserDeletedData struct {
+	ID   uint   `json:"id"`
+	Code string `json:"code"`
+}
+
+// NewUserCreated builds a user.created event carrying the new user
+func NewUserCreated(user *model.UserEntity) (*Event, error) {
+	return newEvent(TypeUserCreated, AggregateUser, user.ID, user.ToResponse())
+}
+
+// NewUserUpdated builds a user.updated event carrying the updated user
+func NewUserUpdated(user *model.UserEntity) (*Event, error) {
+	return newEvent(TypeUserUpdated, AggregateUser, user.ID, user.ToResponse())
+}
+
+// NewUserDeleted builds a user.deleted event carrying the deleted user's identifiers
+func NewUserDeleted(user *model.UserEntity) (*Event, error) {
+	return newEvent(TypeUserDeleted, AggregateUser, user.ID, &UserDeletedData{ID: user.ID, Code: user.Code})
+}
+
+// ToOutbox converts the event to an outbox row that is due immediately
+func (e *Event) ToOutbox() (*model.OutboxEventEntity, error) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s event: %w", e.Type, err)
+	}
+
+	return &model.OutboxEventEntity{
+		EventID:       e.ID,
+		EventType:     e.Type,
+		AggregateType: e.AggregateType,
+		AggregateID:   e.AggregateID,
+		Payload:       payload,
+		Status:        model.OutboxStatusPending,
+		NextAttemptAt: e.OccurredAt,
+	}, nil
+}
+
+// FromOutbox decodes the event stored in an outbox row
+func FromOutbox(row *model.OutboxEventEntity) (*Event, error) {
+	var e Event
+	if err := json.Unmarshal(row.Payload, &e); err != nil {
+		return nil, fmt.Errorf("failed to decode outbox event %s: %w", row.EventID, err)
+	}
+	return &e, nil
+}
+
+func newEvent(eventType, aggregateType string, aggregateID uint, data interface{}) (*Event, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	id, err := newUUID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate event id: %w", err)
+	}
+
+	return &Event{
+		ID:            id,
+		Type:          eventType,
+		AggregateType: aggregateType,
+		AggregateID:   strconv.FormatUint(uint64(aggregateID), 10),
+		OccurredAt:    time.Now().UTC(),
+		Data:          encoded,
+	}, nil
+}
+
+// newUUID returns a random RFC 4122 version 4 UUID
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
@@ -0,0 +1,112 @@
+package events
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/raytr/go-template/internal/repository"
+)
+
+// RelayConfig controls how the relay polls and retries
+type RelayConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	// LeaseTimeout hides a claimed batch from other relays. It must exceed the
+	// time the sink takes to publish a whole batch.
+	LeaseTimeout time.Duration
+}
+
+// Relay publishes pending outbox events to a sink. Several relays can run
+// against the same database; each event is claimed by one of them at a time.
+type Relay struct {
+	outboxRepo *repository.OutboxRepository
+	sink       Sink
+	cfg        RelayConfig
+}
+
+// NewRelay creates a new outbox relay
+func NewRelay(outboxRepo *repository.OutboxRepository, sink Sink, cfg RelayConfig) *Relay {
+	return &Relay{
+		outboxRepo: outboxRepo,
+		sink:       sink,
+		cfg:        cfg,
+	}
+}
+
+// Run polls the outbox until ctx is cancelled
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain full batches without waiting for the next tick
+		for {
+			n, err := r.ProcessBatch(ctx)
+			if err != nil {
+				log.Printf("Outbox relay error: %v", err)
+				break
+			}
+			if n < r.cfg.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch claims one batch of due events, publishes them and records the
+// delivery state of each in its own write. It returns the number of events
+// handled.
+func (r *Relay) ProcessBatch(ctx context.Context) (int, error) {
+	now := time.Now()
+	rows, err := r.outboxRepo.ClaimDue(now, r.cfg.BatchSize, now.Add(r.cfg.LeaseTimeout))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, row := range rows {
+		attempts := row.Attempts + 1
+
+		event, err := FromOutbox(row)
+		if err == nil {
+			err = r.sink.Publish(ctx, event)
+		}
+
+		switch {
+		case err == nil:
+			err = r.outboxRepo.MarkDelivered(row.ID, attempts, time.Now())
+		case attempts >= r.cfg.MaxAttempts:
+			log.Printf("Outbox event %s failed permanently after %d attempts: %v", row.EventID, attempts, err)
+			err = r.outboxRepo.MarkFailed(row.ID, attempts, err.Error())
+		default:
+			err = r.outboxRepo.MarkRetry(row.ID, attempts, err.Error(), time.Now().Add(r.backoff(attempts)))
+		}
+		// The lease expires and the event is published again
+		if err != nil {
+			return len(rows), err
+		}
+	}
+
+	return len(rows), nil
+}
+
+// backoff returns the delay before the given attempt number is retried
+func (r *Relay) backoff(attempts int) time.Duration {
+	delay := r.cfg.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= r.cfg.MaxBackoff {
+			return r.cfg.MaxBackoff
+		}
+	}
+	return delay
+}
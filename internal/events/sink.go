@@ -0,0 +1,135 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Sink publishes events to a downstream system. Delivery is at-least-once, so
+// consumers should deduplicate on Event.ID.
+type Sink interface {
+	Publish(ctx context.Context, event *Event) error
+}
+
+// LogSink writes events to the application log
+type LogSink struct{}
+
+// NewLogSink creates a new log sink
+func NewLogSink() *LogSink {
+	return &LogSink{}
+}
+
+// Publish logs the event
+func (s *LogSink) Publish(_ context.Context, event *Event) error {
+	log.Printf("Event %s %s %s/%s: %s", event.ID, event.Type, event.AggregateType, event.AggregateID, event.Data)
+	return nil
+}
+
+// HTTPSink POSTs events as JSON to a webhook URL
+type HTTPSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPSink creates a new webhook sink
+func NewHTTPSink(url string, timeout time.Duration) *HTTPSink {
+	return &HTTPSink{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Publish POSTs the event and treats any non-2xx response as a failure
+func (s *HTTPSink) Publish(ctx context.Context, event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", event.ID)
+	req.Header.Set("X-Event-Type", event.Type)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// BrokerPublisher is the message broker client used by BrokerSink
+type BrokerPublisher interface {
+	Publish(ctx context.Context, subject string, data []byte) error
+}
+
+// BrokerSink publishes events to a message broker on "<prefix>.<event type>" subjects
+type BrokerSink struct {
+	publisher     BrokerPublisher
+	subjectPrefix string
+}
+
+// NewBrokerSink creates a new broker sink
+func NewBrokerSink(publisher BrokerPublisher, subjectPrefix string) *BrokerSink {
+	return &BrokerSink{
+		publisher:     publisher,
+		subjectPrefix: subjectPrefix,
+	}
+}
+
+// Publish sends the JSON encoded event to the broker
+func (s *BrokerSink) Publish(ctx context.Context, event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	if err := s.publisher.Publish(ctx, s.subjectPrefix+"."+event.Type, body); err != nil {
+		return fmt.Errorf("broker publish failed: %w", err)
+	}
+
+	return nil
+}
+
+// RedisStreamPublisher publishes to Redis streams, one stream per subject, with
+// the encoded event in the "event" field. Unlike Pub/Sub, entries stay in the
+// stream, so consumer groups read every event even if they were down.
+type RedisStreamPublisher struct {
+	client redis.UniversalClient
+	// maxLen trims each stream to about this many entries, 0 for no limit
+	maxLen int64
+}
+
+// NewRedisStreamPublisher creates a new Redis streams publisher
+func NewRedisStreamPublisher(client redis.UniversalClient, maxLen int64) *RedisStreamPublisher {
+	return &RedisStreamPublisher{
+		client: client,
+		maxLen: maxLen,
+	}
+}
+
+// Publish appends data to the stream named subject
+func (p *RedisStreamPublisher) Publish(ctx context.Context, subject string, data []byte) error {
+	return p.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: subject,
+		MaxLen: p.maxLen,
+		Approx: p.maxLen > 0,
+		Values: []interface{}{"event", data},
+	}).Err()
+}
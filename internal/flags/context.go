@@ -0,0 +1,137 @@
+package flags
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Set evaluates flags for one subject, typically for one request. Each flag is
+// evaluated once and keeps its value for the lifetime of the set, even if the
+// store is refreshed meanwhile. A nil Set reports every flag as off.
+type Set struct {
+	flags     map[string]Flag
+	subject   Subject
+	overrides map[string]string
+
+	mu        sync.Mutex
+	evaluated map[string]Evaluation
+}
+
+// Evaluate returns the value of a flag for the set's subject
+func (s *Set) Evaluate(key string) Evaluation {
+	if s == nil {
+		return Evaluation{Key: key, Reason: ReasonUnknown}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.evaluated[key]; ok {
+		return e
+	}
+
+	e := s.evaluate(key)
+	s.evaluated[key] = e
+	return e
+}
+
+func (s *Set) evaluate(key string) Evaluation {
+	f, known := s.flags[key]
+
+	if value, ok := s.overrides[key]; ok {
+		e := Evaluation{Key: key, Reason: ReasonOverride}
+		switch value {
+		case "on", "true":
+			e.Enabled = true
+			if known {
+				e.Variant = f.variant(s.subject)
+			}
+		case "off", "false":
+		default:
+			e.Enabled, e.Variant = true, value
+		}
+		return e
+	}
+
+	if !known {
+		return Evaluation{Key: key, Reason: ReasonUnknown}
+	}
+	return f.Evaluate(s.subject)
+}
+
+// Enabled reports whether a flag is on
+func (s *Set) Enabled(key string) bool {
+	return s.Evaluate(key).Enabled
+}
+
+// Variant returns the variant of a flag, empty when it is off or has no variants
+func (s *Set) Variant(key string) string {
+	return s.Evaluate(key).Variant
+}
+
+// All evaluates every defined flag, sorted by key, followed by the overridden
+// flags that are not defined
+func (s *Set) All() []Evaluation {
+	if s == nil {
+		return []Evaluation{}
+	}
+
+	list := make([]Evaluation, 0, len(s.flags))
+	for _, f := range sorted(s.flags) {
+		list = append(list, s.Evaluate(f.Key))
+	}
+	var undefined []string
+	for key := range s.overrides {
+		if _, ok := s.flags[key]; !ok {
+			undefined = append(undefined, key)
+		}
+	}
+	sort.Strings(undefined)
+	for _, key := range undefined {
+		list = append(list, s.Evaluate(key))
+	}
+	return list
+}
+
+// ParseOverrides parses a list such as "new-search=on, checkout=variant-b,
+// old-export=off". A key alone turns the flag on. Invalid keys are skipped.
+func ParseOverrides(s string) map[string]string {
+	overrides := make(map[string]string)
+	for _, item := range strings.Split(s, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(item), "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !found {
+			value = "on"
+		}
+		if !ValidKey(key) || value == "" {
+			continue
+		}
+		overrides[key] = value
+	}
+	return overrides
+}
+
+type setKey struct{}
+
+// NewContext returns a copy of ctx carrying the set
+func NewContext(ctx context.Context, set *Set) context.Context {
+	return context.WithValue(ctx, setKey{}, set)
+}
+
+// FromContext returns the set stored by NewContext, or nil
+func FromContext(ctx context.Context) *Set {
+	set, _ := ctx.Value(setKey{}).(*Set)
+	return set
+}
+
+// Enabled reports whether a flag is on for the request in ctx
+func Enabled(ctx context.Context, key string) bool {
+	return FromContext(ctx).Enabled(key)
+}
+
+// VariantOf returns the variant of a flag for the request in ctx
+func VariantOf(ctx context.Context, key string) string {
+	return FromContext(ctx).Variant(key)
+}
@@ -0,0 +1,68 @@
+package flags
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestParseOverrides(t *testing.T) {
+	tests := []struct {
+		in   string
+		want map[string]string
+	}{
+		{"", map[string]string{}},
+		{"new-search=on", map[string]string{"new-search": "on"}},
+		{"new-search", map[string]string{"new-search": "on"}},
+		{" new-search = off , checkout=variant-b ", map[string]string{"new-search": "off", "checkout": "variant-b"}},
+		{"a=on,a=off", map[string]string{"a": "off"}},
+		{"Invalid=on,ok=on", map[string]string{"ok": "on"}},
+		{"empty=,ok", map[string]string{"ok": "on"}},
+		{",,", map[string]string{}},
+	}
+
+	for _, tt := range tests {
+		if got := ParseOverrides(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseOverrides(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSetOverrides(t *testing.T) {
+	store := NewStore(map[string]Flag{
+		"off-flag": {},
+		"on-flag":  {Enabled: true},
+		"variants": {Enabled: false, Variants: []Variant{{Name: "a", Weight: 1}}},
+	}, nil, 0)
+
+	set := store.For(Subject{ID: "user:1"}, map[string]string{
+		"off-flag":  "on",
+		"on-flag":   "false",
+		"variants":  "true",
+		"undefined": "variant-b",
+	})
+
+	want := []Evaluation{
+		{Key: "off-flag", Enabled: true, Reason: ReasonOverride},
+		{Key: "on-flag", Reason: ReasonOverride},
+		{Key: "variants", Enabled: true, Variant: "a", Reason: ReasonOverride},
+		{Key: "undefined", Enabled: true, Variant: "variant-b", Reason: ReasonOverride},
+	}
+	if got := set.All(); !reflect.DeepEqual(got, want) {
+		t.Errorf("All() = %+v, want %+v", got, want)
+	}
+
+	if got := set.Evaluate("missing"); got != (Evaluation{Key: "missing", Reason: ReasonUnknown}) {
+		t.Errorf("Evaluate(missing) = %+v, want unknown", got)
+	}
+}
+
+func TestNilSet(t *testing.T) {
+	ctx := context.Background()
+	if Enabled(ctx, "any") || VariantOf(ctx, "any") != "" {
+		t.Error("a context without a set reports a flag as on")
+	}
+	if got := FromContext(ctx).All(); len(got) != 0 {
+		t.Errorf("All() = %+v, want none", got)
+	}
+}
@@ -0,0 +1,149 @@
+package flags
+
+import (
+	"hash/fnv"
+	"regexp"
+)
+
+// Where a flag definition comes from. Definitions stored in the database
+// replace the configured definition with the same key.
+const (
+	SourceConfig   = "config"
+	SourceDatabase = "database"
+)
+
+// Reasons given for an evaluation
+const (
+	ReasonUnknown  = "unknown"
+	ReasonDisabled = "disabled"
+	ReasonOverride = "override"
+	ReasonTarget   = "target"
+	ReasonRollout  = "rollout"
+	ReasonDefault  = "default"
+)
+
+// KeyPattern restricts flag keys to what survives config files and headers
+const KeyPattern = `^[a-z0-9][a-z0-9_.-]{0,99}$`
+
+var keyPattern = regexp.MustCompile(KeyPattern)
+
+// ValidKey reports whether key can name a flag: lower-case letters, digits,
+// "_", "." and "-", at most 100 characters
+func ValidKey(key string) bool {
+	return keyPattern.MatchString(key)
+}
+
+// Variant is one arm of a multivariate flag. Subjects that have the flag on are
+// split between the variants in proportion to their weights.
+type Variant struct {
+	Name   string `mapstructure:"name" json:"name" binding:"required" validate:"required"`
+	Weight int    `mapstructure:"weight" json:"weight" binding:"min=0" validate:"min=0"`
+}
+
+// Flag defines a feature flag. A disabled flag is off for everyone. An enabled
+// flag is on for the listed users and roles, and for Percentage percent of the
+// other subjects. Without a percentage it is on for everyone, unless users or
+// roles are listed, in which case it is on for them only.
+type Flag struct {
+	Key         string    `mapstructure:"-" json:"key"`
+	Description string    `mapstructure:"description" json:"description,omitempty"`
+	Enabled     bool      `mapstructure:"enabled" json:"enabled"`
+	Percentage  *int      `mapstructure:"percentage" json:"percentage" validate:"omitempty,min=0,max=100"`
+	UserIDs     []uint    `mapstructure:"user_ids" json:"user_ids,omitempty"`
+	Roles       []string  `mapstructure:"roles" json:"roles,omitempty"`
+	Variants    []Variant `mapstructure:"variants" json:"variants,omitempty" validate:"dive"`
+	Source      string    `mapstructure:"-" json:"source"`
+}
+
+// Subject is the caller a flag is evaluated for
+type Subject struct {
+	// ID places the subject in percentage rollouts and variants, so that the same
+	// subject always gets the same result
+	ID     string
+	UserID uint
+	Roles  []string
+}
+
+// Evaluation is the value of a flag for a subject
+type Evaluation struct {
+	Key     string `json:"key"`
+	Enabled bool   `json:"enabled"`
+	Variant string `json:"variant,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+// Evaluate computes the value of the flag for subject
+func (f *Flag) Evaluate(subject Subject) Evaluation {
+	e := Evaluation{Key: f.Key}
+
+	switch {
+	case !f.Enabled:
+		e.Reason = ReasonDisabled
+	case f.targets(subject):
+		e.Enabled, e.Reason = true, ReasonTarget
+	case f.Percentage != nil:
+		e.Enabled, e.Reason = bucket(f.Key+":"+subject.ID, 100) < *f.Percentage, ReasonRollout
+	case len(f.UserIDs) > 0 || len(f.Roles) > 0:
+		e.Reason = ReasonTarget
+	default:
+		e.Enabled, e.Reason = true, ReasonDefault
+	}
+
+	if e.Enabled {
+		e.Variant = f.variant(subject)
+	}
+	return e
+}
+
+// targets reports whether the flag lists the subject's user or one of its roles
+func (f *Flag) targets(subject Subject) bool {
+	if subject.UserID != 0 {
+		for _, id := range f.UserIDs {
+			if id == subject.UserID {
+				return true
+			}
+		}
+	}
+	for _, role := range f.Roles {
+		for _, r := range subject.Roles {
+			if r == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// variant picks the subject's variant by weight. Variants all weighted zero are
+// picked with equal chances.
+func (f *Flag) variant(subject Subject) string {
+	if len(f.Variants) == 0 {
+		return ""
+	}
+
+	total := 0
+	for _, v := range f.Variants {
+		total += v.Weight
+	}
+	if total == 0 {
+		return f.Variants[bucket(f.Key+":variant:"+subject.ID, len(f.Variants))].Name
+	}
+
+	// Hashed separately from the rollout so that variants are not correlated
+	// with how early a subject got the flag
+	n := bucket(f.Key+":variant:"+subject.ID, total)
+	for _, v := range f.Variants {
+		if n < v.Weight {
+			return v.Name
+		}
+		n -= v.Weight
+	}
+	return f.Variants[len(f.Variants)-1].Name
+}
+
+// bucket hashes s into [0, n)
+func bucket(s string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return int(h.Sum32() % uint32(n))
+}
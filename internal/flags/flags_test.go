@@ -0,0 +1,168 @@
+package flags
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestFlagEvaluate(t *testing.T) {
+	admin := Subject{ID: "user:1", UserID: 1, Roles: []string{"admin"}}
+	other := Subject{ID: "user:2", UserID: 2, Roles: []string{"user"}}
+	anonymous := Subject{ID: "ip:192.0.2.1"}
+
+	tests := []struct {
+		name    string
+		flag    Flag
+		subject Subject
+		want    Evaluation
+	}{
+		{"disabled", Flag{Key: "f", UserIDs: []uint{1}}, admin, Evaluation{Key: "f", Reason: ReasonDisabled}},
+		{"enabled for everyone", Flag{Key: "f", Enabled: true}, anonymous, Evaluation{Key: "f", Enabled: true, Reason: ReasonDefault}},
+		{"targeted user", Flag{Key: "f", Enabled: true, UserIDs: []uint{1}}, admin, Evaluation{Key: "f", Enabled: true, Reason: ReasonTarget}},
+		{"targeted role", Flag{Key: "f", Enabled: true, Roles: []string{"admin"}}, admin, Evaluation{Key: "f", Enabled: true, Reason: ReasonTarget}},
+		{"targets without a percentage exclude the others", Flag{Key: "f", Enabled: true, UserIDs: []uint{1}, Roles: []string{"admin"}}, other, Evaluation{Key: "f", Reason: ReasonTarget}},
+		{"user ID 0 is never targeted", Flag{Key: "f", Enabled: true, UserIDs: []uint{0}}, anonymous, Evaluation{Key: "f", Reason: ReasonTarget}},
+		{"target wins over a zero percentage", Flag{Key: "f", Enabled: true, Percentage: intPtr(0), Roles: []string{"admin"}}, admin, Evaluation{Key: "f", Enabled: true, Reason: ReasonTarget}},
+		{"zero percentage", Flag{Key: "f", Enabled: true, Percentage: intPtr(0), Roles: []string{"admin"}}, other, Evaluation{Key: "f", Reason: ReasonRollout}},
+		{"full percentage", Flag{Key: "f", Enabled: true, Percentage: intPtr(100)}, anonymous, Evaluation{Key: "f", Enabled: true, Reason: ReasonRollout}},
+		{"variant of an enabled flag", Flag{Key: "f", Enabled: true, Variants: []Variant{{Name: "a", Weight: 1}}}, anonymous, Evaluation{Key: "f", Enabled: true, Variant: "a", Reason: ReasonDefault}},
+		{"no variant when off", Flag{Key: "f", Variants: []Variant{{Name: "a", Weight: 1}}}, anonymous, Evaluation{Key: "f", Reason: ReasonDisabled}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.flag.Evaluate(tt.subject); got != tt.want {
+				t.Errorf("Evaluate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlagEvaluatePercentage(t *testing.T) {
+	f := Flag{Key: "rollout", Enabled: true, Percentage: intPtr(30)}
+
+	on := 0
+	const subjects = 10000
+	for i := 0; i < subjects; i++ {
+		subject := Subject{ID: fmt.Sprintf("user:%d", i)}
+		e := f.Evaluate(subject)
+		if e.Enabled {
+			on++
+		}
+		if again := f.Evaluate(subject); again != e {
+			t.Fatalf("Evaluate(%s) = %+v, then %+v", subject.ID, e, again)
+		}
+	}
+
+	if share := float64(on) / subjects; math.Abs(share-0.3) > 0.02 {
+		t.Errorf("flag is on for %.3f of subjects, want about 0.3", share)
+	}
+}
+
+func TestFlagEvaluatePercentageGrows(t *testing.T) {
+	// Raising the percentage keeps the flag on for subjects that had it
+	small := Flag{Key: "rollout", Enabled: true, Percentage: intPtr(10)}
+	large := Flag{Key: "rollout", Enabled: true, Percentage: intPtr(50)}
+
+	for i := 0; i < 1000; i++ {
+		subject := Subject{ID: fmt.Sprintf("user:%d", i)}
+		if small.Evaluate(subject).Enabled && !large.Evaluate(subject).Enabled {
+			t.Fatalf("%s lost the flag when the percentage grew", subject.ID)
+		}
+	}
+}
+
+func TestFlagVariant(t *testing.T) {
+	tests := []struct {
+		name     string
+		variants []Variant
+		want     map[string]float64
+	}{
+		{"weighted", []Variant{{Name: "a", Weight: 1}, {Name: "b", Weight: 3}}, map[string]float64{"a": 0.25, "b": 0.75}},
+		{"zero weight is never picked", []Variant{{Name: "a", Weight: 0}, {Name: "b", Weight: 5}}, map[string]float64{"b": 1}},
+		{"all zero weights are equal", []Variant{{Name: "a"}, {Name: "b"}}, map[string]float64{"a": 0.5, "b": 0.5}},
+	}
+
+	const subjects = 10000
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := Flag{Key: "checkout", Enabled: true, Variants: tt.variants}
+			counts := map[string]int{}
+			for i := 0; i < subjects; i++ {
+				subject := Subject{ID: fmt.Sprintf("user:%d", i)}
+				v := f.variant(subject)
+				if again := f.variant(subject); again != v {
+					t.Fatalf("variant(%s) = %q, then %q", subject.ID, v, again)
+				}
+				counts[v]++
+			}
+
+			for name := range counts {
+				if _, ok := tt.want[name]; !ok {
+					t.Errorf("variant %q was picked", name)
+				}
+			}
+			for name, want := range tt.want {
+				if share := float64(counts[name]) / subjects; math.Abs(share-want) > 0.02 {
+					t.Errorf("variant %q picked for %.3f of subjects, want about %.2f", name, share, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFlagVariantIndependentOfRollout(t *testing.T) {
+	// Subjects early in the rollout must not all land in the same variant
+	f := Flag{Key: "checkout", Enabled: true, Percentage: intPtr(20), Variants: []Variant{{Name: "a", Weight: 1}, {Name: "b", Weight: 1}}}
+
+	counts := map[string]int{}
+	for i := 0; i < 5000; i++ {
+		if e := f.Evaluate(Subject{ID: fmt.Sprintf("user:%d", i)}); e.Enabled {
+			counts[e.Variant]++
+		}
+	}
+	if counts["a"] == 0 || counts["b"] == 0 {
+		t.Errorf("variants of the subjects in the rollout = %v, want both", counts)
+	}
+}
+
+func TestBucket(t *testing.T) {
+	for _, n := range []int{1, 2, 7, 100} {
+		for i := 0; i < 100; i++ {
+			s := fmt.Sprintf("key:%d", i)
+			b := bucket(s, n)
+			if b < 0 || b >= n {
+				t.Fatalf("bucket(%q, %d) = %d, want [0, %d)", s, n, b, n)
+			}
+			if again := bucket(s, n); again != b {
+				t.Fatalf("bucket(%q, %d) = %d, then %d", s, n, b, again)
+			}
+		}
+	}
+}
+
+func TestValidKey(t *testing.T) {
+	tests := map[string]bool{
+		"new-search":              true,
+		"checkout.v2":             true,
+		"a_b":                     true,
+		"0day":                    true,
+		"":                        false,
+		"-leading":                false,
+		"Upper":                   false,
+		"with space":              false,
+		"key=value":               false,
+		string(make([]byte, 101)): false,
+	}
+
+	for key, want := range tests {
+		if got := ValidKey(key); got != want {
+			t.Errorf("ValidKey(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
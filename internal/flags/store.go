@@ -0,0 +1,155 @@
+package flags
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Source lists the flag definitions stored outside the configuration
+type Source interface {
+	ListFlags() ([]Flag, error)
+}
+
+// Store holds the flag definitions in memory so that evaluating a flag never
+// queries the database. Definitions from the source replace configured ones
+// with the same key; they are read again by Refresh and periodically by Run.
+type Store struct {
+	source   Source
+	interval time.Duration
+
+	mu       sync.RWMutex
+	defaults map[string]Flag
+	stored   map[string]Flag
+	// flags merges defaults and stored. It is replaced, never modified, so that
+	// a Set keeps the definitions it started with.
+	flags map[string]Flag
+}
+
+// NewStore creates a store for the configured flags and those listed by source,
+// refreshed every interval by Run. source may be nil.
+func NewStore(defaults map[string]Flag, source Source, interval time.Duration) *Store {
+	s := &Store{
+		source:   source,
+		interval: interval,
+	}
+	s.SetDefaults(defaults)
+	return s
+}
+
+// SetDefaults replaces the configured flags
+func (s *Store) SetDefaults(defaults map[string]Flag) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.defaults = make(map[string]Flag, len(defaults))
+	for key, f := range defaults {
+		f.Key, f.Source = key, SourceConfig
+		s.defaults[key] = f
+	}
+	s.merge()
+}
+
+// Refresh reads the stored flags again. The previous ones are kept on error.
+func (s *Store) Refresh() error {
+	if s.source == nil {
+		return nil
+	}
+
+	list, err := s.source.ListFlags()
+	if err != nil {
+		return fmt.Errorf("failed to load feature flags: %w", err)
+	}
+
+	stored := make(map[string]Flag, len(list))
+	for _, f := range list {
+		f.Source = SourceDatabase
+		stored[f.Key] = f
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stored = stored
+	s.merge()
+	return nil
+}
+
+// merge rebuilds flags. The caller holds mu.
+func (s *Store) merge() {
+	flags := make(map[string]Flag, len(s.defaults)+len(s.stored))
+	for key, f := range s.defaults {
+		flags[key] = f
+	}
+	for key, f := range s.stored {
+		flags[key] = f
+	}
+	s.flags = flags
+}
+
+// Run refreshes the stored flags every interval until ctx is done
+func (s *Store) Run(ctx context.Context) {
+	if s.source == nil {
+		return
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Refresh(); err != nil {
+			log.Printf("Feature flags refresh error: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Lookup returns the definition of a flag
+func (s *Store) Lookup(key string) (Flag, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	f, ok := s.flags[key]
+	return f, ok
+}
+
+// All returns every flag definition, sorted by key
+func (s *Store) All() []Flag {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sorted(s.flags)
+}
+
+// For returns the flags of subject. overrides maps flag keys to "on", "off" or
+// a variant name and takes precedence over the definitions.
+func (s *Store) For(subject Subject, overrides map[string]string) *Set {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return &Set{
+		flags:     s.flags,
+		subject:   subject,
+		overrides: overrides,
+		evaluated: make(map[string]Evaluation),
+	}
+}
+
+func sorted(flags map[string]Flag) []Flag {
+	list := make([]Flag, 0, len(flags))
+	for _, f := range flags {
+		list = append(list, f)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Key < list[j].Key
+	})
+	return list
+}
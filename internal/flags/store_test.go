@@ -0,0 +1,91 @@
+package flags
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// fakeSource lists fixed flags, or fails with err
+type fakeSource struct {
+	flags []Flag
+	err   error
+}
+
+func (s *fakeSource) ListFlags() ([]Flag, error) {
+	return s.flags, s.err
+}
+
+func sources(list []Flag) map[string]string {
+	got := make(map[string]string, len(list))
+	for _, f := range list {
+		got[f.Key] = f.Source
+	}
+	return got
+}
+
+func TestStoreMergePrecedence(t *testing.T) {
+	source := &fakeSource{flags: []Flag{{Key: "shared", Enabled: true}, {Key: "stored"}}}
+	store := NewStore(map[string]Flag{"shared": {}, "configured": {Enabled: true}}, source, 0)
+
+	want := map[string]string{"shared": SourceConfig, "configured": SourceConfig}
+	if got := sources(store.All()); !reflect.DeepEqual(got, want) {
+		t.Fatalf("before Refresh, sources = %v, want %v", got, want)
+	}
+
+	if err := store.Refresh(); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	want = map[string]string{"shared": SourceDatabase, "configured": SourceConfig, "stored": SourceDatabase}
+	if got := sources(store.All()); !reflect.DeepEqual(got, want) {
+		t.Errorf("after Refresh, sources = %v, want %v", got, want)
+	}
+	if f, _ := store.Lookup("shared"); !f.Enabled {
+		t.Error("the stored definition of shared did not replace the configured one")
+	}
+
+	// Stored flags keep precedence over new defaults
+	store.SetDefaults(map[string]Flag{"shared": {}})
+	want = map[string]string{"shared": SourceDatabase, "stored": SourceDatabase}
+	if got := sources(store.All()); !reflect.DeepEqual(got, want) {
+		t.Errorf("after SetDefaults, sources = %v, want %v", got, want)
+	}
+
+	// A deleted stored flag falls back to its configured definition
+	source.flags = []Flag{{Key: "stored"}}
+	if err := store.Refresh(); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if f, _ := store.Lookup("shared"); f.Enabled || f.Source != SourceConfig {
+		t.Errorf("shared = %+v, want the configured definition", f)
+	}
+}
+
+func TestStoreRefreshKeepsFlagsOnError(t *testing.T) {
+	source := &fakeSource{flags: []Flag{{Key: "stored", Enabled: true}}}
+	store := NewStore(nil, source, 0)
+	if err := store.Refresh(); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	source.err = errors.New("connection refused")
+	if err := store.Refresh(); err == nil {
+		t.Fatal("Refresh() error = nil, want the source's error")
+	}
+	if f, ok := store.Lookup("stored"); !ok || !f.Enabled {
+		t.Errorf("Lookup(stored) = %+v, %v, want the previous definition", f, ok)
+	}
+}
+
+func TestSetKeepsDefinitionsItStartedWith(t *testing.T) {
+	store := NewStore(map[string]Flag{"f": {Enabled: true}}, nil, 0)
+	set := store.For(Subject{ID: "user:1"}, nil)
+
+	store.SetDefaults(map[string]Flag{"f": {}})
+	if !set.Enabled("f") {
+		t.Error("a set saw a definition changed after it was created")
+	}
+	if store.For(Subject{ID: "user:1"}, nil).Enabled("f") {
+		t.Error("a new set did not see the changed definition")
+	}
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/raytr/go-template/internal/model"
+	"github.com/raytr/go-template/internal/service"
+)
+
+// APIKeyHandler handles HTTP requests for API keys
+type APIKeyHandler struct {
+	apiKeyService *service.APIKeyService
+	*PaginationHandler
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(apiKeyService *service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService:     apiKeyService,
+		PaginationHandler: NewPaginationHandler(),
+	}
+}
+
+// CreateAPIKey handles POST /api-keys
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var req model.CreateAPIKeyReq
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithBindingError(c, err)
+		return
+	}
+
+	key, plaintext, err := h.apiKeyService.CreateAPIKey(c.Request.Context(), &req)
+	if err != nil {
+		if abortWithAuthError(c, err) {
+			return
+		}
+		respondWithError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": &model.CreateAPIKeyResponse{
+			APIKeyResponse: key.ToResponse(),
+			Key:            plaintext,
+		},
+		"message": message(c, "api_key.created"),
+	})
+}
+
+// GetAllAPIKeys handles GET /api-keys
+func (h *APIKeyHandler) GetAllAPIKeys(c *gin.Context) {
+	pagination, err := h.ParsePagination(c)
+	if err != nil {
+		h.RespondWithPaginationError(c, err)
+		return
+	}
+
+	keys, totalCount, err := h.apiKeyService.GetAllAPIKeys(c.Request.Context(), pagination.Page, pagination.PageSize)
+	if err != nil {
+		if abortWithAuthError(c, err) {
+			return
+		}
+		respondWithCode(c, http.StatusInternalServerError, "api_keys_list_failed")
+		return
+	}
+
+	keyResponses := make([]*model.APIKeyResponse, len(keys))
+	for i, key := range keys {
+		keyResponses[i] = key.ToResponse()
+	}
+
+	h.RespondWithPaginatedData(c, http.StatusOK, keyResponses, pagination, totalCount)
+}
+
+// RevokeAPIKey handles DELETE /api-keys/:id
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	idStr := c.Param("id")
+	id64, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		respondWithCode(c, http.StatusBadRequest, "invalid_api_key_id")
+		return
+	}
+	id := uint(id64)
+
+	if err := h.apiKeyService.RevokeAPIKey(c.Request.Context(), id); err != nil {
+		if abortWithAuthError(c, err) {
+			return
+		}
+		respondWithError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": message(c, "api_key.revoked"),
+	})
+}
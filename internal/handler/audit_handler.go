@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/raytr/go-template/internal/model"
+	"github.com/raytr/go-template/internal/service"
+)
+
+// AuditHandler handles HTTP requests for the audit log
+type AuditHandler struct {
+	auditService *service.AuditService
+	*PaginationHandler
+}
+
+// NewAuditHandler creates a new audit handler
+func NewAuditHandler(auditService *service.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService:      auditService,
+		PaginationHandler: NewPaginationHandler(),
+	}
+}
+
+// GetAuditEvents handles GET /audit
+func (h *AuditHandler) GetAuditEvents(c *gin.Context) {
+	pagination, err := h.ParsePagination(c)
+	if err != nil {
+		h.RespondWithPaginationError(c, err)
+		return
+	}
+
+	var filter model.AuditEventFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		abortWithBindingError(c, err)
+		return
+	}
+
+	events, totalCount, err := h.auditService.GetAuditEvents(c.Request.Context(), &filter, pagination.Page, pagination.PageSize)
+	if err != nil {
+		if abortWithAuthError(c, err) {
+			return
+		}
+		respondWithCode(c, http.StatusInternalServerError, "audit_events_list_failed")
+		return
+	}
+
+	eventResponses := make([]*model.AuditEventResponse, len(events))
+	for i, event := range events {
+		eventResponses[i] = event.ToResponse()
+	}
+
+	h.RespondWithPaginatedData(c, http.StatusOK, eventResponses, pagination, totalCount)
+}
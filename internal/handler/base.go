@@ -1,8 +1,6 @@
 package handler
 
 import (
-	"net/http"
-
 	"github.com/gin-gonic/gin"
 	"github.com/raytr/go-template/internal/model"
 	"github.com/raytr/go-template/internal/utils"
@@ -33,9 +31,7 @@ func (p *PaginationHandler) RespondWithPaginatedData(
 	c.JSON(statusCode, response)
 }
 
-// RespondWithPaginationError sends a standardized error response for pagination errors
+// RespondWithPaginationError sends a problem+json response for invalid pagination parameters
 func (p *PaginationHandler) RespondWithPaginationError(c *gin.Context, err error) {
-	c.JSON(http.StatusBadRequest, gin.H{
-		"error": err.Error(),
-	})
+	abortWithBindingError(c, err)
 }
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/raytr/go-template/internal/i18n"
+	"github.com/raytr/go-template/internal/model"
+	"github.com/raytr/go-template/internal/problem"
+	"github.com/raytr/go-template/internal/repository"
+	"github.com/raytr/go-template/internal/service"
+)
+
+// errorCodes maps known errors to the codes that key their message catalogs
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{repository.ErrUserNotFound, "user_not_found"},
+	{repository.ErrFeatureFlagNotFound, "feature_flag_not_found"},
+	{repository.ErrAPIKeyNotFound, "api_key_not_found"},
+	{repository.ErrWebhookNotFound, "webhook_not_found"},
+	{repository.ErrWebhookDeliveryNotFound, "webhook_delivery_not_found"},
+	{model.ErrInvalidPage, "invalid_page"},
+	{model.ErrInvalidPageSize, "invalid_page_size"},
+}
+
+// respondWithError writes {"error", "code"} for err, with the message translated
+// into the request's language when err has a code. Validation failures become
+// problem+json and conflicts a 409, whatever the status.
+func respondWithError(c *gin.Context, status int, err error) {
+	var validationErr *service.ValidationError
+	if errors.As(err, &validationErr) {
+		abortWithValidationError(c, validationErr)
+		return
+	}
+
+	var conflictErr *service.ConflictError
+	if errors.As(err, &conflictErr) {
+		respondWithCode(c, http.StatusConflict, conflictErr.Code)
+		return
+	}
+
+	for _, known := range errorCodes {
+		if errors.Is(err, known.err) {
+			respondWithCode(c, status, known.code)
+			return
+		}
+	}
+
+	c.JSON(status, gin.H{
+		"error": err.Error(),
+	})
+}
+
+// abortWithValidationError writes a problem+json response listing the fields that
+// failed the service's rules
+func abortWithValidationError(c *gin.Context, err *service.ValidationError) {
+	l := i18n.FromContext(c.Request.Context())
+
+	errs := make([]problem.FieldError, len(err.Fields))
+	for i, f := range err.Fields {
+		errs[i] = problem.FieldError{Field: f.Field, Rule: f.Rule, Message: problem.RuleMessage(l, f.Rule, "string", f.Param)}
+	}
+	problem.Abort(c, problem.Validation(l, c.Request.URL.Path, errs))
+}
+
+// respondWithCode writes {"error", "code"} with the translated message of code
+func respondWithCode(c *gin.Context, status int, code string) {
+	c.JSON(status, gin.H{
+		"error": i18n.FromContext(c.Request.Context()).Message("error." + code),
+		"code":  code,
+	})
+}
+
+// message translates a message key into the request's language
+func message(c *gin.Context, key string) string {
+	return i18n.FromContext(c.Request.Context()).Message(key)
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/raytr/go-template/internal/flags"
+	"github.com/raytr/go-template/internal/model"
+	"github.com/raytr/go-template/internal/repository"
+	"github.com/raytr/go-template/internal/service"
+)
+
+// FeatureFlagHandler handles HTTP requests for feature flags
+type FeatureFlagHandler struct {
+	flagService *service.FeatureFlagService
+}
+
+// NewFeatureFlagHandler creates a new feature flag handler
+func NewFeatureFlagHandler(flagService *service.FeatureFlagService) *FeatureFlagHandler {
+	return &FeatureFlagHandler{
+		flagService: flagService,
+	}
+}
+
+// GetAllFlags handles GET /feature-flags
+func (h *FeatureFlagHandler) GetAllFlags(c *gin.Context) {
+	list, err := h.flagService.GetAllFlags(c.Request.Context())
+	if err != nil {
+		if abortWithAuthError(c, err) {
+			return
+		}
+		respondWithCode(c, http.StatusInternalServerError, "feature_flags_list_failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": list,
+	})
+}
+
+// SetFlag handles PUT /feature-flags/:key
+func (h *FeatureFlagHandler) SetFlag(c *gin.Context) {
+	var req model.SetFeatureFlagReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithBindingError(c, err)
+		return
+	}
+
+	flag, err := h.flagService.SetFlag(c.Request.Context(), c.Param("key"), &req)
+	if err != nil {
+		if abortWithAuthError(c, err) {
+			return
+		}
+		respondWithError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    flag,
+		"message": message(c, "feature_flag.saved"),
+	})
+}
+
+// DeleteFlag handles DELETE /feature-flags/:key
+func (h *FeatureFlagHandler) DeleteFlag(c *gin.Context) {
+	if err := h.flagService.DeleteFlag(c.Request.Context(), c.Param("key")); err != nil {
+		if abortWithAuthError(c, err) {
+			return
+		}
+		if errors.Is(err, repository.ErrFeatureFlagNotFound) {
+			respondWithError(c, http.StatusNotFound, err)
+			return
+		}
+		respondWithCode(c, http.StatusInternalServerError, "feature_flag_delete_failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": message(c, "feature_flag.deleted"),
+	})
+}
+
+// GetEvaluations handles GET /feature-flags/evaluations. It returns the flags
+// of the caller as evaluated for this request, overrides included.
+func (h *FeatureFlagHandler) GetEvaluations(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": flags.FromContext(c.Request.Context()).All(),
+	})
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/raytr/go-template/internal/auth"
+	"github.com/raytr/go-template/internal/flags"
+	"github.com/raytr/go-template/internal/repository"
+	"github.com/raytr/go-template/internal/service"
+)
+
+func TestDeleteFlag(t *testing.T) {
+	const deleteFlag = `DELETE FROM "feature_flags" WHERE key = \$1`
+
+	tests := []struct {
+		name   string
+		expect func(mock sqlmock.Sqlmock)
+		status int
+		code   string
+	}{
+		{
+			name: "deleted",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(deleteFlag).WithArgs("new-search").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "not found",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(deleteFlag).WithArgs("new-search").WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			status: http.StatusNotFound,
+			code:   "feature_flag_not_found",
+		},
+		{
+			name: "database error",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(deleteFlag).WithArgs("new-search").WillReturnError(errors.New("connection reset"))
+			},
+			status: http.StatusInternalServerError,
+			code:   "feature_flag_delete_failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			tt.expect(mock)
+
+			flagService := service.NewFeatureFlagService(repository.NewFeatureFlagRepository(db), auth.NewPolicy(), flags.NewStore(nil, nil, time.Minute))
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.DELETE("/feature-flags/:key", func(c *gin.Context) {
+				principal := auth.NewPrincipal(1, nil, []string{string(auth.PermFlagsManage)})
+				c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+			}, NewFeatureFlagHandler(flagService).DeleteFlag)
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/feature-flags/new-search", nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.code != "" && !strings.Contains(rec.Body.String(), `"code":"`+tt.code+`"`) {
+				t.Errorf("body = %s, want code %s", rec.Body, tt.code)
+			}
+			if strings.Contains(rec.Body.String(), "connection reset") {
+				t.Errorf("body = %s, leaks the database error", rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/raytr/go-template/internal/cache"
+)
+
+// MetricsHandler handles HTTP requests for runtime metrics
+type MetricsHandler struct{}
+
+// NewMetricsHandler creates a new metrics handler
+func NewMetricsHandler() *MetricsHandler {
+	return &MetricsHandler{}
+}
+
+// GetCacheStats handles GET /metrics/cache
+func (h *MetricsHandler) GetCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": cache.AllStats(),
+	})
+}
@@ -0,0 +1,530 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/raytr/go-template/internal/auth"
+	"github.com/raytr/go-template/internal/flags"
+	"github.com/raytr/go-template/internal/i18n"
+	"github.com/raytr/go-template/internal/idempotency"
+	"github.com/raytr/go-template/internal/model"
+	"github.com/raytr/go-template/internal/openapi"
+	"github.com/raytr/go-template/internal/problem"
+	"github.com/raytr/go-template/internal/ratelimit"
+	"github.com/raytr/go-template/internal/repository"
+	"github.com/raytr/go-template/internal/requestid"
+)
+
+// RequestIDMiddleware propagates the caller's X-Request-ID, or generates one,
+// and stores it in the request context
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestid.Header)
+		if id == "" || len(id) > 100 {
+			id = requestid.New()
+		}
+
+		c.Header(requestid.Header, id)
+		c.Request = c.Request.WithContext(requestid.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// AuthMiddleware authenticates the request and stores the resulting principal
+// in the request context. It accepts "Authorization: Bearer <jwt>",
+// "Authorization: ApiKey <key>" or "X-API-Key: <key>". Invalid credentials get
+// a 401; failures to look them up get a 503.
+func AuthMiddleware(authenticator *auth.Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var (
+			principal *auth.Principal
+			err       error
+		)
+
+		header := c.GetHeader("Authorization")
+		if token, ok := strings.CutPrefix(header, "Bearer "); ok {
+			principal, err = authenticator.AuthenticateJWT(token)
+		} else if key, ok := strings.CutPrefix(header, "ApiKey "); ok {
+			principal, err = authenticator.AuthenticateAPIKey(key)
+		} else if key := c.GetHeader("X-API-Key"); key != "" {
+			principal, err = authenticator.AuthenticateAPIKey(key)
+		} else {
+			c.Next()
+			return
+		}
+
+		if errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, auth.ErrInvalidAPIKey) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": err.Error(),
+				"code":  auth.ReasonUnauthenticated,
+			})
+			return
+		}
+		if err != nil {
+			// The credentials may be fine; the roles or the key could not be loaded
+			log.Printf("Authentication error: %v", err)
+			respondWithCode(c, http.StatusServiceUnavailable, "authentication_unavailable")
+			c.Abort()
+			return
+		}
+
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+		c.Next()
+	}
+}
+
+// RequirePermission declares the permission a route needs
+func RequirePermission(policy *auth.Policy, perm auth.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := policy.Authorize(c.Request.Context(), perm); err != nil {
+			abortWithAuthError(c, err)
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireOwnedPermission declares the permission a route needs, also accepting
+// the ":own" variant when the user ID in the named path parameter is the caller's
+func RequireOwnedPermission(policy *auth.Policy, perm auth.Permission, param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ownerID, err := strconv.ParseUint(c.Param(param), 10, 32)
+		if err != nil {
+			// Only holders of the unrestricted permission get to see the 400
+			ownerID = 0
+		}
+
+		if err := policy.AuthorizeOwned(c.Request.Context(), perm, uint(ownerID)); err != nil {
+			abortWithAuthError(c, err)
+			return
+		}
+		c.Next()
+	}
+}
+
+// abortWithAuthError writes the response for an authorization failure.
+// It reports whether err was an authorization failure.
+func abortWithAuthError(c *gin.Context, err error) bool {
+	var denied *auth.DeniedError
+	if !errors.As(err, &denied) {
+		return false
+	}
+
+	status := http.StatusForbidden
+	if denied.Reason == auth.ReasonUnauthenticated {
+		status = http.StatusUnauthorized
+	}
+
+	c.AbortWithStatusJSON(status, gin.H{
+		"error": denied.Error(),
+		"code":  denied.Reason,
+	})
+	return true
+}
+
+// LocaleMiddleware negotiates the response language from Accept-Language and
+// stores its localizer in the request context
+func LocaleMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		translator := i18n.Negotiate(c.GetHeader("Accept-Language"))
+
+		c.Header("Content-Language", translator.Locale())
+		c.Header("Vary", "Accept-Language")
+		c.Request = c.Request.WithContext(i18n.WithLocalizer(c.Request.Context(), translator))
+		c.Next()
+	}
+}
+
+// CORS headers sent to allowed origins
+const (
+	corsAllowMethods  = "GET, POST, PUT, DELETE, OPTIONS"
+	corsAllowHeaders  = "Authorization, X-API-Key, Content-Type, Accept-Language, Idempotency-Key, X-Request-ID"
+	corsExposeHeaders = "Content-Language, Location, X-Request-ID, Idempotent-Replayed, Retry-After, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset"
+	corsMaxAge        = "600"
+)
+
+// CORSPolicy holds the origins allowed to call the API from a browser. They can
+// change while the server runs.
+type CORSPolicy struct {
+	mu      sync.RWMutex
+	any     bool
+	origins map[string]bool
+}
+
+// NewCORSPolicy creates a policy allowing origins. "*" allows every origin and
+// an empty list none.
+func NewCORSPolicy(origins []string) *CORSPolicy {
+	p := &CORSPolicy{}
+	p.SetOrigins(origins)
+	return p
+}
+
+// SetOrigins replaces the allowed origins
+func (p *CORSPolicy) SetOrigins(origins []string) {
+	allowed := make(map[string]bool, len(origins))
+	anyOrigin := false
+	for _, origin := range origins {
+		if origin == "*" {
+			anyOrigin = true
+		}
+		allowed[strings.TrimSuffix(origin, "/")] = true
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.any = anyOrigin
+	p.origins = allowed
+}
+
+// Allows reports whether requests from origin are allowed
+func (p *CORSPolicy) Allows(origin string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.any || p.origins[origin]
+}
+
+// CORSMiddleware adds CORS headers for allowed origins and answers their
+// preflight requests. Preflight requests from other origins get 403.
+func CORSMiddleware(policy *CORSPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		c.Writer.Header().Add("Vary", "Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		if !policy.Allows(origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		c.Header("Access-Control-Allow-Origin", origin)
+		if preflight {
+			c.Header("Access-Control-Allow-Methods", corsAllowMethods)
+			c.Header("Access-Control-Allow-Headers", corsAllowHeaders)
+			c.Header("Access-Control-Max-Age", corsMaxAge)
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Header("Access-Control-Expose-Headers", corsExposeHeaders)
+		c.Next()
+	}
+}
+
+// RateLimitMiddleware enforces the limiter's rules per client and route. Clients
+// are identified by API key, then user, then IP address.
+func RateLimitMiddleware(limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result, limited, err := limiter.Allow(c.Request.Context(), clientIdentity(c), c.Request.Method, c.FullPath())
+		if err != nil {
+			// Fail open: an unavailable backend should not take the API down
+			log.Printf("Rate limiter error: %v", err)
+			c.Next()
+			return
+		}
+
+		if !limited {
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error": "Rate limit exceeded",
+				"code":  "rate_limited",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// clientIdentity identifies the caller by API key, then user, then IP address
+func clientIdentity(c *gin.Context) string {
+	if principal, ok := auth.PrincipalFromContext(c.Request.Context()); ok {
+		if principal.APIKeyID != 0 {
+			return fmt.Sprintf("key:%d", principal.APIKeyID)
+		}
+		if principal.UserID != 0 {
+			return fmt.Sprintf("user:%d", principal.UserID)
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// FeatureFlagMiddleware evaluates feature flags for the caller and stores them in
+// the request context, where flags.Enabled and flags.VariantOf read them. The
+// X-Feature-Flags header overrides flags, e.g. "new-search=on, checkout=variant-b",
+// when allowOverrides is set or the caller may manage flags.
+func FeatureFlagMiddleware(store *flags.Store, allowOverrides bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		subject := flags.Subject{ID: clientIdentity(c)}
+		principal, ok := auth.PrincipalFromContext(c.Request.Context())
+		if ok {
+			subject.UserID, subject.Roles = principal.UserID, principal.Roles
+		}
+
+		var overrides map[string]string
+		if header := c.GetHeader("X-Feature-Flags"); header != "" {
+			if allowOverrides || (ok && principal.HasPermission(auth.PermFlagsManage)) {
+				overrides = flags.ParseOverrides(header)
+			}
+		}
+
+		set := store.For(subject, overrides)
+		c.Request = c.Request.WithContext(flags.NewContext(c.Request.Context(), set))
+		c.Next()
+	}
+}
+
+// BodyLimitMiddleware caps request bodies at maxBytes. Reading past the limit
+// fails with *http.MaxBytesError, which abortWithBindingError turns into a 413.
+func BodyLimitMiddleware(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		c.Next()
+	}
+}
+
+// readBody reads the whole request body and puts a copy back for the handler
+func readBody(c *gin.Context) ([]byte, error) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// IdempotencyMiddleware makes POST requests that carry an Idempotency-Key safe
+// to retry. The first request with a key runs and its response is stored; retries
+// with the same body get the stored response back, a different body gets a 422,
+// and a retry while the first request is still running gets a 409. Keys are
+// scoped to the caller and path. Bodies over the size limit get a 413 and no key.
+// It goes after the permission checks and validation of a route, so that their
+// rejections are not stored.
+func IdempotencyMiddleware(repo *repository.IdempotencyRepository, cfg idempotency.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(idempotency.Header)
+		if c.Request.Method != http.MethodPost || key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > idempotency.MaxKeyLength {
+			respondWithCode(c, http.StatusBadRequest, "invalid_idempotency_key")
+			c.Abort()
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, cfg.MaxBodyBytes)
+		body, err := readBody(c)
+		if err != nil {
+			abortWithBindingError(c, err)
+			return
+		}
+
+		now := time.Now()
+		rec := &model.IdempotencyKeyEntity{
+			Scope:       clientIdentity(c) + " " + c.Request.URL.Path,
+			Key:         key,
+			Fingerprint: idempotency.Fingerprint(c.Request.Method, c.Request.URL.Path, body),
+			LockedAt:    now,
+			ExpiresAt:   now.Add(cfg.TTL),
+		}
+
+		acquired, existing, err := repo.Acquire(rec, now.Add(-cfg.LockTimeout))
+		if err != nil {
+			// Without the key the request could run twice, so it does not run at all
+			log.Printf("Idempotency store error: %v", err)
+			respondWithCode(c, http.StatusServiceUnavailable, "idempotency_unavailable")
+			c.Abort()
+			return
+		}
+
+		if !acquired {
+			switch {
+			case existing.Fingerprint != rec.Fingerprint:
+				respondWithCode(c, http.StatusUnprocessableEntity, "idempotency_key_mismatch")
+			case existing.Status != model.IdempotencyStatusCompleted:
+				c.Header("Retry-After", "1")
+				respondWithCode(c, http.StatusConflict, "idempotency_key_in_use")
+			default:
+				for name, value := range existing.ResponseHeaders {
+					c.Header(name, value)
+				}
+				c.Header(idempotency.ReplayedHeader, "true")
+				c.Data(existing.ResponseStatus, existing.ResponseHeaders["Content-Type"], existing.ResponseBody)
+			}
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		completed := false
+		defer func() {
+			// Free the key when the handler panics so that the client can retry
+			if !completed {
+				if err := repo.Release(rec.ID); err != nil {
+					log.Printf("Idempotency store error: %v", err)
+				}
+			}
+		}()
+
+		c.Next()
+
+		// Server errors and authorization failures are not stored, so that a
+		// retry runs the request again once the server or the caller's access is fixed
+		if !storable(recorder.Status()) {
+			return
+		}
+
+		headers := map[string]string{}
+		for _, name := range []string{"Content-Type", "Content-Language", "Location"} {
+			if value := recorder.Header().Get(name); value != "" {
+				headers[name] = value
+			}
+		}
+		if err := repo.Complete(rec.ID, recorder.Status(), headers, recorder.body.Bytes()); err != nil {
+			log.Printf("Idempotency store error: %v", err)
+			return
+		}
+		completed = true
+	}
+}
+
+// storable reports whether a response with status is stored for replay
+func storable(status int) bool {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return false
+	case status == http.StatusUnauthorized, status == http.StatusForbidden:
+		return false
+	}
+	return true
+}
+
+// OpenAPIValidationMiddleware checks requests, responses or both against the
+// OpenAPI document. With validateRequests, the path, query and JSON body of
+// requests are validated and violations are rejected with a problem+json list of
+// field errors. With validateResponses, JSON responses are checked and violations
+// are logged; this is meant for development and tests.
+func OpenAPIValidationMiddleware(specHandler *SpecHandler, validateRequests, validateResponses bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		validator := specHandler.Validator()
+		op, ok := validator.Operation(c.Request.Method, c.FullPath())
+		if !ok {
+			c.Next()
+			return
+		}
+
+		if validateRequests {
+			pathParams := make(map[string]string, len(c.Params))
+			for _, param := range c.Params {
+				pathParams[param.Key] = param.Value
+			}
+			violations := validator.ValidateParameters(op, pathParams, c.Request.URL.Query())
+
+			if op.RequestBody != nil {
+				body, err := readBody(c)
+				if err != nil {
+					abortWithBindingError(c, err)
+					return
+				}
+				violations = append(violations, validator.ValidateRequestBody(op, body)...)
+			}
+
+			if len(violations) > 0 {
+				l := i18n.FromContext(c.Request.Context())
+				problem.Abort(c, problem.Validation(l, c.Request.URL.Path, toFieldErrors(l, violations)))
+				return
+			}
+		}
+
+		if !validateResponses {
+			c.Next()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "application/json") {
+			return
+		}
+		for _, v := range validator.ValidateResponseBody(op, recorder.Status(), recorder.body.Bytes()) {
+			log.Printf("Response of %s %s violates the OpenAPI spec: %s %s (%s)", c.Request.Method, c.FullPath(), v.Field, v.Message, v.Rule)
+		}
+	}
+}
+
+func toFieldErrors(l i18n.Localizer, violations []openapi.Violation) []problem.FieldError {
+	errs := make([]problem.FieldError, len(violations))
+	for i, v := range violations {
+		errs[i] = problem.FieldError{Field: v.Field, Rule: v.Rule, Message: problem.RuleMessage(l, v.Rule, v.Kind, v.Param)}
+	}
+	return errs
+}
+
+// abortWithBindingError writes a problem+json response for a request that could
+// not be bound or failed its binding rules, or a 413 when its body is too large
+func abortWithBindingError(c *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		respondWithCode(c, http.StatusRequestEntityTooLarge, "request_too_large")
+		c.Abort()
+		return
+	}
+
+	l := i18n.FromContext(c.Request.Context())
+	problem.Abort(c, problem.FromBindingError(l, c.Request.URL.Path, err))
+}
+
+// responseRecorder keeps a copy of the response body while writing it through
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
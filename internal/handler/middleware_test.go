@@ -0,0 +1,310 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/raytr/go-template/internal/auth"
+	"github.com/raytr/go-template/internal/idempotency"
+	"github.com/raytr/go-template/internal/repository"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newMockDB returns a Postgres handle backed by sqlmock. Statements run
+// without GORM's default transaction.
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		conn.Close()
+	})
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{
+		Logger:                 logger.Discard,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	return db, mock
+}
+
+const (
+	acquireKey  = `INSERT INTO idempotency_keys`
+	findKey     = `SELECT \* FROM "idempotency_keys"`
+	completeKey = `UPDATE "idempotency_keys" SET`
+	releaseKey  = `DELETE FROM "idempotency_keys"`
+	thingBody   = `{"name":"thing"}`
+)
+
+var keyColumns = []string{"id", "scope", "key", "fingerprint", "status", "response_status", "response_headers", "response_body", "locked_at", "expires_at", "created_at"}
+
+// newIdempotentRouter serves POST /things behind the idempotency middleware,
+// after handlers, with a handler answering status
+func newIdempotentRouter(db *gorm.DB, status int, handlers ...gin.HandlerFunc) (*gin.Engine, *int) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	calls := 0
+	handlers = append(handlers,
+		IdempotencyMiddleware(repository.NewIdempotencyRepository(db), idempotency.Config{
+			TTL: time.Hour, LockTimeout: time.Minute, MaxBodyBytes: 1 << 20,
+		}),
+		func(c *gin.Context) {
+			calls++
+			c.JSON(status, gin.H{"name": "thing"})
+		})
+	router.POST("/things", handlers...)
+	return router, &calls
+}
+
+func postThing(router *gin.Engine, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/things", strings.NewReader(body))
+	req.Header.Set(idempotency.Header, "key-1")
+	req.RemoteAddr = "192.0.2.1:1234"
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotencyMiddlewareStoresResponse(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectQuery(acquireKey).
+		WithArgs("ip:192.0.2.1 /things", "key-1", idempotency.Fingerprint(http.MethodPost, "/things", []byte(thingBody)),
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectExec(completeKey).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	router, calls := newIdempotentRouter(db, http.StatusCreated)
+	if rec := postThing(router, thingBody); rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+	if *calls != 1 {
+		t.Errorf("handler ran %d times, want 1", *calls)
+	}
+}
+
+func TestIdempotencyMiddlewareReplaysResponse(t *testing.T) {
+	db, mock := newMockDB(t)
+	now := time.Now()
+	fingerprint := idempotency.Fingerprint(http.MethodPost, "/things", []byte(thingBody))
+	mock.ExpectQuery(acquireKey).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(findKey).WillReturnRows(sqlmock.NewRows(keyColumns).
+		AddRow(7, "ip:192.0.2.1 /things", "key-1", fingerprint, "completed", 201,
+			`{"Content-Type":"application/json; charset=utf-8"}`, []byte(`{"name":"stored"}`), now, now, now))
+
+	router, calls := newIdempotentRouter(db, http.StatusCreated)
+	rec := postThing(router, thingBody)
+
+	if rec.Code != http.StatusCreated || rec.Body.String() != `{"name":"stored"}` {
+		t.Errorf("response = %d %s, want the stored 201", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get(idempotency.ReplayedHeader); got != "true" {
+		t.Errorf("%s = %q, want true", idempotency.ReplayedHeader, got)
+	}
+	if *calls != 0 {
+		t.Errorf("handler ran %d times, want 0", *calls)
+	}
+}
+
+func TestIdempotencyMiddlewareRejectsOtherBody(t *testing.T) {
+	db, mock := newMockDB(t)
+	now := time.Now()
+	mock.ExpectQuery(acquireKey).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(findKey).WillReturnRows(sqlmock.NewRows(keyColumns).
+		AddRow(7, "ip:192.0.2.1 /things", "key-1", "another fingerprint", "completed", 201, nil, nil, now, now, now))
+
+	router, calls := newIdempotentRouter(db, http.StatusCreated)
+	rec := postThing(router, thingBody)
+
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "idempotency_key_mismatch") {
+		t.Errorf("response = %d %s, want 422 idempotency_key_mismatch", rec.Code, rec.Body)
+	}
+	if *calls != 0 {
+		t.Errorf("handler ran %d times, want 0", *calls)
+	}
+}
+
+func TestIdempotencyMiddlewareReportsKeyInUse(t *testing.T) {
+	db, mock := newMockDB(t)
+	now := time.Now()
+	fingerprint := idempotency.Fingerprint(http.MethodPost, "/things", []byte(thingBody))
+	mock.ExpectQuery(acquireKey).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(findKey).WillReturnRows(sqlmock.NewRows(keyColumns).
+		AddRow(7, "ip:192.0.2.1 /things", "key-1", fingerprint, "processing", nil, nil, nil, now, now, now))
+
+	router, _ := newIdempotentRouter(db, http.StatusCreated)
+	rec := postThing(router, thingBody)
+
+	if rec.Code != http.StatusConflict || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("response = %d with Retry-After %q, want 409 with Retry-After", rec.Code, rec.Header().Get("Retry-After"))
+	}
+}
+
+func TestIdempotencyMiddlewareReleasesUnstoredResponses(t *testing.T) {
+	for _, status := range []int{http.StatusForbidden, http.StatusUnauthorized, http.StatusInternalServerError} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			db, mock := newMockDB(t)
+			mock.ExpectQuery(acquireKey).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+			mock.ExpectExec(releaseKey).WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+
+			router, _ := newIdempotentRouter(db, status)
+			if rec := postThing(router, thingBody); rec.Code != status {
+				t.Errorf("status = %d, want %d", rec.Code, status)
+			}
+		})
+	}
+}
+
+func TestIdempotencyMiddlewareRunsAfterPermissionCheck(t *testing.T) {
+	// No statement is expected: a denied request takes no key
+	db, _ := newMockDB(t)
+
+	router, calls := newIdempotentRouter(db, http.StatusCreated, RequirePermission(auth.NewPolicy(), auth.PermUsersCreate))
+	if rec := postThing(router, thingBody); rec.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if *calls != 0 {
+		t.Errorf("handler ran %d times, want 0", *calls)
+	}
+}
+
+func TestRouterTakesIdempotencyKeysAfterPermissionChecks(t *testing.T) {
+	// The router's database is not usable, so taking a key would panic
+	router := newTestRouter(t)
+
+	for _, path := range []string{"/api/v1/users", "/api/v1/api-keys", "/api/v1/webhooks", "/api/v1/webhook-deliveries/1/redeliver"} {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(thingBody))
+		req.Header.Set(idempotency.Header, "key-1")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("POST %s status = %d, want %d", path, rec.Code, http.StatusUnauthorized)
+		}
+	}
+}
+
+func bearerToken(t *testing.T, userID string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   userID,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}).SignedString([]byte("test-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + token
+}
+
+// newAuthRouter serves GET /users/:id behind AuthMiddleware and
+// RequireOwnedPermission for users:read
+func newAuthRouter(db *gorm.DB) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	authenticator := auth.NewAuthenticator("test-secret", repository.NewRBACRepository(db), repository.NewAPIKeyRepository(db))
+	router.GET("/users/:id",
+		AuthMiddleware(authenticator),
+		RequireOwnedPermission(auth.NewPolicy(), auth.PermUsersRead, "id"),
+		func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func getUser(router *gin.Engine, id, authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/users/"+id, nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAuthMiddlewareReportsStoreFailures(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectQuery(`FROM "roles"`).WillReturnError(errors.New(`pq: relation "user_roles" does not exist`))
+
+	rec := getUser(newAuthRouter(db), "7", bearerToken(t, "7"))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `"code":"authentication_unavailable"`) || strings.Contains(body, "user_roles") {
+		t.Errorf("body = %s, want a generic authentication_unavailable error", body)
+	}
+}
+
+func TestAuthMiddlewareRejectsInvalidCredentials(t *testing.T) {
+	db, _ := newMockDB(t)
+	router := newAuthRouter(db)
+
+	for name, authorization := range map[string]string{
+		"bad token":   "Bearer not-a-token",
+		"bad api key": "ApiKey not-a-key",
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec := getUser(router, "7", authorization)
+			if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), `"code":"unauthenticated"`) {
+				t.Errorf("status = %d, body = %s, want 401 unauthenticated", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequireOwnedPermission(t *testing.T) {
+	tests := []struct {
+		name        string
+		permissions []string
+		id          string
+		status      int
+		code        string
+	}{
+		{"own record", []string{"users:read:own"}, "7", http.StatusOK, ""},
+		{"someone else's record", []string{"users:read:own"}, "8", http.StatusForbidden, auth.ReasonNotResourceOwner},
+		{"unparsable id", []string{"users:read:own"}, "abc", http.StatusForbidden, auth.ReasonNotResourceOwner},
+		{"no permission", []string{"users:list"}, "7", http.StatusForbidden, auth.ReasonMissingPermission},
+		{"unrestricted permission", []string{"users:read"}, "8", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			mock.ExpectQuery(`FROM "roles"`).WithArgs(7).WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("custom"))
+			rows := sqlmock.NewRows([]string{"name"})
+			for _, perm := range tt.permissions {
+				rows.AddRow(perm)
+			}
+			mock.ExpectQuery(`FROM "permissions"`).WithArgs("custom").WillReturnRows(rows)
+
+			rec := getUser(newAuthRouter(db), tt.id, bearerToken(t, "7"))
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body = %s", rec.Code, tt.status, rec.Body.String())
+			}
+			if tt.code != "" && !strings.Contains(rec.Body.String(), `"code":"`+tt.code+`"`) {
+				t.Errorf("body = %s, want code %s", rec.Body.String(), tt.code)
+			}
+		})
+	}
+}
+
+func TestRequireOwnedPermissionAnonymous(t *testing.T) {
+	db, _ := newMockDB(t)
+	rec := getUser(newAuthRouter(db), "7", "")
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,300 @@
+package handler
+
+import (
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/raytr/go-template/api"
+	"github.com/raytr/go-template/internal/auth"
+	"github.com/raytr/go-template/internal/cache"
+	"github.com/raytr/go-template/internal/flags"
+	"github.com/raytr/go-template/internal/model"
+	"github.com/raytr/go-template/internal/openapi"
+)
+
+// HealthResponse represents the response of the health check endpoint
+type HealthResponse struct {
+	Status string `json:"status" binding:"required"`
+}
+
+// apiOperations documents every route registered in SetupRouter. A route
+// without an entry here is reported by SpecHandler.Build and fails the tests.
+var apiOperations = map[string]openapi.Operation{
+	// Users
+	"POST /api/v1/users": {
+		ID: "createUser", Summary: "Create a user", Tags: []string{"users"},
+		Idempotent:  true,
+		Permissions: perms(auth.PermUsersCreate),
+		Request:     &model.CreateUserReq{}, Response: &model.UserResponse{},
+		Status: http.StatusCreated, Errors: []int{http.StatusBadRequest, http.StatusConflict},
+	},
+	"GET /api/v1/users": {
+		ID: "listUsers", Summary: "List users", Tags: []string{"users"},
+		Permissions: perms(auth.PermUsersList),
+		Query:       []interface{}{&model.PaginationRequest{}},
+		Response:    &model.UserResponse{}, List: true, Paginated: true,
+		Errors: []int{http.StatusBadRequest},
+	},
+	"GET /api/v1/users/:id": {
+		ID: "getUser", Summary: "Get a user", Tags: []string{"users"},
+		Permissions: perms(auth.PermUsersRead, auth.PermUsersReadOwn),
+		Response:    &model.UserResponse{},
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	"GET /api/v1/users/by-email/:email": {
+		ID: "getUserByEmail", Summary: "Get a user by email", Tags: []string{"users"},
+		Description: "The email is matched case-insensitively.",
+		Permissions: perms(auth.PermUsersRead, auth.PermUsersReadOwn),
+		Response:    &model.UserResponse{},
+		Errors:      []int{http.StatusNotFound},
+	},
+	"GET /api/v1/users/code/:code": {
+		ID: "getUserByCode", Summary: "Get a user by code", Tags: []string{"users"},
+		Permissions: perms(auth.PermUsersRead, auth.PermUsersReadOwn),
+		Response:    &model.UserResponse{},
+		Errors:      []int{http.StatusNotFound},
+	},
+	"PUT /api/v1/users/code/:code": {
+		ID: "upsertUserByCode", Summary: "Create or replace a user by code", Tags: []string{"users"},
+		Description: "Creates the user (201) or replaces all of its fields (200). Repeating the request is safe. " +
+			"Requires both `users:create` and `users:update`.",
+		Request:       &model.UpsertUserReq{},
+		Response:      &model.UserResponse{},
+		ExtraStatuses: []int{http.StatusCreated},
+		Errors:        []int{http.StatusBadRequest, http.StatusConflict},
+	},
+	"DELETE /api/v1/users/code/:code": {
+		ID: "deleteUserByCode", Summary: "Delete a user by code", Tags: []string{"users"},
+		Permissions: perms(auth.PermUsersDelete),
+		Errors:      []int{http.StatusNotFound},
+	},
+	"PUT /api/v1/users/:id": {
+		ID: "updateUser", Summary: "Update a user", Tags: []string{"users"},
+		Permissions: perms(auth.PermUsersUpdate, auth.PermUsersUpdateOwn),
+		Request:     &model.UpdateUserReq{}, Response: &model.UserResponse{},
+		Errors: []int{http.StatusBadRequest, http.StatusConflict},
+	},
+	"DELETE /api/v1/users/:id": {
+		ID: "deleteUser", Summary: "Delete a user", Tags: []string{"users"},
+		Permissions: perms(auth.PermUsersDelete),
+		Errors:      []int{http.StatusBadRequest},
+	},
+
+	// API keys
+	"POST /api/v1/api-keys": {
+		ID: "createAPIKey", Summary: "Create an API key", Tags: []string{"api-keys"},
+		Idempotent:  true,
+		Permissions: perms(auth.PermAPIKeysManage),
+		Request:     &model.CreateAPIKeyReq{}, Response: &model.CreateAPIKeyResponse{},
+		Status: http.StatusCreated, Errors: []int{http.StatusBadRequest},
+	},
+	"GET /api/v1/api-keys": {
+		ID: "listAPIKeys", Summary: "List API keys", Tags: []string{"api-keys"},
+		Permissions: perms(auth.PermAPIKeysManage),
+		Query:       []interface{}{&model.PaginationRequest{}},
+		Response:    &model.APIKeyResponse{}, List: true, Paginated: true,
+		Errors: []int{http.StatusBadRequest},
+	},
+	"DELETE /api/v1/api-keys/:id": {
+		ID: "revokeAPIKey", Summary: "Revoke an API key", Tags: []string{"api-keys"},
+		Permissions: perms(auth.PermAPIKeysManage),
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+
+	// Webhooks
+	"POST /api/v1/webhooks": {
+		ID: "createWebhook", Summary: "Create a webhook subscription", Tags: []string{"webhooks"},
+		Idempotent:  true,
+		Permissions: perms(auth.PermWebhooksManage),
+		Request:     &model.CreateWebhookReq{}, Response: &model.CreateWebhookResponse{},
+		Status: http.StatusCreated, Errors: []int{http.StatusBadRequest},
+	},
+	"GET /api/v1/webhooks": {
+		ID: "listWebhooks", Summary: "List webhook subscriptions", Tags: []string{"webhooks"},
+		Permissions: perms(auth.PermWebhooksManage),
+		Query:       []interface{}{&model.PaginationRequest{}},
+		Response:    &model.WebhookResponse{}, List: true, Paginated: true,
+		Errors: []int{http.StatusBadRequest},
+	},
+	"GET /api/v1/webhooks/:id": {
+		ID: "getWebhook", Summary: "Get a webhook subscription", Tags: []string{"webhooks"},
+		Permissions: perms(auth.PermWebhooksManage),
+		Response:    &model.WebhookResponse{},
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	"PUT /api/v1/webhooks/:id": {
+		ID: "updateWebhook", Summary: "Update a webhook subscription", Tags: []string{"webhooks"},
+		Permissions: perms(auth.PermWebhooksManage),
+		Request:     &model.UpdateWebhookReq{}, Response: &model.WebhookResponse{},
+		Errors: []int{http.StatusBadRequest},
+	},
+	"DELETE /api/v1/webhooks/:id": {
+		ID: "deleteWebhook", Summary: "Delete a webhook subscription", Tags: []string{"webhooks"},
+		Permissions: perms(auth.PermWebhooksManage),
+		Errors:      []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	"GET /api/v1/webhook-deliveries": {
+		ID: "listWebhookDeliveries", Summary: "List webhook deliveries", Tags: []string{"webhooks"},
+		Description: "Filter on `status=dead` to list the dead-letter deliveries.",
+		Permissions: perms(auth.PermWebhooksManage),
+		Query:       []interface{}{&model.PaginationRequest{}, &model.WebhookDeliveryFilter{}},
+		Response:    &model.WebhookDeliveryEntity{}, List: true, Paginated: true,
+		Errors: []int{http.StatusBadRequest},
+	},
+	"GET /api/v1/webhook-deliveries/:id/attempts": {
+		ID: "listWebhookDeliveryAttempts", Summary: "List the attempts of a webhook delivery", Tags: []string{"webhooks"},
+		Permissions: perms(auth.PermWebhooksManage),
+		Response:    &model.WebhookDeliveryAttemptEntity{}, List: true,
+		Errors: []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	"POST /api/v1/webhook-deliveries/:id/redeliver": {
+		ID: "redeliverWebhook", Summary: "Redeliver a webhook delivery", Tags: []string{"webhooks"},
+		Idempotent:  true,
+		Permissions: perms(auth.PermWebhooksManage),
+		Status:      http.StatusAccepted, Errors: []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+
+	// Feature flags
+	"GET /api/v1/feature-flags": {
+		ID: "listFeatureFlags", Summary: "List feature flags", Tags: []string{"feature-flags"},
+		Description: "Lists the flags defined in the configuration and in the database. " +
+			"`source` tells which definition applies; a database definition replaces the configured one.",
+		Permissions: perms(auth.PermFlagsManage),
+		Response:    &flags.Flag{}, List: true,
+	},
+	"GET /api/v1/feature-flags/evaluations": {
+		ID: "getFeatureFlagEvaluations", Summary: "Evaluate feature flags for the caller", Tags: []string{"feature-flags"},
+		Description: "Returns the value of every flag for the caller, including the overrides sent in `X-Feature-Flags`.",
+		Response:    &flags.Evaluation{}, List: true,
+	},
+	"PUT /api/v1/feature-flags/:key": {
+		ID: "setFeatureFlag", Summary: "Create or replace a feature flag", Tags: []string{"feature-flags"},
+		Description: "Stores the flag in the database, where it replaces any configured definition. " +
+			"Other replicas apply the change at their next refresh.",
+		Permissions: perms(auth.PermFlagsManage),
+		Request:     &model.SetFeatureFlagReq{}, Response: &flags.Flag{},
+		Errors: []int{http.StatusBadRequest},
+	},
+	"DELETE /api/v1/feature-flags/:key": {
+		ID: "deleteFeatureFlag", Summary: "Delete a stored feature flag", Tags: []string{"feature-flags"},
+		Description: "Removes the database definition. A configured definition of the flag applies again.",
+		Permissions: perms(auth.PermFlagsManage),
+		Errors:      []int{http.StatusNotFound},
+	},
+
+	// Audit
+	"GET /api/v1/audit": {
+		ID: "listAuditEvents", Summary: "List audit events", Tags: []string{"audit"},
+		Permissions: perms(auth.PermAuditRead),
+		Query:       []interface{}{&model.PaginationRequest{}, &model.AuditEventFilter{}},
+		Response:    &model.AuditEventResponse{}, List: true, Paginated: true,
+		Errors: []int{http.StatusBadRequest},
+	},
+
+	// Metrics
+	"GET /api/v1/metrics/cache": {
+		ID: "getCacheStats", Summary: "Cache metrics", Tags: []string{"system"},
+		Description: "Hit, miss and error counters of each cache, by cache name.",
+		Permissions: perms(auth.PermMetricsRead),
+		Response:    map[string]cache.Stats{},
+	},
+
+	// Operations
+	"GET /health": {
+		ID: "health", Summary: "Health check", Tags: []string{"system"},
+		Public: true, Response: &HealthResponse{}, NoEnvelope: true,
+	},
+	"GET /openapi.json": {
+		ID: "openapi", Summary: "OpenAPI document", Tags: []string{"system"},
+		Public: true, NoEnvelope: true,
+	},
+	"GET /docs": {
+		ID: "docs", Summary: "Interactive API documentation", Tags: []string{"system"},
+		Public: true, ContentType: "text/html",
+	},
+	"GET /docs/assets/*filepath": {
+		ID: "docsAsset", Summary: "Documentation page assets", Tags: []string{"system"},
+		Description: "Scripts and styles of `/docs`, including swagger-ui, embedded in the binary.",
+		Public:      true, ContentType: "application/octet-stream",
+		Errors: []int{http.StatusNotFound},
+	},
+}
+
+func perms(permissions ...auth.Permission) []string {
+	names := make([]string, len(permissions))
+	for i, p := range permissions {
+		names[i] = string(p)
+	}
+	return names
+}
+
+// SpecHandler serves the OpenAPI document generated from the router's routes
+type SpecHandler struct {
+	info      openapi.Info
+	once      sync.Once
+	routes    func() gin.RoutesInfo
+	doc       *openapi.Document
+	validator *openapi.Validator
+	missing   []string
+}
+
+// NewSpecHandler creates a new spec handler. The document is generated on first
+// use so that routes registered after the handler are included.
+func NewSpecHandler(title string, routes func() gin.RoutesInfo) *SpecHandler {
+	return &SpecHandler{
+		info:   openapi.Info{Title: title, Version: "1.0.0"},
+		routes: routes,
+	}
+}
+
+// Build generates the document and logs routes that have no spec entry
+func (h *SpecHandler) Build() *openapi.Document {
+	h.once.Do(func() {
+		h.doc, h.missing = openapi.Generate(h.info, h.routes(), apiOperations)
+		h.validator = openapi.NewValidator(h.doc)
+		if len(h.missing) > 0 {
+			log.Printf("Routes missing from the OpenAPI spec: %s", strings.Join(h.missing, ", "))
+		}
+	})
+	return h.doc
+}
+
+// Validator returns a validator for the generated document
+func (h *SpecHandler) Validator() *openapi.Validator {
+	h.Build()
+	return h.validator
+}
+
+// ServeSpec handles GET /openapi.json
+func (h *SpecHandler) ServeSpec(c *gin.Context) {
+	c.JSON(http.StatusOK, h.Build())
+}
+
+// ServeDocs handles GET /docs
+func (h *SpecHandler) ServeDocs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", api.DocsHTML)
+}
+
+// ServeDocsAsset handles GET /docs/assets/*filepath with the files embedded in
+// the binary. Directories are not listed.
+func (h *SpecHandler) ServeDocsAsset(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("filepath"), "/")
+	data, err := fs.ReadFile(api.Assets, name)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Data(http.StatusOK, contentType, data)
+}
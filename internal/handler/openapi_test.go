@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/raytr/go-template/internal/config"
+	"github.com/raytr/go-template/internal/flags"
+	"github.com/raytr/go-template/internal/openapi"
+	"gorm.io/gorm"
+)
+
+func newTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{
+		App:  config.AppConfig{Name: "go-template"},
+		Auth: config.AuthConfig{JWTSecret: "test-secret"},
+	}
+	router, err := SetupRouter(&gorm.DB{}, cfg, config.NewReloader(cfg), flags.NewStore(nil, nil, time.Minute))
+	if err != nil {
+		t.Fatalf("SetupRouter() failed: %v", err)
+	}
+	return router
+}
+
+func TestEveryRouteHasSpecEntry(t *testing.T) {
+	router := newTestRouter(t)
+
+	_, missing := openapi.Generate(openapi.Info{}, router.Routes(), apiOperations)
+	for _, key := range missing {
+		t.Errorf("route %q has no entry in apiOperations", key)
+	}
+}
+
+func TestEverySpecEntryHasRoute(t *testing.T) {
+	router := newTestRouter(t)
+
+	registered := map[string]bool{}
+	for _, route := range router.Routes() {
+		registered[openapi.RouteKey(route.Method, route.Path)] = true
+	}
+
+	for key := range apiOperations {
+		if !registered[key] {
+			t.Errorf("apiOperations entry %q does not match a registered route", key)
+		}
+	}
+}
+
+func TestServeSpec(t *testing.T) {
+	router := newTestRouter(t)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /openapi.json status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var doc openapi.Document
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid OpenAPI document: %v", err)
+	}
+
+	if doc.OpenAPI != openapi.Version {
+		t.Errorf("openapi = %q, want %q", doc.OpenAPI, openapi.Version)
+	}
+
+	createUser := doc.Paths["/api/v1/users"]["post"]
+	if createUser == nil || createUser.RequestBody == nil {
+		t.Fatal("POST /api/v1/users is missing its request body")
+	}
+
+	schema := doc.Components.Schemas["CreateUserReq"]
+	if schema == nil {
+		t.Fatal("CreateUserReq schema is missing")
+	}
+	if schema.Properties["email"].Format != "email" {
+		t.Errorf("CreateUserReq.email format = %q, want email", schema.Properties["email"].Format)
+	}
+	if len(schema.Required) != 3 {
+		t.Errorf("CreateUserReq required = %v, want code, name and email", schema.Required)
+	}
+}
+
+func TestOpenAPIValidationMiddlewareChecksResponsesWithoutRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	spec := NewSpecHandler("test", router.Routes)
+	router.POST("/api/v1/users", OpenAPIValidationMiddleware(spec, false, true), func(c *gin.Context) {
+		c.JSON(http.StatusTeapot, gin.H{"name": "thing"})
+	})
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(`{"code": 1}`)))
+
+	if rec.Code != http.StatusTeapot {
+		t.Fatalf("status = %d, want %d; the request should not be validated", rec.Code, http.StatusTeapot)
+	}
+	if !strings.Contains(logs.String(), "status 418 is not documented") {
+		t.Errorf("response violation was not logged, got %q", logs.String())
+	}
+}
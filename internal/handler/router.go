@@ -1,33 +1,175 @@
 package handler
 
 import (
+	"fmt"
+
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/raytr/go-template/internal/auth"
+	"github.com/raytr/go-template/internal/cache"
+	"github.com/raytr/go-template/internal/config"
+	"github.com/raytr/go-template/internal/flags"
+	"github.com/raytr/go-template/internal/idempotency"
+	"github.com/raytr/go-template/internal/problem"
+	"github.com/raytr/go-template/internal/ratelimit"
 	"github.com/raytr/go-template/internal/repository"
 	"github.com/raytr/go-template/internal/service"
+	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
 
-// SetupRouter configures and returns the Gin router
-func SetupRouter(db *gorm.DB) *gin.Engine {
+// SetupRouter configures and returns the Gin router. Settings that can change
+// at runtime are followed through reloader; feature flags are read from flagStore.
+// It fails when a backend cannot be set up from the configuration.
+func SetupRouter(db *gorm.DB, cfg *config.Config, reloader *config.Reloader, flagStore *flags.Store) (*gin.Engine, error) {
 	router := gin.New()
 
+	// Report binding failures by JSON field name
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		problem.UseJSONFieldNames(v)
+	}
+
+	router.Use(RequestIDMiddleware())
+	router.Use(LocaleMiddleware())
+
+	cors := NewCORSPolicy(cfg.Server.CORSOrigins)
+	reloader.Subscribe(func(c *config.Config) {
+		cors.SetOrigins(c.Server.CORSOrigins)
+	})
+	router.Use(CORSMiddleware(cors))
+
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
+	router.Use(BodyLimitMiddleware(cfg.Server.MaxBodyBytes))
+
+	rbacRepo := repository.NewRBACRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	authenticator := auth.NewAuthenticator(cfg.Auth.JWTSecret.Value(), rbacRepo, apiKeyRepo)
+	policy := auth.NewPolicy()
+
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, policy)
+	apiKeyHandler := NewAPIKeyHandler(apiKeyService)
+
+	transactor := repository.NewTransactor(db)
+	auditRepo := repository.NewAuditRepository(db)
+	auditService := service.NewAuditService(auditRepo, policy)
+	auditHandler := NewAuditHandler(auditService)
+
+	outboxRepo := repository.NewOutboxRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	webhookService := service.NewWebhookService(webhookRepo, policy, cfg.Webhook.AllowPrivateTargets)
+	webhookHandler := NewWebhookHandler(webhookService)
+
+	userNormalizer, err := service.NewUserNormalizer(service.UserRules{
+		PhoneRegion:         cfg.Users.PhoneRegion,
+		CodePattern:         cfg.Users.CodePattern,
+		AllowedEmailDomains: cfg.Users.AllowedEmailDomains,
+		DeniedEmailDomains:  cfg.Users.DeniedEmailDomains,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid user rules: %w", err)
+	}
+
+	userCache, err := newUserCache(cfg)
+	if err != nil {
+		return nil, err
+	}
 
 	userRepo := repository.NewUserRepository(db)
-	userService := service.NewUserService(userRepo)
+	userService := service.NewUserService(userRepo, auditRepo, outboxRepo, webhookRepo, transactor, policy, userNormalizer, userCache)
 	userHandler := NewUserHandler(userService)
 
+	flagService := service.NewFeatureFlagService(repository.NewFeatureFlagRepository(db), policy, flagStore)
+	flagHandler := NewFeatureFlagHandler(flagService)
+
+	// API documentation generated from the routes below. It is built once every
+	// route is registered.
+	specHandler := NewSpecHandler(cfg.App.Name, router.Routes)
+
 	v1 := router.Group("/api/v1")
+	v1.Use(AuthMiddleware(authenticator))
+	v1.Use(FeatureFlagMiddleware(flagStore, cfg.FeatureFlags.HeaderOverrides))
+	if cfg.RateLimit.Enabled {
+		store, err := newRateLimitStore(db, cfg)
+		if err != nil {
+			return nil, err
+		}
+		limiter := ratelimit.NewLimiter(store, cfg.RateLimit.Default, cfg.RateLimit.Routes)
+		reloader.Subscribe(func(c *config.Config) {
+			limiter.SetRules(c.RateLimit.Default, c.RateLimit.Routes)
+		})
+		v1.Use(RateLimitMiddleware(limiter))
+	}
+
+	// Requests are validated as the last step before each handler, after the
+	// permission checks, so that callers without access learn nothing of the schema
+	validate := func(c *gin.Context) { c.Next() }
+	if cfg.Validation.Requests || cfg.Validation.Responses {
+		validate = OpenAPIValidationMiddleware(specHandler, cfg.Validation.Requests, cfg.Validation.Responses)
+	}
+
+	// Idempotency keys are taken after the permission checks and validation, so
+	// that their rejections are never stored and replayed
+	idempotent := IdempotencyMiddleware(repository.NewIdempotencyRepository(db), idempotency.Config{
+		TTL:          cfg.Idempotency.TTL,
+		LockTimeout:  cfg.Idempotency.LockTimeout,
+		MaxBodyBytes: cfg.Server.MaxBodyBytes,
+	})
 	{
 		users := v1.Group("/users")
 		{
-			users.POST("", userHandler.CreateUser)
-			users.GET("", userHandler.GetAllUsers)
-			users.GET("/:id", userHandler.GetUser)
-			users.PUT("/:id", userHandler.UpdateUser)
-			users.DELETE("/:id", userHandler.DeleteUser)
+			users.POST("", RequirePermission(policy, auth.PermUsersCreate), validate, idempotent, userHandler.CreateUser)
+			users.GET("", RequirePermission(policy, auth.PermUsersList), validate, userHandler.GetAllUsers)
+			// Ownership is checked by the service once the email or code is resolved
+			users.GET("/by-email/:email", validate, userHandler.GetUserByEmail)
+			users.GET("/code/:code", validate, userHandler.GetUserByCode)
+			users.PUT("/code/:code", RequirePermission(policy, auth.PermUsersCreate), RequirePermission(policy, auth.PermUsersUpdate), validate, userHandler.UpsertUserByCode)
+			users.DELETE("/code/:code", RequirePermission(policy, auth.PermUsersDelete), validate, userHandler.DeleteUserByCode)
+			users.GET("/:id", RequireOwnedPermission(policy, auth.PermUsersRead, "id"), validate, userHandler.GetUser)
+			users.PUT("/:id", RequireOwnedPermission(policy, auth.PermUsersUpdate, "id"), validate, userHandler.UpdateUser)
+			users.DELETE("/:id", RequirePermission(policy, auth.PermUsersDelete), validate, userHandler.DeleteUser)
 		}
+
+		apiKeys := v1.Group("/api-keys")
+		apiKeys.Use(RequirePermission(policy, auth.PermAPIKeysManage))
+		{
+			apiKeys.POST("", validate, idempotent, apiKeyHandler.CreateAPIKey)
+			apiKeys.GET("", validate, apiKeyHandler.GetAllAPIKeys)
+			apiKeys.DELETE("/:id", validate, apiKeyHandler.RevokeAPIKey)
+		}
+
+		webhooks := v1.Group("/webhooks")
+		webhooks.Use(RequirePermission(policy, auth.PermWebhooksManage))
+		{
+			webhooks.POST("", validate, idempotent, webhookHandler.CreateWebhook)
+			webhooks.GET("", validate, webhookHandler.GetAllWebhooks)
+			webhooks.GET("/:id", validate, webhookHandler.GetWebhook)
+			webhooks.PUT("/:id", validate, webhookHandler.UpdateWebhook)
+			webhooks.DELETE("/:id", validate, webhookHandler.DeleteWebhook)
+		}
+
+		deliveries := v1.Group("/webhook-deliveries")
+		deliveries.Use(RequirePermission(policy, auth.PermWebhooksManage))
+		{
+			deliveries.GET("", validate, webhookHandler.GetDeliveries)
+			deliveries.GET("/:id/attempts", validate, webhookHandler.GetDeliveryAttempts)
+			deliveries.POST("/:id/redeliver", validate, idempotent, webhookHandler.Redeliver)
+		}
+
+		featureFlags := v1.Group("/feature-flags")
+		{
+			// Any caller may read its own flags
+			featureFlags.GET("/evaluations", validate, flagHandler.GetEvaluations)
+			featureFlags.GET("", RequirePermission(policy, auth.PermFlagsManage), validate, flagHandler.GetAllFlags)
+			featureFlags.PUT("/:key", RequirePermission(policy, auth.PermFlagsManage), validate, flagHandler.SetFlag)
+			featureFlags.DELETE("/:key", RequirePermission(policy, auth.PermFlagsManage), validate, flagHandler.DeleteFlag)
+		}
+
+		v1.GET("/audit", RequirePermission(policy, auth.PermAuditRead), validate, auditHandler.GetAuditEvents)
+
+		// Cache hit and miss counters
+		v1.GET("/metrics/cache", RequirePermission(policy, auth.PermMetricsRead), validate, NewMetricsHandler().GetCacheStats)
 	}
 
 	// Health check endpoint
@@ -37,5 +179,46 @@ func SetupRouter(db *gorm.DB) *gin.Engine {
 		})
 	})
 
-	return router
+	router.GET("/openapi.json", specHandler.ServeSpec)
+	router.GET("/docs", specHandler.ServeDocs)
+	router.GET("/docs/assets/*filepath", specHandler.ServeDocsAsset)
+	specHandler.Build()
+
+	return router, nil
+}
+
+// newRateLimitStore creates the rate limit backend selected by RATE_LIMIT_BACKEND
+func newRateLimitStore(db *gorm.DB, cfg *config.Config) (ratelimit.Store, error) {
+	switch cfg.RateLimit.Backend {
+	case "postgres":
+		return ratelimit.NewPostgresStore(db, cfg.RateLimit.IdleTTL), nil
+	case "redis":
+		opts, err := redis.ParseURL(cfg.RateLimit.RedisURL.Value())
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate_limit.redis_url: %w", err)
+		}
+		return ratelimit.NewRedisStore(redis.NewClient(opts), "ratelimit:"), nil
+	default:
+		return ratelimit.NewMemoryStore(cfg.RateLimit.IdleTTL), nil
+	}
+}
+
+// newUserCache creates the user read cache for the backend selected by
+// CACHE_BACKEND. It returns nil when caching is disabled.
+func newUserCache(cfg *config.Config) (*service.UserCache, error) {
+	var store cache.Store
+	switch cfg.Cache.Backend {
+	case "memory":
+		store = cache.NewMemoryStore(cfg.Cache.Size)
+	case "redis":
+		opts, err := redis.ParseURL(cfg.Cache.RedisURL.Value())
+		if err != nil {
+			return nil, fmt.Errorf("invalid cache.redis_url: %w", err)
+		}
+		store = cache.NewRedisStore(redis.NewClient(opts), "cache:")
+	default:
+		return nil, nil
+	}
+
+	return service.NewUserCache(cache.New("users", store, cfg.Cache.TTL)), nil
 }
@@ -28,23 +28,22 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 	var req model.CreateUserReq
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request body: " + err.Error(),
-		})
+		abortWithBindingError(c, err)
 		return
 	}
 
-	user, err := h.userService.CreateUser(&req)
+	user, err := h.userService.CreateUser(c.Request.Context(), &req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		if abortWithAuthError(c, err) {
+			return
+		}
+		respondWithError(c, http.StatusBadRequest, err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
 		"data":    user.ToResponse(),
-		"message": "User created successfully",
+		"message": message(c, "user.created"),
 	})
 }
 
@@ -53,18 +52,17 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 	idStr := c.Param("id")
 	id64, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid user ID",
-		})
+		respondWithCode(c, http.StatusBadRequest, "invalid_user_id")
 		return
 	}
 	id := uint(id64)
 
-	user, err := h.userService.GetUserByID(id)
+	user, err := h.userService.GetUserByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": err.Error(),
-		})
+		if abortWithAuthError(c, err) {
+			return
+		}
+		respondWithError(c, http.StatusNotFound, err)
 		return
 	}
 
@@ -73,6 +71,81 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 	})
 }
 
+// GetUserByEmail handles GET /users/by-email/:email
+func (h *UserHandler) GetUserByEmail(c *gin.Context) {
+	user, err := h.userService.GetUserByEmail(c.Request.Context(), c.Param("email"))
+	if err != nil {
+		if abortWithAuthError(c, err) {
+			return
+		}
+		respondWithError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": user.ToResponse(),
+	})
+}
+
+// GetUserByCode handles GET /users/code/:code
+func (h *UserHandler) GetUserByCode(c *gin.Context) {
+	user, err := h.userService.GetUserByCode(c.Request.Context(), c.Param("code"))
+	if err != nil {
+		if abortWithAuthError(c, err) {
+			return
+		}
+		respondWithError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": user.ToResponse(),
+	})
+}
+
+// UpsertUserByCode handles PUT /users/code/:code
+func (h *UserHandler) UpsertUserByCode(c *gin.Context) {
+	var req model.UpsertUserReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithBindingError(c, err)
+		return
+	}
+
+	user, created, err := h.userService.UpsertUserByCode(c.Request.Context(), c.Param("code"), &req)
+	if err != nil {
+		if abortWithAuthError(c, err) {
+			return
+		}
+		respondWithError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	status, msg := http.StatusOK, message(c, "user.updated")
+	if created {
+		status, msg = http.StatusCreated, message(c, "user.created")
+	}
+
+	c.JSON(status, gin.H{
+		"data":    user.ToResponse(),
+		"message": msg,
+	})
+}
+
+// DeleteUserByCode handles DELETE /users/code/:code
+func (h *UserHandler) DeleteUserByCode(c *gin.Context) {
+	if err := h.userService.DeleteUserByCode(c.Request.Context(), c.Param("code")); err != nil {
+		if abortWithAuthError(c, err) {
+			return
+		}
+		respondWithError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": message(c, "user.deleted"),
+	})
+}
+
 // GetAllUsers handles GET /users
 func (h *UserHandler) GetAllUsers(c *gin.Context) {
 	// Parse pagination parameters using base handler
@@ -83,11 +156,12 @@ func (h *UserHandler) GetAllUsers(c *gin.Context) {
 	}
 
 	// Get users from service
-	users, totalCount, err := h.userService.GetAllUsers(pagination.Page, pagination.PageSize)
+	users, totalCount, err := h.userService.GetAllUsers(c.Request.Context(), pagination.Page, pagination.PageSize)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to retrieve users",
-		})
+		if abortWithAuthError(c, err) {
+			return
+		}
+		respondWithCode(c, http.StatusInternalServerError, "users_list_failed")
 		return
 	}
 
@@ -106,32 +180,29 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	idStr := c.Param("id")
 	id64, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid user ID",
-		})
+		respondWithCode(c, http.StatusBadRequest, "invalid_user_id")
 		return
 	}
 	id := uint(id64)
 
 	var req model.UpdateUserReq
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request body: " + err.Error(),
-		})
+		abortWithBindingError(c, err)
 		return
 	}
 
-	user, err := h.userService.UpdateUser(id, &req)
+	user, err := h.userService.UpdateUser(c.Request.Context(), id, &req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		if abortWithAuthError(c, err) {
+			return
+		}
+		respondWithError(c, http.StatusBadRequest, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"data":    user.ToResponse(),
-		"message": "User updated successfully",
+		"message": message(c, "user.updated"),
 	})
 }
 
@@ -140,21 +211,20 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 	idStr := c.Param("id")
 	id64, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid user ID",
-		})
+		respondWithCode(c, http.StatusBadRequest, "invalid_user_id")
 		return
 	}
 	id := uint(id64)
 
-	if err := h.userService.DeleteUser(id); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+	if err := h.userService.DeleteUser(c.Request.Context(), id); err != nil {
+		if abortWithAuthError(c, err) {
+			return
+		}
+		respondWithError(c, http.StatusBadRequest, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "User deleted successfully",
+		"message": message(c, "user.deleted"),
 	})
 }
@@ -0,0 +1,230 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/raytr/go-template/internal/model"
+	"github.com/raytr/go-template/internal/service"
+)
+
+// WebhookHandler handles HTTP requests for webhook subscriptions and deliveries
+type WebhookHandler struct {
+	webhookService *service.WebhookService
+	*PaginationHandler
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(webhookService *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService:    webhookService,
+		PaginationHandler: NewPaginationHandler(),
+	}
+}
+
+// CreateWebhook handles POST /webhooks
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req model.CreateWebhookReq
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithBindingError(c, err)
+		return
+	}
+
+	sub, err := h.webhookService.CreateWebhook(c.Request.Context(), &req)
+	if err != nil {
+		if abortWithAuthError(c, err) {
+			return
+		}
+		respondWithError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": &model.CreateWebhookResponse{
+			WebhookResponse: sub.ToResponse(),
+			Secret:          sub.Secret,
+		},
+		"message": message(c, "webhook.created"),
+	})
+}
+
+// GetWebhook handles GET /webhooks/:id
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	sub, err := h.webhookService.GetWebhookByID(c.Request.Context(), id)
+	if err != nil {
+		if abortWithAuthError(c, err) {
+			return
+		}
+		respondWithError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": sub.ToResponse(),
+	})
+}
+
+// GetAllWebhooks handles GET /webhooks
+func (h *WebhookHandler) GetAllWebhooks(c *gin.Context) {
+	pagination, err := h.ParsePagination(c)
+	if err != nil {
+		h.RespondWithPaginationError(c, err)
+		return
+	}
+
+	subs, totalCount, err := h.webhookService.GetAllWebhooks(c.Request.Context(), pagination.Page, pagination.PageSize)
+	if err != nil {
+		if abortWithAuthError(c, err) {
+			return
+		}
+		respondWithCode(c, http.StatusInternalServerError, "webhooks_list_failed")
+		return
+	}
+
+	subResponses := make([]*model.WebhookResponse, len(subs))
+	for i, sub := range subs {
+		subResponses[i] = sub.ToResponse()
+	}
+
+	h.RespondWithPaginatedData(c, http.StatusOK, subResponses, pagination, totalCount)
+}
+
+// UpdateWebhook handles PUT /webhooks/:id
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	var req model.UpdateWebhookReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithBindingError(c, err)
+		return
+	}
+
+	sub, err := h.webhookService.UpdateWebhook(c.Request.Context(), id, &req)
+	if err != nil {
+		if abortWithAuthError(c, err) {
+			return
+		}
+		respondWithError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    sub.ToResponse(),
+		"message": message(c, "webhook.updated"),
+	})
+}
+
+// DeleteWebhook handles DELETE /webhooks/:id
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(c.Request.Context(), id); err != nil {
+		if abortWithAuthError(c, err) {
+			return
+		}
+		respondWithError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": message(c, "webhook.deleted"),
+	})
+}
+
+// GetDeliveries handles GET /webhook-deliveries
+func (h *WebhookHandler) GetDeliveries(c *gin.Context) {
+	pagination, err := h.ParsePagination(c)
+	if err != nil {
+		h.RespondWithPaginationError(c, err)
+		return
+	}
+
+	var filter model.WebhookDeliveryFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		abortWithBindingError(c, err)
+		return
+	}
+
+	deliveries, totalCount, err := h.webhookService.GetDeliveries(c.Request.Context(), &filter, pagination.Page, pagination.PageSize)
+	if err != nil {
+		if abortWithAuthError(c, err) {
+			return
+		}
+		respondWithCode(c, http.StatusInternalServerError, "webhook_deliveries_list_failed")
+		return
+	}
+
+	h.RespondWithPaginatedData(c, http.StatusOK, deliveries, pagination, totalCount)
+}
+
+// GetDeliveryAttempts handles GET /webhook-deliveries/:id/attempts
+func (h *WebhookHandler) GetDeliveryAttempts(c *gin.Context) {
+	id, ok := parseDeliveryID(c)
+	if !ok {
+		return
+	}
+
+	attempts, err := h.webhookService.GetDeliveryAttempts(c.Request.Context(), id)
+	if err != nil {
+		if abortWithAuthError(c, err) {
+			return
+		}
+		respondWithError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": attempts,
+	})
+}
+
+// Redeliver handles POST /webhook-deliveries/:id/redeliver
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	id, ok := parseDeliveryID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.Redeliver(c.Request.Context(), id); err != nil {
+		if abortWithAuthError(c, err) {
+			return
+		}
+		respondWithError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": message(c, "webhook.redelivery_scheduled"),
+	})
+}
+
+func parseWebhookID(c *gin.Context) (uint, bool) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondWithCode(c, http.StatusBadRequest, "invalid_webhook_id")
+		return 0, false
+	}
+	return uint(id64), true
+}
+
+func parseDeliveryID(c *gin.Context) (uint64, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithCode(c, http.StatusBadRequest, "invalid_webhook_delivery_id")
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,79 @@
+package i18n
+
+var english = map[string]string{
+	// Problem details
+	"validation.title":  "Request validation failed",
+	"validation.detail": "One or more fields are invalid",
+	"malformed.detail":  "The request could not be parsed",
+
+	// Validator tags and OpenAPI rules
+	"rule.required":   "is required",
+	"rule.email":      "must be a valid email address",
+	"rule.url":        "must be a valid URL",
+	"rule.oneof":      "must be one of {0}",
+	"rule.min":        "must be at least {0}",
+	"rule.min.string": "must be at least {0} characters long",
+	"rule.min.array":  "must contain at least {0} items",
+	"rule.max":        "must be at most {0}",
+	"rule.max.string": "must be at most {0} characters long",
+	"rule.max.array":  "must contain at most {0} items",
+	"rule.type":       "must be of type {0}",
+	"rule.datetime":   "must be an RFC 3339 date-time",
+	"rule.json":       "must be valid JSON",
+	"rule.invalid":    "is invalid",
+
+	// Service rules
+	"rule.notblank":     "must not be blank",
+	"rule.pattern":      "must match the format {0}",
+	"rule.email_domain": "must not use the email domain {0}",
+	"rule.e164":         "must be a valid phone number",
+	"rule.unique":       "must not repeat the same {0}",
+	"rule.future":       "must be in the future",
+	"rule.public_url":   "must be an http(s) URL on a public address",
+
+	// Users
+	"user.created": "User created successfully",
+	"user.updated": "User updated successfully",
+	"user.deleted": "User deleted successfully",
+
+	// Feature flags
+	"feature_flag.saved":   "Feature flag saved successfully",
+	"feature_flag.deleted": "Feature flag deleted successfully",
+
+	// API keys
+	"api_key.created": "API key created successfully. Store the key now, it will not be shown again",
+	"api_key.revoked": "API key revoked successfully",
+
+	// Webhooks
+	"webhook.created":              "Webhook created successfully. Store the secret now, it will not be shown again",
+	"webhook.updated":              "Webhook updated successfully",
+	"webhook.deleted":              "Webhook deleted successfully",
+	"webhook.redelivery_scheduled": "Webhook delivery scheduled",
+
+	// Error codes
+	"error.user_not_found":                 "User not found",
+	"error.email_taken":                    "Email is already in use",
+	"error.invalid_user_id":                "Invalid user ID",
+	"error.users_list_failed":              "Failed to retrieve users",
+	"error.invalid_page":                   "page is required and must be >= 1",
+	"error.invalid_page_size":              "page_size is required and must be between 1 and 100",
+	"error.invalid_idempotency_key":        "Idempotency-Key must be at most 255 characters",
+	"error.idempotency_key_mismatch":       "Idempotency-Key was already used with a different request",
+	"error.idempotency_key_in_use":         "A request with this Idempotency-Key is still in progress",
+	"error.idempotency_unavailable":        "Idempotency keys cannot be checked right now, try again later",
+	"error.authentication_unavailable":     "Credentials cannot be checked right now, try again later",
+	"error.feature_flag_not_found":         "Feature flag not found",
+	"error.feature_flags_list_failed":      "Failed to retrieve feature flags",
+	"error.feature_flag_delete_failed":     "Failed to delete the feature flag",
+	"error.request_too_large":              "Request body is too large",
+	"error.api_key_not_found":              "API key not found",
+	"error.invalid_api_key_id":             "Invalid API key ID",
+	"error.api_keys_list_failed":           "Failed to retrieve API keys",
+	"error.webhook_not_found":              "Webhook not found",
+	"error.webhook_delivery_not_found":     "Webhook delivery not found",
+	"error.invalid_webhook_id":             "Invalid webhook ID",
+	"error.invalid_webhook_delivery_id":    "Invalid delivery ID",
+	"error.webhooks_list_failed":           "Failed to retrieve webhooks",
+	"error.webhook_deliveries_list_failed": "Failed to retrieve webhook deliveries",
+	"error.audit_events_list_failed":       "Failed to retrieve audit events",
+}
@@ -0,0 +1,154 @@
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/vi"
+	ut "github.com/go-playground/universal-translator"
+)
+
+// DefaultLocale is used when the client accepts none of the supported locales,
+// and for keys missing from a catalog
+const DefaultLocale = "en"
+
+// catalogs maps each supported locale to its messages, keyed by message code.
+// Texts use universal-translator placeholders ({0}, {1}, ...).
+var catalogs = map[string]map[string]string{
+	"en": english,
+	"vi": vietnamese,
+}
+
+// Localizer returns the text of a message code in one language
+type Localizer interface {
+	Message(key string, params ...string) string
+}
+
+// Translator is the Localizer of one locale. Keys missing from its catalog fall
+// back to English, then to the key itself.
+type Translator struct {
+	locale   string
+	trans    ut.Translator
+	fallback ut.Translator
+}
+
+// Locale returns the translator's locale
+func (t *Translator) Locale() string {
+	return t.locale
+}
+
+// Message returns the text for key with its placeholders replaced by params
+func (t *Translator) Message(key string, params ...string) string {
+	// Pad missing params so that a short call cannot index past them
+	if n := arity[key]; len(params) < n {
+		params = append(params, make([]string, n-len(params))...)
+	}
+
+	if msg, err := t.trans.T(key, params...); err == nil {
+		return msg
+	}
+	if msg, err := t.fallback.T(key, params...); err == nil {
+		return msg
+	}
+	return key
+}
+
+var (
+	universal *ut.UniversalTranslator
+	arity     = map[string]int{}
+)
+
+func init() {
+	supported := map[string]locales.Translator{"en": en.New(), "vi": vi.New()}
+
+	universal = ut.New(supported[DefaultLocale])
+	for locale, catalog := range catalogs {
+		if err := universal.AddTranslator(supported[locale], true); err != nil {
+			panic(fmt.Sprintf("i18n: add locale %s: %v", locale, err))
+		}
+		trans, _ := universal.GetTranslator(locale)
+		for key, text := range catalog {
+			if err := trans.Add(key, text, false); err != nil {
+				panic(fmt.Sprintf("i18n: %s catalog: %v", locale, err))
+			}
+			if n := strings.Count(text, "{"); n > arity[key] {
+				arity[key] = n
+			}
+		}
+	}
+}
+
+// For returns the translator of a supported locale, or of the default locale
+func For(locale string) *Translator {
+	if _, ok := catalogs[locale]; !ok {
+		locale = DefaultLocale
+	}
+
+	trans, _ := universal.GetTranslator(locale)
+	fallback, _ := universal.GetTranslator(DefaultLocale)
+	return &Translator{locale: locale, trans: trans, fallback: fallback}
+}
+
+// Negotiate picks the supported locale the client prefers from an Accept-Language
+// header, e.g. "vi-VN,vi;q=0.9,en;q=0.8". Region subtags fall back to their
+// language, and "*" stands for the default locale.
+func Negotiate(acceptLanguage string) *Translator {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{strings.ToLower(tag), q})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, c := range candidates {
+		if c.tag == "*" {
+			return For(DefaultLocale)
+		}
+		// Match "vi-VN" to "vi"
+		base, _, _ := strings.Cut(c.tag, "-")
+		if _, ok := catalogs[base]; ok {
+			return For(base)
+		}
+	}
+	return For(DefaultLocale)
+}
+
+type localizerKey struct{}
+
+// WithLocalizer stores the request's localizer in the context
+func WithLocalizer(ctx context.Context, l Localizer) context.Context {
+	return context.WithValue(ctx, localizerKey{}, l)
+}
+
+// FromContext returns the request's localizer, or the default locale's
+func FromContext(ctx context.Context) Localizer {
+	if l, ok := ctx.Value(localizerKey{}).(Localizer); ok {
+		return l
+	}
+	return For(DefaultLocale)
+}
@@ -0,0 +1,110 @@
+package i18n
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"vi", "vi"},
+		{"en", "en"},
+		{"VI", "vi"},
+		{"fr", "en"},
+
+		// Region subtags fall back to their language
+		{"vi-VN", "vi"},
+		{"en-GB,vi;q=0.9", "en"},
+		{"fr-FR,vi-VN;q=0.5", "vi"},
+
+		// q-values order the candidates, not their position
+		{"en;q=0.5,vi;q=0.8", "vi"},
+		{"vi-VN,vi;q=0.9,en;q=0.8", "vi"},
+		{"en;q=0.8, vi ;q=0.9", "vi"},
+		{"vi;q=0.5,en", "en"},
+		{"vi,en", "vi"},
+		{"vi;q=0,en;q=0.1", "en"},
+		{"vi;q=0", "en"},
+		{"vi;q=abc,en;q=0.1", "en"},
+
+		// * is any language, so the default one
+		{"*", "en"},
+		{"fr,*;q=0.5,vi;q=0.3", "en"},
+		{"vi;q=0.9,*;q=0.1", "vi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := Negotiate(tt.header).Locale(); got != tt.want {
+				t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessage(t *testing.T) {
+	if got := For("vi").Message("rule.min.string", "3"); got != "phải có ít nhất 3 ký tự" {
+		t.Errorf("vi rule.min.string = %q", got)
+	}
+	if got := For("en").Message("rule.oneof", "a, b"); got != "must be one of a, b" {
+		t.Errorf("en rule.oneof = %q", got)
+	}
+	// Missing params are left empty rather than failing the lookup
+	if got := For("en").Message("rule.oneof"); got != "must be one of " {
+		t.Errorf("en rule.oneof without params = %q", got)
+	}
+	if got := For("en").Message("no.such.key"); got != "no.such.key" {
+		t.Errorf("unknown key = %q, want the key itself", got)
+	}
+	if got := For("fr").Locale(); got != DefaultLocale {
+		t.Errorf("For(fr) locale = %q, want %q", got, DefaultLocale)
+	}
+}
+
+func TestMessageFallsBackToEnglish(t *testing.T) {
+	const key = "test.only_in_english"
+	trans, _ := universal.GetTranslator("en")
+	if err := trans.Add(key, "only {0}", false); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := For("vi").Message(key, "here"); got != "only here" {
+		t.Errorf("vi %s = %q, want the English text", key, got)
+	}
+}
+
+func TestCatalogsMatch(t *testing.T) {
+	for locale, catalog := range catalogs {
+		for key, text := range english {
+			translated, ok := catalog[key]
+			if !ok {
+				t.Errorf("%s catalog is missing %q", locale, key)
+				continue
+			}
+			if got, want := strings.Count(translated, "{"), strings.Count(text, "{"); got != want {
+				t.Errorf("%s %q has %d placeholders, want %d", locale, key, got, want)
+			}
+		}
+		for key := range catalog {
+			if _, ok := english[key]; !ok {
+				t.Errorf("%s catalog has %q, which English lacks", locale, key)
+			}
+		}
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if got := FromContext(context.Background()).(*Translator).Locale(); got != DefaultLocale {
+		t.Errorf("FromContext() without a localizer = %q, want %q", got, DefaultLocale)
+	}
+
+	ctx := WithLocalizer(context.Background(), For("vi"))
+	if got := FromContext(ctx).(*Translator).Locale(); got != "vi" {
+		t.Errorf("FromContext() = %q, want vi", got)
+	}
+}
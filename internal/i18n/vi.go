@@ -0,0 +1,79 @@
+package i18n
+
+var vietnamese = map[string]string{
+	// Problem details
+	"validation.title":  "Yêu cầu không hợp lệ",
+	"validation.detail": "Một hoặc nhiều trường không hợp lệ",
+	"malformed.detail":  "Không thể đọc nội dung yêu cầu",
+
+	// Validator tags and OpenAPI rules
+	"rule.required":   "là bắt buộc",
+	"rule.email":      "phải là địa chỉ email hợp lệ",
+	"rule.url":        "phải là URL hợp lệ",
+	"rule.oneof":      "phải là một trong các giá trị {0}",
+	"rule.min":        "phải lớn hơn hoặc bằng {0}",
+	"rule.min.string": "phải có ít nhất {0} ký tự",
+	"rule.min.array":  "phải có ít nhất {0} phần tử",
+	"rule.max":        "phải nhỏ hơn hoặc bằng {0}",
+	"rule.max.string": "chỉ được có tối đa {0} ký tự",
+	"rule.max.array":  "chỉ được có tối đa {0} phần tử",
+	"rule.type":       "phải có kiểu {0}",
+	"rule.datetime":   "phải là thời gian theo định dạng RFC 3339",
+	"rule.json":       "phải là JSON hợp lệ",
+	"rule.invalid":    "không hợp lệ",
+
+	// Service rules
+	"rule.notblank":     "không được để trống",
+	"rule.pattern":      "phải đúng định dạng {0}",
+	"rule.email_domain": "không được dùng tên miền email {0}",
+	"rule.e164":         "phải là số điện thoại hợp lệ",
+	"rule.unique":       "không được lặp lại cùng {0}",
+	"rule.future":       "phải là thời điểm trong tương lai",
+	"rule.public_url":   "phải là URL http(s) trỏ tới địa chỉ công khai",
+
+	// Users
+	"user.created": "Tạo người dùng thành công",
+	"user.updated": "Cập nhật người dùng thành công",
+	"user.deleted": "Xóa người dùng thành công",
+
+	// Feature flags
+	"feature_flag.saved":   "Lưu cờ tính năng thành công",
+	"feature_flag.deleted": "Xóa cờ tính năng thành công",
+
+	// API keys
+	"api_key.created": "Tạo khóa API thành công. Hãy lưu khóa ngay, khóa sẽ không được hiển thị lại",
+	"api_key.revoked": "Thu hồi khóa API thành công",
+
+	// Webhooks
+	"webhook.created":              "Tạo webhook thành công. Hãy lưu khóa bí mật ngay, khóa sẽ không được hiển thị lại",
+	"webhook.updated":              "Cập nhật webhook thành công",
+	"webhook.deleted":              "Xóa webhook thành công",
+	"webhook.redelivery_scheduled": "Đã lên lịch gửi lại webhook",
+
+	// Error codes
+	"error.user_not_found":                 "Không tìm thấy người dùng",
+	"error.email_taken":                    "Email đã được sử dụng",
+	"error.invalid_user_id":                "ID người dùng không hợp lệ",
+	"error.users_list_failed":              "Không thể lấy danh sách người dùng",
+	"error.invalid_page":                   "page là bắt buộc và phải lớn hơn hoặc bằng 1",
+	"error.invalid_page_size":              "page_size là bắt buộc và phải nằm trong khoảng từ 1 đến 100",
+	"error.invalid_idempotency_key":        "Idempotency-Key chỉ được có tối đa 255 ký tự",
+	"error.idempotency_key_mismatch":       "Idempotency-Key đã được dùng cho một yêu cầu khác",
+	"error.idempotency_key_in_use":         "Yêu cầu với Idempotency-Key này vẫn đang được xử lý",
+	"error.idempotency_unavailable":        "Hiện không thể kiểm tra Idempotency-Key, vui lòng thử lại sau",
+	"error.authentication_unavailable":     "Hiện không thể kiểm tra thông tin xác thực, vui lòng thử lại sau",
+	"error.feature_flag_not_found":         "Không tìm thấy cờ tính năng",
+	"error.feature_flags_list_failed":      "Không thể lấy danh sách cờ tính năng",
+	"error.feature_flag_delete_failed":     "Không thể xóa cờ tính năng",
+	"error.request_too_large":              "Nội dung yêu cầu quá lớn",
+	"error.api_key_not_found":              "Không tìm thấy khóa API",
+	"error.invalid_api_key_id":             "ID khóa API không hợp lệ",
+	"error.api_keys_list_failed":           "Không thể lấy danh sách khóa API",
+	"error.webhook_not_found":              "Không tìm thấy webhook",
+	"error.webhook_delivery_not_found":     "Không tìm thấy lần gửi webhook",
+	"error.invalid_webhook_id":             "ID webhook không hợp lệ",
+	"error.invalid_webhook_delivery_id":    "ID lần gửi không hợp lệ",
+	"error.webhooks_list_failed":           "Không thể lấy danh sách webhook",
+	"error.webhook_deliveries_list_failed": "Không thể lấy danh sách lần gửi webhook",
+	"error.audit_events_list_failed":       "Không thể lấy nhật ký kiểm toán",
+}
@@ -0,0 +1,33 @@
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Header is the request header carrying the client's idempotency key
+const Header = "Idempotency-Key"
+
+// ReplayedHeader is set on responses replayed from a stored key
+const ReplayedHeader = "Idempotent-Replayed"
+
+// MaxKeyLength is the longest accepted key
+const MaxKeyLength = 255
+
+// Config controls how long keys live and when a held key is considered abandoned
+type Config struct {
+	TTL         time.Duration
+	LockTimeout time.Duration
+	// MaxBodyBytes bounds the body read to fingerprint a request
+	MaxBodyBytes int64
+}
+
+// Fingerprint identifies a request by method, path and body, so that a key
+// reused for a different request can be told apart from a retry
+func Fingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,44 @@
+package idempotency
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/raytr/go-template/internal/repository"
+)
+
+// Sweeper deletes expired idempotency keys in the background
+type Sweeper struct {
+	repo     *repository.IdempotencyRepository
+	interval time.Duration
+}
+
+// NewSweeper creates a new sweeper that runs every interval
+func NewSweeper(repo *repository.IdempotencyRepository, interval time.Duration) *Sweeper {
+	return &Sweeper{
+		repo:     repo,
+		interval: interval,
+	}
+}
+
+// Run sweeps until ctx is cancelled
+func (s *Sweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := s.repo.DeleteExpired(time.Now())
+		if err != nil {
+			log.Printf("Idempotency sweeper error: %v", err)
+		} else if n > 0 {
+			log.Printf("Idempotency sweeper deleted %d expired keys", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"time"
+)
+
+// commitStatement finds migrations that commit on their own, which a dry run
+// would apply for real
+var commitStatement = regexp.MustCompile(`(?im)^\s*COMMIT\b`)
+
+// PlannedMigration is a pending migration with the SQL it would run and, after
+// a dry run, how running it went
+type PlannedMigration struct {
+	File
+	SQL string
+	// Ran is set once the dry run executed the migration, successfully or not
+	Ran      bool
+	Duration time.Duration
+	Err      error
+}
+
+// Plan lists the pending migrations with their SQL, in the order Up applies them
+func (r *Runner) Plan() ([]PlannedMigration, error) {
+	status, err := r.Status()
+	if err != nil {
+		return nil, err
+	}
+	if status.Dirty {
+		return nil, &DirtyError{Version: status.Version}
+	}
+
+	plan := make([]PlannedMigration, len(status.Pending))
+	for i, f := range status.Pending {
+		if f.up == "" {
+			return nil, fmt.Errorf("migration %d has no up file", f.Version)
+		}
+		sql, err := fs.ReadFile(r.fsys, f.up)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %d: %w", f.Version, err)
+		}
+		plan[i] = PlannedMigration{File: f, SQL: string(sql)}
+	}
+
+	return plan, nil
+}
+
+// DryRun runs the pending migrations in a transaction that is always rolled
+// back, proving that they apply to the current schema, and times each one. It
+// stops at the first failure, which it returns with the plan. Statements that
+// cannot run in a transaction, such as CREATE INDEX CONCURRENTLY, fail here.
+// The locks the migrations take are held until the rollback, so lock_timeout
+// and statement_timeout are set from the options for the transaction.
+func (r *Runner) DryRun() ([]PlannedMigration, error) {
+	var plan []PlannedMigration
+	err := r.withLock(func() error {
+		var err error
+		if plan, err = r.Plan(); err != nil {
+			return err
+		}
+		if len(plan) == 0 {
+			return ErrNoChange
+		}
+		for _, step := range plan {
+			if commitStatement.MatchString(step.SQL) {
+				return fmt.Errorf("migration %d (%s) commits on its own and cannot be dry run", step.Version, step.Name)
+			}
+		}
+
+		tx, err := r.conn.BeginTx(context.Background(), nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin dry run: %w", err)
+		}
+		defer tx.Rollback()
+
+		// Give up on a lock rather than queue behind the application's queries,
+		// which would then queue behind the dry run. 0 leaves a setting unlimited.
+		for _, setting := range []struct {
+			name    string
+			timeout time.Duration
+		}{
+			{"lock_timeout", r.opts.LockTimeout},
+			{"statement_timeout", r.opts.StatementTimeout},
+		} {
+			if _, err := tx.Exec(fmt.Sprintf("SET LOCAL %s = %d", setting.name, setting.timeout.Milliseconds())); err != nil {
+				return fmt.Errorf("failed to set %s for the dry run: %w", setting.name, err)
+			}
+		}
+
+		for i := range plan {
+			step := &plan[i]
+
+			ctx, cancel := context.Background(), context.CancelFunc(func() {})
+			if r.opts.StatementTimeout > 0 {
+				ctx, cancel = context.WithTimeout(ctx, r.opts.StatementTimeout)
+			}
+			start := time.Now()
+			_, step.Err = tx.ExecContext(ctx, step.SQL)
+			step.Duration = time.Since(start)
+			step.Ran = true
+			cancel()
+
+			if step.Err != nil {
+				return fmt.Errorf("migration %d (%s) failed: %w", step.Version, step.Name, step.Err)
+			}
+		}
+		return nil
+	})
+
+	return plan, err
+}
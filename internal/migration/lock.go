@@ -0,0 +1,122 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// lockNotAvailable is the Postgres error code for an exceeded lock_timeout
+const lockNotAvailable = "55P03"
+
+// verifyPollInterval is how often Verify checks the version while waiting
+const verifyPollInterval = time.Second
+
+// Startup policies, chosen by migration.on_start
+const (
+	// OnStartMigrate applies pending migrations, one instance at a time
+	OnStartMigrate = "migrate"
+	// OnStartVerify refuses to start while migrations are pending
+	OnStartVerify = "verify"
+	// OnStartSkip ignores migrations
+	OnStartSkip = "skip"
+)
+
+// ErrLockTimeout is returned when another instance kept the migration lock
+// for longer than Options.LockTimeout
+var ErrLockTimeout = errors.New("timed out waiting for another instance to finish migrating")
+
+// PendingError reports that the database is behind the migrations of the source
+type PendingError struct {
+	Version uint
+	Pending []File
+}
+
+func (e *PendingError) Error() string {
+	return fmt.Sprintf("database is at version %d with %d pending migrations, up to %d",
+		e.Version, len(e.Pending), e.Pending[len(e.Pending)-1].Version)
+}
+
+// withLock runs fn holding the Postgres advisory lock of golang-migrate, so that
+// a single instance or migrate CLI migrates at a time. Session locks stack, so
+// golang-migrate still takes the lock itself on the same connection.
+func (r *Runner) withLock(fn func() error) error {
+	ctx := context.Background()
+
+	var name, schema string
+	if err := r.conn.QueryRowContext(ctx, "SELECT current_database(), current_schema()").Scan(&name, &schema); err != nil {
+		return fmt.Errorf("failed to read database name: %w", err)
+	}
+	key, err := database.GenerateAdvisoryLockId(name, schema, postgres.DefaultMigrationsTable)
+	if err != nil {
+		return fmt.Errorf("failed to generate lock id: %w", err)
+	}
+	id, err := strconv.ParseInt(key, 10, 64)
+	if err != nil {
+		return fmt.Errorf("failed to generate lock id: %w", err)
+	}
+
+	// lock_timeout also bounds the wait for an advisory lock. It is reset before
+	// the migrations run so that their own locks wait as long as they need.
+	if _, err := r.conn.ExecContext(ctx, fmt.Sprintf("SET lock_timeout = %d", r.opts.LockTimeout.Milliseconds())); err != nil {
+		return fmt.Errorf("failed to set lock timeout: %w", err)
+	}
+	_, err = r.conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", id)
+	if _, resetErr := r.conn.ExecContext(ctx, "RESET lock_timeout"); resetErr != nil && err == nil {
+		err = resetErr
+	}
+
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.As(err, &pgErr) && pgErr.Code == lockNotAvailable:
+		return ErrLockTimeout
+	case err != nil:
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+
+	defer func() {
+		if _, err := r.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", id); err != nil {
+			log.Printf("Failed to release migration lock: %v", err)
+		}
+	}()
+
+	return fn()
+}
+
+// Verify returns nil once every migration of the source is applied, waiting up
+// to wait for another instance to apply them. It fails with a *PendingError or
+// *DirtyError when they are still missing. A database migrated further by a
+// newer build is accepted.
+func (r *Runner) Verify(wait time.Duration) error {
+	deadline := time.Now().Add(wait)
+
+	for {
+		status, err := r.Status()
+		if err != nil {
+			return err
+		}
+
+		// Dirty is also seen while another instance runs a migration
+		switch {
+		case !status.Dirty && len(status.Pending) == 0:
+			if status.Unknown {
+				log.Printf("Database is at version %d, newer than the migrations of this build", status.Version)
+			}
+			return nil
+		case time.Now().After(deadline):
+			if status.Dirty {
+				return &DirtyError{Version: status.Version}
+			}
+			return &PendingError{Version: status.Version, Pending: status.Pending}
+		}
+
+		time.Sleep(verifyPollInterval)
+	}
+}
@@ -1,108 +1,279 @@
 package migration
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
+	"os"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 	"gorm.io/gorm"
 )
 
-// Runner handles database migrations
+var (
+	// ErrNoChange is returned when there is nothing to migrate
+	ErrNoChange = migrate.ErrNoChange
+	// ErrUnknownVersion is returned for a version that has no migration
+	ErrUnknownVersion = errors.New("no migration has this version")
+	// ErrOutOfRange is returned when asked for more steps than there are migrations
+	ErrOutOfRange = errors.New("not enough migrations")
+	// ErrDropNotAllowed is returned by Drop outside development and test
+	ErrDropNotAllowed = errors.New("dropping the database is only allowed when app.env is development or test")
+)
+
+// DirtyError reports that a migration failed part way. The schema must be fixed
+// by hand, then the version forced to the last one that fully applied.
+type DirtyError struct {
+	Version uint
+}
+
+func (e *DirtyError) Error() string {
+	return fmt.Sprintf("database is dirty at version %d: fix the schema, then force the version", e.Version)
+}
+
+// Options configures a Runner
+type Options struct {
+	// Env is the application environment. Drop is refused outside development and test.
+	Env string
+	// Verbose logs each migration step of golang-migrate
+	Verbose bool
+	// LockTimeout bounds the wait for another instance that is migrating
+	LockTimeout time.Duration
+	// StatementTimeout cancels a migration file that runs longer, 0 for no limit
+	StatementTimeout time.Duration
+}
+
+// Runner applies the migrations of a source to the database. It holds one
+// connection of the pool until Close. Changes are made under an advisory lock so
+// that concurrent runners, in other instances too, wait for each other.
 type Runner struct {
-	db            *gorm.DB
-	migrationsDir string
+	m     *migrate.Migrate
+	conn  *sql.Conn
+	fsys  fs.FS
+	files []File
+	opts  Options
 }
 
-// NewRunner creates a new migration runner
-func NewRunner(db *gorm.DB, migrationsDir string) *Runner {
-	return &Runner{
-		db:            db,
-		migrationsDir: migrationsDir,
+// NewRunner creates a migration runner for the migrations in fsys, usually the
+// result of Source
+func NewRunner(db *gorm.DB, fsys fs.FS, opts Options) (*Runner, error) {
+	files, err := List(fsys)
+	if err != nil {
+		return nil, err
 	}
-}
 
-// Run executes all pending migrations
-func (r *Runner) Run() error {
-	// Get the underlying SQL database
-	sqlDB, err := r.db.DB()
+	src, err := iofs.New(fsys, ".")
 	if err != nil {
-		return fmt.Errorf("failed to get sql.DB from gorm: %w", err)
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
 	}
 
-	driver, err := postgres.WithInstance(sqlDB, &postgres.Config{})
+	// Get the underlying SQL database
+	sqlDB, err := db.DB()
 	if err != nil {
-		return fmt.Errorf("failed to create migration driver: %w", err)
+		src.Close()
+		return nil, fmt.Errorf("failed to get sql.DB from gorm: %w", err)
 	}
 
-	m, err := migrate.NewWithDatabaseInstance(
-		fmt.Sprintf("file://%s", r.migrationsDir),
-		"postgres",
-		driver,
-	)
+	// A dedicated connection, as closing a driver made by postgres.WithInstance
+	// would close the whole pool
+	conn, err := sqlDB.Conn(context.Background())
 	if err != nil {
-		return fmt.Errorf("failed to create migrate instance: %w", err)
+		src.Close()
+		return nil, fmt.Errorf("failed to get a database connection: %w", err)
 	}
 
-	// Run migrations
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		return fmt.Errorf("failed to run migrations: %w", err)
+	driver, err := postgres.WithConnection(context.Background(), conn, &postgres.Config{
+		StatementTimeout: opts.StatementTimeout,
+	})
+	if err != nil {
+		src.Close()
+		conn.Close()
+		return nil, fmt.Errorf("failed to create migration driver: %w", err)
 	}
 
-	if err == migrate.ErrNoChange {
-		log.Println("No new migrations to apply")
-	} else {
-		log.Println("Migrations applied successfully")
+	m, err := migrate.NewWithInstance("iofs", src, "postgres", driver)
+	if err != nil {
+		src.Close()
+		conn.Close()
+		return nil, fmt.Errorf("failed to create migrate instance: %w", err)
 	}
+	m.Log = &logger{verbose: opts.Verbose}
 
+	return &Runner{
+		m:     m,
+		conn:  conn,
+		fsys:  fsys,
+		files: files,
+		opts:  opts,
+	}, nil
+}
+
+// Close releases the source and the connection
+func (r *Runner) Close() error {
+	srcErr, dbErr := r.m.Close()
+	if err := errors.Join(srcErr, dbErr); err != nil {
+		return fmt.Errorf("failed to close migrate instance: %w", err)
+	}
 	return nil
 }
 
-// Version returns the current migration version
+// Files returns the migrations of the source in version order
+func (r *Runner) Files() []File {
+	return r.files
+}
+
+// Version returns the current migration version, 0 when none was applied
 func (r *Runner) Version() (uint, bool, error) {
-	// Get the underlying SQL database
-	sqlDB, err := r.db.DB()
+	version, dirty, err := r.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
 	if err != nil {
-		return 0, false, fmt.Errorf("failed to get sql.DB from gorm: %w", err)
+		return 0, false, fmt.Errorf("failed to get migration version: %w", err)
 	}
 
-	driver, err := postgres.WithInstance(sqlDB, &postgres.Config{})
-	if err != nil {
-		return 0, false, fmt.Errorf("failed to create migration driver: %w", err)
+	return version, dirty, nil
+}
+
+// Up applies every pending migration
+func (r *Runner) Up() error {
+	return r.withLock(func() error {
+		return r.wrap("apply migrations", r.m.Up())
+	})
+}
+
+// Down rolls back the last n migrations
+func (r *Runner) Down(n int) error {
+	if n < 1 {
+		return fmt.Errorf("cannot roll back %d migrations: %w", n, ErrOutOfRange)
 	}
+	return r.withLock(func() error {
+		return r.wrap("roll back migrations", r.m.Steps(-n))
+	})
+}
 
-	m, err := migrate.NewWithDatabaseInstance(
-		fmt.Sprintf("file://%s", r.migrationsDir),
-		"postgres",
-		driver,
-	)
-	if err != nil {
-		return 0, false, fmt.Errorf("failed to create migrate instance: %w", err)
+// Steps applies n migrations when n is positive, or rolls back -n
+func (r *Runner) Steps(n int) error {
+	return r.withLock(func() error {
+		return r.wrap("migrate", r.m.Steps(n))
+	})
+}
+
+// Goto migrates up or down to version
+func (r *Runner) Goto(version uint) error {
+	if !r.hasVersion(version) {
+		return fmt.Errorf("cannot migrate to %d: %w", version, ErrUnknownVersion)
 	}
+	return r.withLock(func() error {
+		return r.wrap("migrate", r.m.Migrate(version))
+	})
+}
 
-	version, dirty, err := m.Version()
-	if err != nil && err != migrate.ErrNilVersion {
-		return 0, false, fmt.Errorf("failed to get migration version: %w", err)
+// Force sets the version without running any migration and clears the dirty
+// flag. -1 records that no migration is applied.
+func (r *Runner) Force(version int) error {
+	if version != -1 && (version < 0 || !r.hasVersion(uint(version))) {
+		return fmt.Errorf("cannot force version %d: %w", version, ErrUnknownVersion)
 	}
+	return r.withLock(func() error {
+		return r.wrap("force version", r.m.Force(version))
+	})
+}
 
-	return version, dirty, nil
+// Drop deletes everything in the database schema. It is refused unless
+// Options.Env is development or test.
+func (r *Runner) Drop() error {
+	if r.opts.Env != "development" && r.opts.Env != "test" {
+		return ErrDropNotAllowed
+	}
+	return r.withLock(func() error {
+		return r.wrap("drop database", r.m.Drop())
+	})
 }
 
-// CheckAndRun checks if migrations are needed and runs them if necessary
+// CheckAndRun applies the pending migrations. Instances starting together take
+// turns: the first applies the migrations and the others find none left.
 func (r *Runner) CheckAndRun() error {
-	version, dirty, err := r.Version()
-	if err != nil && err.Error() != "failed to get migration version: no migration" {
-		return fmt.Errorf("failed to check migration status: %w", err)
+	return r.withLock(func() error {
+		// Checked under the lock, as the database is dirty while another
+		// instance runs a migration
+		version, dirty, err := r.Version()
+		if err != nil {
+			return fmt.Errorf("failed to check migration status: %w", err)
+		}
+
+		if dirty {
+			return &DirtyError{Version: version}
+		}
+
+		// Older instances keep starting during a rolling deployment
+		if n := len(r.files); n > 0 && version > r.files[n-1].Version {
+			log.Printf("Database is at version %d, newer than the migrations of this build", version)
+			return nil
+		}
+
+		log.Printf("Current migration version: %d", version)
+
+		// Run migrations
+		err = r.wrap("apply migrations", r.m.Up())
+		switch {
+		case errors.Is(err, ErrNoChange):
+			log.Println("No new migrations to apply")
+			return nil
+		case err != nil:
+			return err
+		}
+
+		log.Println("Migrations applied successfully")
+		return nil
+	})
+}
+
+// wrap translates the errors of golang-migrate into the errors of this package
+func (r *Runner) wrap(action string, err error) error {
+	var dirty migrate.ErrDirty
+	var short migrate.ErrShortLimit
+
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, migrate.ErrNoChange):
+		return ErrNoChange
+	case errors.As(err, &dirty):
+		return &DirtyError{Version: uint(dirty.Version)}
+	case errors.As(err, &short):
+		return fmt.Errorf("failed to %s: %d short: %w", action, short.Short, ErrOutOfRange)
+	case errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("failed to %s: %w", action, ErrUnknownVersion)
+	default:
+		return fmt.Errorf("failed to %s: %w", action, err)
 	}
+}
 
-	if dirty {
-		return fmt.Errorf("database is in dirty state at version %d, manual intervention required", version)
+func (r *Runner) hasVersion(version uint) bool {
+	for _, f := range r.files {
+		if f.Version == version {
+			return true
+		}
 	}
+	return false
+}
 
-	log.Printf("Current migration version: %d", version)
+// logger routes the output of golang-migrate to the standard logger
+type logger struct {
+	verbose bool
+}
+
+func (l *logger) Printf(format string, v ...interface{}) {
+	log.Printf("migrate: "+format, v...)
+}
 
-	// Run migrations
-	return r.Run()
+func (l *logger) Verbose() bool {
+	return l.verbose
 }
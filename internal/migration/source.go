@@ -0,0 +1,70 @@
+package migration
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/raytr/go-template/migrations"
+)
+
+// Source returns the migrations in dir, or the ones embedded in the binary when
+// dir is empty. A directory lets migrations be edited without rebuilding.
+func Source(dir string) fs.FS {
+	if dir == "" {
+		return migrations.FS
+	}
+	return os.DirFS(dir)
+}
+
+// File describes one migration version of a source
+type File struct {
+	Version uint
+	Name    string
+	HasDown bool
+	// up is the name of the up file
+	up string
+}
+
+// List returns the migrations of fsys in version order. Files that are not
+// named like migrations are ignored.
+func List(fsys fs.FS) ([]File, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[uint]*File)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		m, err := source.DefaultParse(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		f, ok := byVersion[m.Version]
+		if !ok {
+			f = &File{Version: m.Version, Name: m.Identifier}
+			byVersion[m.Version] = f
+		}
+		switch m.Direction {
+		case source.Up:
+			f.up = entry.Name()
+		case source.Down:
+			f.HasDown = true
+		}
+	}
+
+	files := make([]File, 0, len(byVersion))
+	for _, f := range byVersion {
+		files = append(files, *f)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Version < files[j].Version
+	})
+	return files, nil
+}
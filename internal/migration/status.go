@@ -0,0 +1,37 @@
+package migration
+
+// Status lists the migrations of the source that are applied to the database
+// and the ones still pending
+type Status struct {
+	Version uint
+	Dirty   bool
+	Applied []File
+	Pending []File
+	// Unknown is set when the database version has no migration in the source,
+	// for instance when a newer build migrated it
+	Unknown bool
+}
+
+// Status reports the migration state of the database. golang-migrate records
+// only the current version, so every migration up to it counts as applied.
+func (r *Runner) Status() (*Status, error) {
+	version, dirty, err := r.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	status := &Status{
+		Version: version,
+		Dirty:   dirty,
+		Unknown: version != 0 && !r.hasVersion(version),
+	}
+	for _, f := range r.files {
+		if f.Version <= version {
+			status.Applied = append(status.Applied, f)
+		} else {
+			status.Pending = append(status.Pending, f)
+		}
+	}
+
+	return status, nil
+}
@@ -0,0 +1,76 @@
+package model
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// APIKeyEntity represents the api_keys table in the database
+type APIKeyEntity struct {
+	ID         uint           `gorm:"primaryKey;autoIncrement;type:serial" json:"id"`
+	Name       string         `gorm:"type:varchar(255);not null" json:"name"`
+	Prefix     string         `gorm:"type:varchar(16);unique;not null" json:"prefix"`
+	KeyHash    string         `gorm:"type:varchar(64);not null" json:"-"`
+	Scopes     pq.StringArray `gorm:"type:text[];not null;default:'{}'" json:"scopes"`
+	CreatedBy  *uint          `gorm:"type:integer" json:"created_by,omitempty"`
+	ExpiresAt  *time.Time     `gorm:"type:timestamp" json:"expires_at,omitempty"`
+	LastUsedAt *time.Time     `gorm:"type:timestamp" json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time     `gorm:"type:timestamp" json:"revoked_at,omitempty"`
+	CreatedAt  time.Time      `gorm:"autoCreateTime;type:timestamp;not null;index:idx_api_keys_created_at,sort:desc" json:"created_at"`
+}
+
+// TableName specifies the table name for APIKeyEntity
+func (APIKeyEntity) TableName() string {
+	return "api_keys"
+}
+
+// IsActive reports whether the key is neither revoked nor expired at the given time
+func (k *APIKeyEntity) IsActive(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// CreateAPIKeyReq represents the request for creating a new API key
+type CreateAPIKeyReq struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// APIKeyResponse represents the response for API key metadata
+type APIKeyResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  *uint      `json:"created_by,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreateAPIKeyResponse represents the response for a newly created API key.
+// Key holds the plaintext key and is only ever returned here.
+type CreateAPIKeyResponse struct {
+	*APIKeyResponse
+	Key string `json:"key"`
+}
+
+// ToResponse converts APIKeyEntity to APIKeyResponse
+func (k *APIKeyEntity) ToResponse() *APIKeyResponse {
+	return &APIKeyResponse{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.Scopes,
+		CreatedBy:  k.CreatedBy,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+		CreatedAt:  k.CreatedAt,
+	}
+}
@@ -0,0 +1,115 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Audit actions recorded for user mutations
+const (
+	AuditActionUserCreated = "user.created"
+	AuditActionUserUpdated = "user.updated"
+	AuditActionUserDeleted = "user.deleted"
+)
+
+// Audit actor types
+const (
+	AuditActorUser      = "user"
+	AuditActorAPIKey    = "api_key"
+	AuditActorSystem    = "system"
+	AuditActorAnonymous = "anonymous"
+)
+
+// FieldChange holds the before and after value of a single field
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// AuditChanges maps JSON field names to their change. It is stored as JSONB.
+type AuditChanges map[string]FieldChange
+
+// Value implements driver.Valuer
+func (c AuditChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(c)
+}
+
+// Scan implements sql.Scanner
+func (c *AuditChanges) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	case nil:
+		*c = AuditChanges{}
+		return nil
+	default:
+		return fmt.Errorf("unsupported type %T for AuditChanges", value)
+	}
+	return json.Unmarshal(data, c)
+}
+
+// AuditEventEntity represents the audit_events table in the database
+type AuditEventEntity struct {
+	ID         uint64       `gorm:"primaryKey;autoIncrement" json:"id"`
+	ActorType  string       `gorm:"type:varchar(20);not null;index:idx_audit_events_actor" json:"actor_type"`
+	ActorID    string       `gorm:"type:varchar(50);index:idx_audit_events_actor" json:"actor_id,omitempty"`
+	RequestID  string       `gorm:"type:varchar(100);index:idx_audit_events_request_id" json:"request_id,omitempty"`
+	Action     string       `gorm:"type:varchar(50);not null" json:"action"`
+	EntityType string       `gorm:"type:varchar(50);not null;index:idx_audit_events_entity" json:"entity_type"`
+	EntityID   string       `gorm:"type:varchar(50);not null;index:idx_audit_events_entity" json:"entity_id"`
+	Changes    AuditChanges `gorm:"type:jsonb;not null;default:'{}'" json:"changes"`
+	CreatedAt  time.Time    `gorm:"autoCreateTime;type:timestamp;not null;index:idx_audit_events_created_at,sort:desc" json:"created_at"`
+}
+
+// TableName specifies the table name for AuditEventEntity
+func (AuditEventEntity) TableName() string {
+	return "audit_events"
+}
+
+// AuditEventFilter represents the query filters for listing audit events
+type AuditEventFilter struct {
+	ActorType  string     `form:"actor_type"`
+	ActorID    string     `form:"actor_id"`
+	RequestID  string     `form:"request_id"`
+	Action     string     `form:"action"`
+	EntityType string     `form:"entity_type"`
+	EntityID   string     `form:"entity_id"`
+	From       *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To         *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// AuditEventResponse represents the response for audit event data
+type AuditEventResponse struct {
+	ID         uint64       `json:"id"`
+	ActorType  string       `json:"actor_type"`
+	ActorID    string       `json:"actor_id,omitempty"`
+	RequestID  string       `json:"request_id,omitempty"`
+	Action     string       `json:"action"`
+	EntityType string       `json:"entity_type"`
+	EntityID   string       `json:"entity_id"`
+	Changes    AuditChanges `json:"changes"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// ToResponse converts AuditEventEntity to AuditEventResponse
+func (e *AuditEventEntity) ToResponse() *AuditEventResponse {
+	return &AuditEventResponse{
+		ID:         e.ID,
+		ActorType:  e.ActorType,
+		ActorID:    e.ActorID,
+		RequestID:  e.RequestID,
+		Action:     e.Action,
+		EntityType: e.EntityType,
+		EntityID:   e.EntityID,
+		Changes:    e.Changes,
+		CreatedAt:  e.CreatedAt,
+	}
+}
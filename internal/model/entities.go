@@ -0,0 +1,20 @@
+package model
+
+// Entities returns one value of every model stored in the database, in the order
+// of the migrations that create their tables
+func Entities() []interface{} {
+	return []interface{}{
+		&UserEntity{},
+		&RoleEntity{},
+		&PermissionEntity{},
+		&UserRoleEntity{},
+		&APIKeyEntity{},
+		&AuditEventEntity{},
+		&OutboxEventEntity{},
+		&WebhookSubscriptionEntity{},
+		&WebhookDeliveryEntity{},
+		&WebhookDeliveryAttemptEntity{},
+		&IdempotencyKeyEntity{},
+		&FeatureFlagEntity{},
+	}
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/raytr/go-template/internal/flags"
+)
+
+// FeatureFlagEntity represents the feature_flags table in the database
+type FeatureFlagEntity struct {
+	Key         string          `gorm:"primaryKey;type:varchar(100)" json:"key"`
+	Description string          `gorm:"type:text" json:"description,omitempty"`
+	Enabled     bool            `gorm:"not null;default:false" json:"enabled"`
+	Percentage  *int            `gorm:"type:integer" json:"percentage"`
+	UserIDs     pq.Int64Array   `gorm:"type:bigint[];not null" json:"user_ids"`
+	Roles       pq.StringArray  `gorm:"type:text[];not null" json:"roles"`
+	Variants    json.RawMessage `gorm:"type:jsonb;not null" json:"variants"`
+	CreatedAt   time.Time       `gorm:"autoCreateTime;type:timestamp;not null" json:"created_at"`
+	UpdatedAt   time.Time       `gorm:"autoUpdateTime;type:timestamp;not null" json:"updated_at"`
+}
+
+// TableName specifies the table name for FeatureFlagEntity
+func (FeatureFlagEntity) TableName() string {
+	return "feature_flags"
+}
+
+// SetFeatureFlagReq represents the request for creating or replacing a feature flag
+type SetFeatureFlagReq struct {
+	Enabled     *bool           `json:"enabled" binding:"required"`
+	Description string          `json:"description,omitempty"`
+	Percentage  *int            `json:"percentage,omitempty" binding:"omitempty,min=0,max=100"`
+	UserIDs     []uint          `json:"user_ids,omitempty"`
+	Roles       []string        `json:"roles,omitempty"`
+	Variants    []flags.Variant `json:"variants,omitempty" binding:"dive"`
+}
+
+// NewFeatureFlagEntity builds the stored definition of a flag from a request
+func NewFeatureFlagEntity(key string, req *SetFeatureFlagReq) (*FeatureFlagEntity, error) {
+	variants := req.Variants
+	if variants == nil {
+		variants = []flags.Variant{}
+	}
+	encoded, err := json.Marshal(variants)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode variants: %w", err)
+	}
+
+	userIDs := make(pq.Int64Array, len(req.UserIDs))
+	for i, id := range req.UserIDs {
+		userIDs[i] = int64(id)
+	}
+	roles := pq.StringArray(req.Roles)
+	if roles == nil {
+		roles = pq.StringArray{}
+	}
+
+	return &FeatureFlagEntity{
+		Key:         key,
+		Description: req.Description,
+		Enabled:     *req.Enabled,
+		Percentage:  req.Percentage,
+		UserIDs:     userIDs,
+		Roles:       roles,
+		Variants:    encoded,
+	}, nil
+}
+
+// ToFlag converts FeatureFlagEntity to the definition evaluated by the flags package
+func (e *FeatureFlagEntity) ToFlag() (flags.Flag, error) {
+	var variants []flags.Variant
+	if len(e.Variants) > 0 {
+		if err := json.Unmarshal(e.Variants, &variants); err != nil {
+			return flags.Flag{}, fmt.Errorf("invalid variants of feature flag %s: %w", e.Key, err)
+		}
+	}
+
+	var userIDs []uint
+	for _, id := range e.UserIDs {
+		userIDs = append(userIDs, uint(id))
+	}
+
+	return flags.Flag{
+		Key:         e.Key,
+		Description: e.Description,
+		Enabled:     e.Enabled,
+		Percentage:  e.Percentage,
+		UserIDs:     userIDs,
+		Roles:       e.Roles,
+		Variants:    variants,
+		Source:      flags.SourceDatabase,
+	}, nil
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+)
+
+// Idempotency key states
+const (
+	IdempotencyStatusProcessing = "processing"
+	IdempotencyStatusCompleted  = "completed"
+)
+
+// IdempotencyKeyEntity represents the idempotency_keys table in the database.
+// Scope identifies the caller and endpoint the key was used for.
+type IdempotencyKeyEntity struct {
+	ID              uint64            `gorm:"primaryKey;autoIncrement" json:"id"`
+	Scope           string            `gorm:"type:varchar(255);not null;uniqueIndex:idempotency_keys_scope_key_key" json:"scope"`
+	Key             string            `gorm:"type:varchar(255);not null;uniqueIndex:idempotency_keys_scope_key_key" json:"key"`
+	Fingerprint     string            `gorm:"type:varchar(64);not null" json:"fingerprint"`
+	Status          string            `gorm:"type:varchar(20);not null;default:processing" json:"status"`
+	ResponseStatus  int               `gorm:"type:integer" json:"response_status,omitempty"`
+	ResponseHeaders map[string]string `gorm:"type:jsonb;serializer:json" json:"response_headers,omitempty"`
+	ResponseBody    []byte            `gorm:"type:bytea" json:"-"`
+	LockedAt        time.Time         `gorm:"type:timestamp;not null" json:"locked_at"`
+	ExpiresAt       time.Time         `gorm:"type:timestamp;not null;index:idx_idempotency_keys_expires_at" json:"expires_at"`
+	CreatedAt       time.Time         `gorm:"autoCreateTime;type:timestamp;not null" json:"created_at"`
+}
+
+// TableName specifies the table name for IdempotencyKeyEntity
+func (IdempotencyKeyEntity) TableName() string {
+	return "idempotency_keys"
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Outbox event delivery states
+const (
+	OutboxStatusPending   = "pending"
+	OutboxStatusDelivered = "delivered"
+	OutboxStatusFailed    = "failed"
+)
+
+// OutboxEventEntity represents the outbox_events table in the database
+type OutboxEventEntity struct {
+	ID            uint64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	EventID       string          `gorm:"type:varchar(36);unique;not null" json:"event_id"`
+	EventType     string          `gorm:"type:varchar(100);not null" json:"event_type"`
+	AggregateType string          `gorm:"type:varchar(50);not null;index:idx_outbox_events_aggregate" json:"aggregate_type"`
+	AggregateID   string          `gorm:"type:varchar(50);not null;index:idx_outbox_events_aggregate" json:"aggregate_id"`
+	Payload       json.RawMessage `gorm:"type:jsonb;not null" json:"payload"`
+	Status        string          `gorm:"type:varchar(20);not null;default:pending" json:"status"`
+	Attempts      int             `gorm:"type:integer;not null;default:0" json:"attempts"`
+	LastError     string          `gorm:"type:text" json:"last_error,omitempty"`
+	NextAttemptAt time.Time       `gorm:"type:timestamp;not null;index:idx_outbox_events_pending,where:status = 'pending'" json:"next_attempt_at"`
+	DeliveredAt   *time.Time      `gorm:"type:timestamp" json:"delivered_at,omitempty"`
+	CreatedAt     time.Time       `gorm:"autoCreateTime;type:timestamp;not null" json:"created_at"`
+}
+
+// TableName specifies the table name for OutboxEventEntity
+func (OutboxEventEntity) TableName() string {
+	return "outbox_events"
+}
@@ -1,10 +1,16 @@
 package model
 
 import (
-	"fmt"
+	"errors"
 	"math"
 )
 
+// Pagination validation errors
+var (
+	ErrInvalidPage     = errors.New("page is required and must be >= 1")
+	ErrInvalidPageSize = errors.New("page_size is required and must be between 1 and 100")
+)
+
 // PaginationRequest represents pagination parameters for requests
 type PaginationRequest struct {
 	Page     int `json:"page" form:"page" binding:"required,min=1"`
@@ -14,10 +20,10 @@ type PaginationRequest struct {
 // Validate validates pagination parameters
 func (p *PaginationRequest) Validate() error {
 	if p.Page < 1 {
-		return fmt.Errorf("page is required and must be >= 1")
+		return ErrInvalidPage
 	}
 	if p.PageSize < 1 || p.PageSize > 100 {
-		return fmt.Errorf("page_size is required and must be between 1 and 100")
+		return ErrInvalidPageSize
 	}
 	return nil
 }
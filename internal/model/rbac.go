@@ -0,0 +1,44 @@
+package model
+
+import (
+	"time"
+)
+
+// RoleEntity represents the roles table in the database
+type RoleEntity struct {
+	ID          uint               `gorm:"primaryKey;autoIncrement;type:serial" json:"id"`
+	Name        string             `gorm:"type:varchar(50);unique;not null" json:"name"`
+	Description string             `gorm:"type:text" json:"description,omitempty"`
+	Permissions []PermissionEntity `gorm:"many2many:role_permissions;joinForeignKey:RoleID;joinReferences:PermissionID" json:"permissions,omitempty"`
+	CreatedAt   time.Time          `gorm:"autoCreateTime;type:timestamp;not null" json:"created_at"`
+}
+
+// TableName specifies the table name for RoleEntity
+func (RoleEntity) TableName() string {
+	return "roles"
+}
+
+// PermissionEntity represents the permissions table in the database
+type PermissionEntity struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement;type:serial" json:"id"`
+	Name        string    `gorm:"type:varchar(100);unique;not null" json:"name"`
+	Description string    `gorm:"type:text" json:"description,omitempty"`
+	CreatedAt   time.Time `gorm:"autoCreateTime;type:timestamp;not null" json:"created_at"`
+}
+
+// TableName specifies the table name for PermissionEntity
+func (PermissionEntity) TableName() string {
+	return "permissions"
+}
+
+// UserRoleEntity represents the user_roles join table in the database
+type UserRoleEntity struct {
+	UserID    uint      `gorm:"primaryKey;type:integer" json:"user_id"`
+	RoleID    uint      `gorm:"primaryKey;type:integer;index:idx_user_roles_role_id" json:"role_id"`
+	CreatedAt time.Time `gorm:"autoCreateTime;type:timestamp;not null" json:"created_at"`
+}
+
+// TableName specifies the table name for UserRoleEntity
+func (UserRoleEntity) TableName() string {
+	return "user_roles"
+}
@@ -2,6 +2,8 @@ package model
 
 import (
 	"time"
+
+	"github.com/lib/pq"
 )
 
 // UserEntity represents the users table in the database
@@ -38,6 +40,20 @@ type UpdateUserReq struct {
 	Address string `json:"address,omitempty"`
 }
 
+// UpsertUserReq represents the request for creating or replacing the user with a given code
+type UpsertUserReq struct {
+	Name    string `json:"name" binding:"required"`
+	Email   string `json:"email" binding:"required,email"`
+	Phone   string `json:"phone,omitempty"`
+	Address string `json:"address,omitempty"`
+}
+
+// DuplicateEmail lists the users sharing an email when case is ignored
+type DuplicateEmail struct {
+	Email   string        `json:"email"`
+	UserIDs pq.Int64Array `gorm:"type:integer[]" json:"user_ids"`
+}
+
 // UserResponse represents the response for user data
 type UserResponse struct {
 	ID        uint      `json:"id"`
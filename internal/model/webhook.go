@@ -0,0 +1,128 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Webhook delivery states. Dead deliveries exhausted their attempts and form the
+// dead-letter list. Cancelled deliveries were due while their subscription was
+// inactive and were never attempted.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryDead      = "dead"
+	WebhookDeliveryCancelled = "cancelled"
+)
+
+// WebhookSubscriptionEntity represents the webhook_subscriptions table in the database
+type WebhookSubscriptionEntity struct {
+	ID          uint           `gorm:"primaryKey;autoIncrement;type:serial" json:"id"`
+	URL         string         `gorm:"type:text;not null" json:"url"`
+	EventTypes  pq.StringArray `gorm:"type:text[];not null" json:"event_types"`
+	Secret      string         `gorm:"type:varchar(255);not null" json:"-"`
+	Description string         `gorm:"type:text" json:"description,omitempty"`
+	Active      bool           `gorm:"not null;default:true" json:"active"`
+	CreatedAt   time.Time      `gorm:"autoCreateTime;type:timestamp;not null" json:"created_at"`
+	UpdatedAt   time.Time      `gorm:"autoUpdateTime;type:timestamp;not null" json:"updated_at"`
+}
+
+// TableName specifies the table name for WebhookSubscriptionEntity
+func (WebhookSubscriptionEntity) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// WebhookDeliveryEntity represents the webhook_deliveries table in the database
+type WebhookDeliveryEntity struct {
+	ID             uint64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	SubscriptionID uint            `gorm:"type:integer;not null;uniqueIndex:webhook_deliveries_subscription_id_event_id_key;index:idx_webhook_deliveries_subscription" json:"subscription_id"`
+	EventID        string          `gorm:"type:varchar(36);not null;uniqueIndex:webhook_deliveries_subscription_id_event_id_key" json:"event_id"`
+	EventType      string          `gorm:"type:varchar(100);not null" json:"event_type"`
+	Payload        json.RawMessage `gorm:"type:jsonb;not null" json:"payload"`
+	Status         string          `gorm:"type:varchar(20);not null;default:pending" json:"status"`
+	Attempts       int             `gorm:"type:integer;not null;default:0" json:"attempts"`
+	LastStatusCode *int            `gorm:"type:integer" json:"last_status_code,omitempty"`
+	LastError      string          `gorm:"type:text" json:"last_error,omitempty"`
+	NextAttemptAt  time.Time       `gorm:"type:timestamp;not null;index:idx_webhook_deliveries_pending,where:status = 'pending'" json:"next_attempt_at"`
+	DeliveredAt    *time.Time      `gorm:"type:timestamp" json:"delivered_at,omitempty"`
+	CreatedAt      time.Time       `gorm:"autoCreateTime;type:timestamp;not null;index:idx_webhook_deliveries_subscription,sort:desc" json:"created_at"`
+}
+
+// TableName specifies the table name for WebhookDeliveryEntity
+func (WebhookDeliveryEntity) TableName() string {
+	return "webhook_deliveries"
+}
+
+// WebhookDeliveryAttemptEntity represents the webhook_delivery_attempts table in the database
+type WebhookDeliveryAttemptEntity struct {
+	ID         uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	DeliveryID uint64    `gorm:"not null;index:idx_webhook_delivery_attempts_delivery" json:"delivery_id"`
+	Attempt    int       `gorm:"type:integer;not null" json:"attempt"`
+	StatusCode *int      `gorm:"type:integer" json:"status_code,omitempty"`
+	Error      string    `gorm:"type:text" json:"error,omitempty"`
+	DurationMs int       `gorm:"type:integer;not null" json:"duration_ms"`
+	CreatedAt  time.Time `gorm:"autoCreateTime;type:timestamp;not null" json:"created_at"`
+}
+
+// TableName specifies the table name for WebhookDeliveryAttemptEntity
+func (WebhookDeliveryAttemptEntity) TableName() string {
+	return "webhook_delivery_attempts"
+}
+
+// CreateWebhookReq represents the request for creating a webhook subscription.
+// A secret is generated when none is given.
+type CreateWebhookReq struct {
+	URL         string   `json:"url" binding:"required,url"`
+	EventTypes  []string `json:"event_types" binding:"required,min=1"`
+	Secret      string   `json:"secret,omitempty" binding:"omitempty,min=16"`
+	Description string   `json:"description,omitempty"`
+}
+
+// UpdateWebhookReq represents the request for updating a webhook subscription
+type UpdateWebhookReq struct {
+	URL         string   `json:"url,omitempty" binding:"omitempty,url"`
+	EventTypes  []string `json:"event_types,omitempty"`
+	Secret      string   `json:"secret,omitempty" binding:"omitempty,min=16"`
+	Description string   `json:"description,omitempty"`
+	Active      *bool    `json:"active,omitempty"`
+}
+
+// WebhookDeliveryFilter represents the query filters for listing webhook deliveries
+type WebhookDeliveryFilter struct {
+	SubscriptionID uint   `form:"subscription_id"`
+	Status         string `form:"status" binding:"omitempty,oneof=pending delivered dead cancelled"`
+	EventID        string `form:"event_id"`
+}
+
+// WebhookResponse represents the response for webhook subscription data
+type WebhookResponse struct {
+	ID          uint      `json:"id"`
+	URL         string    `json:"url"`
+	EventTypes  []string  `json:"event_types"`
+	Description string    `json:"description,omitempty"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// CreateWebhookResponse represents the response for a newly created subscription.
+// Secret is only ever returned here.
+type CreateWebhookResponse struct {
+	*WebhookResponse
+	Secret string `json:"secret"`
+}
+
+// ToResponse converts WebhookSubscriptionEntity to WebhookResponse
+func (w *WebhookSubscriptionEntity) ToResponse() *WebhookResponse {
+	return &WebhookResponse{
+		ID:          w.ID,
+		URL:         w.URL,
+		EventTypes:  w.EventTypes,
+		Description: w.Description,
+		Active:      w.Active,
+		CreatedAt:   w.CreatedAt,
+		UpdatedAt:   w.UpdatedAt,
+	}
+}
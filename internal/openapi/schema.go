@@ -0,0 +1,246 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of the JSON Schema 2020-12 vocabulary used by OpenAPI 3.1
+// that this project generates
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 interface{}        `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// SchemaRegistry turns Go types into schemas, collecting named struct types as
+// reusable components
+type SchemaRegistry struct {
+	components map[string]*Schema
+}
+
+// NewSchemaRegistry creates an empty schema registry
+func NewSchemaRegistry() *SchemaRegistry {
+	return &SchemaRegistry{
+		components: make(map[string]*Schema),
+	}
+}
+
+// Components returns the named schemas collected so far
+func (r *SchemaRegistry) Components() map[string]*Schema {
+	return r.components
+}
+
+// Resolve follows a "#/components/schemas/<name>" reference
+func (r *SchemaRegistry) Resolve(s *Schema) *Schema {
+	if s == nil || s.Ref == "" {
+		return s
+	}
+	return r.components[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+}
+
+// SchemaFor returns the schema of v's type. Named structs become references to components.
+func (r *SchemaRegistry) SchemaFor(v interface{}) *Schema {
+	return r.schemaForType(reflect.TypeOf(v))
+}
+
+func (r *SchemaRegistry) schemaForType(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: float(0)}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: r.schemaForType(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: r.schemaForType(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return r.structSchema(t)
+		}
+		if _, ok := r.components[t.Name()]; !ok {
+			// Reserve the name first so recursive types terminate
+			r.components[t.Name()] = &Schema{}
+			*r.components[t.Name()] = *r.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + t.Name()}
+	default:
+		return &Schema{}
+	}
+}
+
+func (r *SchemaRegistry) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, omitted := jsonName(field)
+		if omitted {
+			continue
+		}
+
+		// Flatten embedded structs, as encoding/json does
+		if field.Anonymous && name == "" {
+			embedded := r.Resolve(r.schemaForType(field.Type))
+			for k, v := range embedded.Properties {
+				schema.Properties[k] = v
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		prop := r.schemaForType(field.Type)
+		required := applyBinding(prop, field.Tag.Get("binding"))
+		if field.Type.Kind() == reflect.Ptr && prop.Ref == "" {
+			prop.Type = []interface{}{prop.Type, "null"}
+		}
+
+		schema.Properties[name] = prop
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	return schema
+}
+
+// ParameterFields lists the form-tagged fields of a query struct with their schema
+// and whether they are required
+func (r *SchemaRegistry) ParameterFields(v interface{}) []ParameterField {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var fields []ParameterField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("form"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		schema := r.schemaForType(field.Type)
+		required := applyBinding(schema, field.Tag.Get("binding"))
+		fields = append(fields, ParameterField{Name: name, Schema: schema, Required: required})
+	}
+
+	return fields
+}
+
+// ParameterField describes one query parameter derived from a struct field
+type ParameterField struct {
+	Name     string
+	Schema   *Schema
+	Required bool
+}
+
+// jsonName returns the JSON property name of a field and whether it is skipped
+func jsonName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+	return strings.Split(tag, ",")[0], false
+}
+
+// applyBinding translates gin/validator binding rules into schema keywords.
+// It reports whether the field is required.
+func applyBinding(s *Schema, binding string) bool {
+	required := false
+
+	for _, rule := range strings.Split(binding, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "email":
+			s.Format = "email"
+		case "url":
+			s.Format = "uri"
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				s.Enum = append(s.Enum, v)
+			}
+		case "min", "max":
+			n, err := strconv.Atoi(param)
+			if err != nil {
+				continue
+			}
+			setBound(s, name == "min", n)
+		}
+	}
+
+	return required
+}
+
+func setBound(s *Schema, isMin bool, n int) {
+	switch s.Type {
+	case "string":
+		if isMin {
+			s.MinLength = &n
+		} else {
+			s.MaxLength = &n
+		}
+	case "array":
+		if isMin {
+			s.MinItems = &n
+		} else {
+			s.MaxItems = &n
+		}
+	default:
+		if isMin {
+			s.Minimum = float(float64(n))
+		} else {
+			s.Maximum = float(float64(n))
+		}
+	}
+}
+
+func float(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,309 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/raytr/go-template/internal/model"
+	"github.com/raytr/go-template/internal/problem"
+)
+
+// Version is the OpenAPI version of generated documents
+const Version = "3.1.0"
+
+// Document is an OpenAPI 3.1 document
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Paths      map[string]map[string]*OpObject `json:"paths"`
+	Components Components                      `json:"components"`
+	Security   []map[string][]string           `json:"security,omitempty"`
+}
+
+// Info holds the document metadata
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Components holds the reusable schemas and security schemes
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme describes how a request authenticates
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+}
+
+// OpObject is an OpenAPI operation object
+type OpObject struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter is an OpenAPI path or query parameter
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody is an OpenAPI request body
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response is an OpenAPI response
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema of a body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Operation declares the documentation of one route. Types are example values
+// (usually nil pointers) whose struct definitions become schemas.
+type Operation struct {
+	ID          string
+	Summary     string
+	Tags        []string
+	Permissions []string
+	Public      bool
+	Query       []interface{}
+	Request     interface{}
+	Response    interface{}
+	List        bool
+	Paginated   bool
+	Status      int
+	// ExtraStatuses are further success statuses returning the same body
+	ExtraStatuses []int
+	// Idempotent operations accept an Idempotency-Key header
+	Idempotent  bool
+	Errors      []int
+	NoEnvelope  bool
+	ContentType string
+	Description string
+}
+
+// RouteKey returns the "<METHOD> <gin path>" key used to declare operations
+func RouteKey(method, path string) string {
+	return method + " " + path
+}
+
+// Generate builds the document for the given routes from their declared operations.
+// It returns the keys of routes that have no declaration.
+func Generate(info Info, routes gin.RoutesInfo, ops map[string]Operation) (*Document, []string) {
+	registry := NewSchemaRegistry()
+	errorSchema := registry.SchemaFor(ErrorResponse{})
+	problemSchema := registry.SchemaFor(problem.Problem{})
+	paginationSchema := registry.SchemaFor(model.PaginationResponse{})
+
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   map[string]map[string]*OpObject{},
+		Components: Components{
+			SecuritySchemes: map[string]*SecurityScheme{
+				"bearerAuth":   {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				"apiKeyHeader": {Type: "apiKey", In: "header", Name: "X-API-Key"},
+			},
+		},
+		Security: []map[string][]string{{"bearerAuth": {}}, {"apiKeyHeader": {}}},
+	}
+
+	var missing []string
+	for _, route := range routes {
+		key := RouteKey(route.Method, route.Path)
+		op, ok := ops[key]
+		if !ok {
+			missing = append(missing, key)
+			continue
+		}
+
+		path := ToOpenAPIPath(route.Path)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]*OpObject{}
+		}
+		doc.Paths[path][strings.ToLower(route.Method)] = buildOperation(registry, route.Path, op, errorSchema, problemSchema, paginationSchema)
+	}
+
+	doc.Components.Schemas = registry.Components()
+	sort.Strings(missing)
+
+	return doc, missing
+}
+
+func buildOperation(registry *SchemaRegistry, ginPath string, op Operation, errorSchema, problemSchema, paginationSchema *Schema) *OpObject {
+	obj := &OpObject{
+		OperationID: op.ID,
+		Summary:     op.Summary,
+		Description: op.Description,
+		Tags:        op.Tags,
+		Responses:   map[string]*Response{},
+	}
+
+	if len(op.Permissions) > 0 {
+		obj.Description = strings.TrimSpace(obj.Description + "\n\nRequires permission `" + strings.Join(op.Permissions, "` or `") + "`.")
+	}
+	if op.Public {
+		obj.Security = []map[string][]string{}
+	}
+
+	for _, name := range PathParams(ginPath) {
+		schema := &Schema{Type: "string"}
+		if name == "id" {
+			schema = &Schema{Type: "integer", Minimum: float(1)}
+		}
+		obj.Parameters = append(obj.Parameters, &Parameter{
+			Name: name, In: "path", Required: true, Schema: schema,
+		})
+	}
+	if op.Idempotent {
+		obj.Parameters = append(obj.Parameters, &Parameter{
+			Name: "Idempotency-Key", In: "header",
+			Schema: &Schema{Type: "string", MaxLength: intPtr(255)},
+		})
+	}
+	for _, query := range op.Query {
+		for _, field := range registry.ParameterFields(query) {
+			obj.Parameters = append(obj.Parameters, &Parameter{
+				Name: field.Name, In: "query", Required: field.Required, Schema: field.Schema,
+			})
+		}
+	}
+
+	if op.Request != nil {
+		obj.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]*MediaType{"application/json": {Schema: registry.SchemaFor(op.Request)}},
+		}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	contentType := op.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	success := successSchema(registry, op, paginationSchema)
+	for _, code := range append([]int{status}, op.ExtraStatuses...) {
+		obj.Responses[fmt.Sprint(code)] = &Response{
+			Description: http.StatusText(code),
+			Content:     map[string]*MediaType{contentType: {Schema: success}},
+		}
+	}
+
+	errs := append([]int{}, op.Errors...)
+	if op.Request != nil {
+		errs = append(errs, http.StatusRequestEntityTooLarge)
+	}
+	if op.Idempotent {
+		errs = append(errs, http.StatusConflict, http.StatusUnprocessableEntity)
+	}
+	if !op.Public {
+		errs = append(errs, http.StatusUnauthorized, http.StatusForbidden)
+	}
+	for _, code := range errs {
+		resp := &Response{
+			Description: http.StatusText(code),
+			Content:     map[string]*MediaType{"application/json": {Schema: errorSchema}},
+		}
+		// Requests rejected by the validation middleware carry the field errors
+		if code == http.StatusBadRequest {
+			resp.Content[problem.ContentType] = &MediaType{Schema: problemSchema}
+		}
+		obj.Responses[fmt.Sprint(code)] = resp
+	}
+
+	return obj
+}
+
+// successSchema wraps the response type in the {"data", "message", "pagination"} envelope
+func successSchema(registry *SchemaRegistry, op Operation, paginationSchema *Schema) *Schema {
+	var data *Schema
+	if op.Response != nil {
+		data = registry.SchemaFor(op.Response)
+		if op.List {
+			data = &Schema{Type: "array", Items: data}
+		}
+	}
+
+	if op.ContentType != "" && op.ContentType != "application/json" {
+		return &Schema{Type: "string"}
+	}
+
+	if op.NoEnvelope {
+		if data == nil {
+			return &Schema{Type: "object"}
+		}
+		return data
+	}
+
+	envelope := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	if data != nil {
+		envelope.Properties["data"] = data
+		envelope.Required = append(envelope.Required, "data")
+	}
+	if op.Paginated {
+		envelope.Properties["pagination"] = paginationSchema
+		envelope.Required = append(envelope.Required, "pagination")
+	} else {
+		envelope.Properties["message"] = &Schema{Type: "string"}
+	}
+
+	return envelope
+}
+
+// ErrorResponse documents the body of error responses
+type ErrorResponse struct {
+	Error string `json:"error" binding:"required"`
+	Code  string `json:"code,omitempty"`
+}
+
+// ToOpenAPIPath converts a gin path such as /users/:id to /users/{id}
+func ToOpenAPIPath(ginPath string) string {
+	segments := strings.Split(ginPath, "/")
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "*") {
+			segments[i] = "{" + seg[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// PathParams returns the parameter names of a gin path
+func PathParams(ginPath string) []string {
+	var names []string
+	for _, seg := range strings.Split(ginPath, "/") {
+		if strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "*") {
+			names = append(names, seg[1:])
+		}
+	}
+	return names
+}
+
+func intPtr(n int) *int {
+	return &n
+}
@@ -0,0 +1,377 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Violation describes a value that does not match its schema. Rule, Kind and
+// Param identify the failure so that the message can be localized; Message is
+// its English text.
+type Violation struct {
+	Field   string
+	Rule    string
+	Kind    string
+	Param   string
+	Message string
+}
+
+// Validator checks request and response values against a document
+type Validator struct {
+	doc *Document
+}
+
+// NewValidator creates a validator for doc
+func NewValidator(doc *Document) *Validator {
+	return &Validator{
+		doc: doc,
+	}
+}
+
+// Operation returns the operation documented for a method and gin route path
+func (v *Validator) Operation(method, ginPath string) (*OpObject, bool) {
+	ops, ok := v.doc.Paths[ToOpenAPIPath(ginPath)]
+	if !ok {
+		return nil, false
+	}
+	op, ok := ops[strings.ToLower(method)]
+	return op, ok
+}
+
+// ValidateParameters checks path and query parameters. pathParams and query map
+// names to their raw string values.
+func (v *Validator) ValidateParameters(op *OpObject, pathParams map[string]string, query url.Values) []Violation {
+	var violations []Violation
+
+	for _, param := range op.Parameters {
+		var (
+			raw     string
+			present bool
+		)
+		switch param.In {
+		case "path":
+			raw, present = pathParams[param.Name]
+		case "query":
+			present = query.Has(param.Name) && query.Get(param.Name) != ""
+			raw = query.Get(param.Name)
+		default:
+			continue
+		}
+
+		if !present {
+			if param.Required {
+				violations = append(violations, Violation{Field: param.Name, Rule: "required", Message: "is required"})
+			}
+			continue
+		}
+
+		value, violation := coerce(param.Schema, raw)
+		if violation != nil {
+			violation.Field = param.Name
+			violations = append(violations, *violation)
+			continue
+		}
+
+		violations = append(violations, v.validate(param.Schema, value, param.Name)...)
+	}
+
+	return violations
+}
+
+// ValidateRequestBody checks a JSON request body
+func (v *Validator) ValidateRequestBody(op *OpObject, body []byte) []Violation {
+	if op.RequestBody == nil {
+		return nil
+	}
+
+	media, ok := op.RequestBody.Content["application/json"]
+	if !ok {
+		return nil
+	}
+
+	if len(strings.TrimSpace(string(body))) == 0 {
+		if op.RequestBody.Required {
+			return []Violation{{Rule: "required", Message: "request body is required"}}
+		}
+		return nil
+	}
+
+	return v.validateJSON(media.Schema, body)
+}
+
+// ValidateResponseBody checks a JSON response body against the response documented for status
+func (v *Validator) ValidateResponseBody(op *OpObject, status int, body []byte) []Violation {
+	resp, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		return []Violation{{Rule: "status", Param: strconv.Itoa(status), Message: fmt.Sprintf("status %d is not documented", status)}}
+	}
+
+	media, ok := resp.Content["application/json"]
+	if !ok {
+		return nil
+	}
+
+	return v.validateJSON(media.Schema, body)
+}
+
+func (v *Validator) validateJSON(schema *Schema, body []byte) []Violation {
+	decoder := json.NewDecoder(strings.NewReader(string(body)))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return []Violation{{Rule: "json", Message: "must be valid JSON"}}
+	}
+
+	return v.validate(schema, value, "")
+}
+
+// validate checks a decoded JSON value. field is the dotted path of value.
+func (v *Validator) validate(schema *Schema, value interface{}, field string) []Violation {
+	schema = v.resolve(schema)
+	if schema == nil {
+		return nil
+	}
+
+	if value == nil {
+		if allowsType(schema, "null") || schema.Type == nil {
+			return nil
+		}
+		return []Violation{{Field: field, Rule: "required", Message: "is required"}}
+	}
+
+	switch val := value.(type) {
+	case map[string]interface{}:
+		if !allowsType(schema, "object") {
+			return []Violation{typeViolation(field, fmt.Sprint(primaryType(schema)))}
+		}
+		return v.validateObject(schema, val, field)
+	case []interface{}:
+		if !allowsType(schema, "array") {
+			return []Violation{typeViolation(field, fmt.Sprint(primaryType(schema)))}
+		}
+		return v.validateArray(schema, val, field)
+	case string:
+		if !allowsType(schema, "string") {
+			return []Violation{typeViolation(field, fmt.Sprint(primaryType(schema)))}
+		}
+		return validateString(schema, val, field)
+	case json.Number:
+		return validateNumber(schema, val, field)
+	case bool:
+		if !allowsType(schema, "boolean") {
+			return []Violation{typeViolation(field, fmt.Sprint(primaryType(schema)))}
+		}
+	}
+
+	return nil
+}
+
+func (v *Validator) validateObject(schema *Schema, obj map[string]interface{}, field string) []Violation {
+	var violations []Violation
+
+	for _, name := range schema.Required {
+		if _, ok := obj[name]; !ok {
+			violations = append(violations, Violation{Field: join(field, name), Rule: "required", Message: "is required"})
+		}
+	}
+
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		prop, ok := schema.Properties[name]
+		if !ok {
+			if additional, ok := schema.AdditionalProperties.(*Schema); ok {
+				violations = append(violations, v.validate(additional, obj[name], join(field, name))...)
+			}
+			continue
+		}
+		violations = append(violations, v.validate(prop, obj[name], join(field, name))...)
+	}
+
+	return violations
+}
+
+func (v *Validator) validateArray(schema *Schema, arr []interface{}, field string) []Violation {
+	var violations []Violation
+
+	if schema.MinItems != nil && len(arr) < *schema.MinItems {
+		violations = append(violations, bound(field, "min", "array", *schema.MinItems, "must contain at least %d items"))
+	}
+	if schema.MaxItems != nil && len(arr) > *schema.MaxItems {
+		violations = append(violations, bound(field, "max", "array", *schema.MaxItems, "must contain at most %d items"))
+	}
+
+	for i, item := range arr {
+		violations = append(violations, v.validate(schema.Items, item, fmt.Sprintf("%s[%d]", field, i))...)
+	}
+
+	return violations
+}
+
+func validateString(schema *Schema, s string, field string) []Violation {
+	var violations []Violation
+	length := len([]rune(s))
+
+	if schema.MinLength != nil && length < *schema.MinLength {
+		violations = append(violations, bound(field, "min", "string", *schema.MinLength, "must be at least %d characters long"))
+	}
+	if schema.MaxLength != nil && length > *schema.MaxLength {
+		violations = append(violations, bound(field, "max", "string", *schema.MaxLength, "must be at most %d characters long"))
+	}
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, s) {
+		violations = append(violations, Violation{Field: field, Rule: "oneof", Param: enumList(schema.Enum), Message: "must be one of " + enumList(schema.Enum)})
+	}
+
+	switch schema.Format {
+	case "email":
+		if addr, err := mail.ParseAddress(s); err != nil || addr.Address != s {
+			violations = append(violations, Violation{Field: field, Rule: "email", Message: "must be a valid email address"})
+		}
+	case "uri":
+		if u, err := url.ParseRequestURI(s); err != nil || u.Scheme == "" || u.Host == "" {
+			violations = append(violations, Violation{Field: field, Rule: "url", Message: "must be a valid URL"})
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, s); err != nil {
+			violations = append(violations, Violation{Field: field, Rule: "datetime", Message: "must be an RFC 3339 date-time"})
+		}
+	}
+
+	return violations
+}
+
+func validateNumber(schema *Schema, n json.Number, field string) []Violation {
+	isInteger := !strings.ContainsAny(n.String(), ".eE")
+	switch {
+	case allowsType(schema, "number"):
+	case allowsType(schema, "integer") && isInteger:
+	case allowsType(schema, "integer"):
+		return []Violation{typeViolation(field, "integer")}
+	default:
+		return []Violation{typeViolation(field, fmt.Sprint(primaryType(schema)))}
+	}
+
+	f, err := n.Float64()
+	if err != nil {
+		return []Violation{typeViolation(field, "number")}
+	}
+
+	var violations []Violation
+	if schema.Minimum != nil && f < *schema.Minimum {
+		violations = append(violations, Violation{Field: field, Rule: "min", Kind: "number", Param: formatFloat(*schema.Minimum), Message: "must be at least " + formatFloat(*schema.Minimum)})
+	}
+	if schema.Maximum != nil && f > *schema.Maximum {
+		violations = append(violations, Violation{Field: field, Rule: "max", Kind: "number", Param: formatFloat(*schema.Maximum), Message: "must be at most " + formatFloat(*schema.Maximum)})
+	}
+
+	return violations
+}
+
+// coerce converts a raw parameter string to the JSON type of its schema
+func coerce(schema *Schema, raw string) (interface{}, *Violation) {
+	switch {
+	case allowsType(schema, "integer"):
+		if _, err := strconv.ParseInt(raw, 10, 64); err != nil {
+			return nil, &Violation{Rule: "type", Param: "integer", Message: "must be of type integer"}
+		}
+		return json.Number(raw), nil
+	case allowsType(schema, "number"):
+		if _, err := strconv.ParseFloat(raw, 64); err != nil {
+			return nil, &Violation{Rule: "type", Param: "number", Message: "must be of type number"}
+		}
+		return json.Number(raw), nil
+	case allowsType(schema, "boolean"):
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, &Violation{Rule: "type", Param: "boolean", Message: "must be of type boolean"}
+		}
+		return b, nil
+	default:
+		return raw, nil
+	}
+}
+
+func (v *Validator) resolve(s *Schema) *Schema {
+	if s == nil || s.Ref == "" {
+		return s
+	}
+	return v.doc.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+}
+
+// allowsType reports whether the schema's type keyword, a string or a list, includes t.
+// A schema without a type allows anything.
+func allowsType(s *Schema, t string) bool {
+	switch typ := s.Type.(type) {
+	case nil:
+		return true
+	case string:
+		return typ == t
+	case []interface{}:
+		for _, item := range typ {
+			if item == t {
+				return true
+			}
+		}
+	case []string:
+		for _, item := range typ {
+			if item == t {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func typeViolation(field, typ string) Violation {
+	return Violation{Field: field, Rule: "type", Param: typ, Message: "must be of type " + typ}
+}
+
+func bound(field, rule, kind string, n int, format string) Violation {
+	return Violation{Field: field, Rule: rule, Kind: kind, Param: strconv.Itoa(n), Message: fmt.Sprintf(format, n)}
+}
+
+func primaryType(s *Schema) interface{} {
+	if list, ok := s.Type.([]interface{}); ok && len(list) > 0 {
+		return list[0]
+	}
+	return s.Type
+}
+
+func inEnum(enum []interface{}, s string) bool {
+	for _, e := range enum {
+		if fmt.Sprint(e) == s {
+			return true
+		}
+	}
+	return false
+}
+
+func enumList(enum []interface{}) string {
+	values := make([]string, len(enum))
+	for i, e := range enum {
+		values[i] = fmt.Sprint(e)
+	}
+	return strings.Join(values, ", ")
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func join(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
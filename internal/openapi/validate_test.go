@@ -0,0 +1,277 @@
+package openapi
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func intRef(n int) *int { return &n }
+
+// testValidator documents POST /things, whose body is a Thing, and GET /things/:id
+func testValidator() (*Validator, *OpObject, *OpObject) {
+	thing := &Schema{
+		Type:     "object",
+		Required: []string{"name", "kind"},
+		Properties: map[string]*Schema{
+			"name":    {Type: "string", MinLength: intRef(2), MaxLength: intRef(5)},
+			"kind":    {Type: "string", Enum: []interface{}{"small", "large"}},
+			"count":   {Type: "integer", Minimum: float(1), Maximum: float(10)},
+			"ratio":   {Type: "number"},
+			"active":  {Type: "boolean"},
+			"note":    {Type: []interface{}{"string", "null"}},
+			"email":   {Type: "string", Format: "email"},
+			"site":    {Type: "string", Format: "uri"},
+			"seen_at": {Type: "string", Format: "date-time"},
+			"owner":   {Ref: "#/components/schemas/Owner"},
+			"tags": {
+				Type: "array", MinItems: intRef(1), MaxItems: intRef(2),
+				Items: &Schema{Type: "string", MinLength: intRef(1)},
+			},
+			"labels": {Type: "object", AdditionalProperties: &Schema{Type: "integer"}},
+		},
+	}
+	owner := &Schema{
+		Type:     "object",
+		Required: []string{"id"},
+		Properties: map[string]*Schema{
+			"id":      {Type: "integer"},
+			"address": {Type: "object", Properties: map[string]*Schema{"city": {Type: "string"}}},
+		},
+	}
+
+	create := &OpObject{
+		RequestBody: &RequestBody{
+			Required: true,
+			Content:  map[string]*MediaType{"application/json": {Schema: thing}},
+		},
+		Responses: map[string]*Response{
+			"201": {Content: map[string]*MediaType{"application/json": {Schema: &Schema{Ref: "#/components/schemas/Owner"}}}},
+			"204": {},
+		},
+	}
+	get := &OpObject{
+		Parameters: []*Parameter{
+			{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "integer", Minimum: float(1)}},
+			{Name: "page", In: "query", Schema: &Schema{Type: "integer"}},
+			{Name: "ratio", In: "query", Schema: &Schema{Type: "number"}},
+			{Name: "active", In: "query", Schema: &Schema{Type: "boolean"}},
+			{Name: "sort", In: "query", Required: true, Schema: &Schema{Type: "string", Enum: []interface{}{"asc", "desc"}}},
+		},
+	}
+
+	doc := &Document{
+		Paths: map[string]map[string]*OpObject{
+			"/things":      {"post": create},
+			"/things/{id}": {"get": get},
+		},
+		Components: Components{Schemas: map[string]*Schema{"Owner": owner}},
+	}
+	return NewValidator(doc), create, get
+}
+
+// fieldRules returns the field and rule of each violation, as "field rule"
+func fieldRules(violations []Violation) []string {
+	var got []string
+	for _, v := range violations {
+		got = append(got, v.Field+" "+v.Rule)
+	}
+	return got
+}
+
+func TestValidateRequestBody(t *testing.T) {
+	v, create, _ := testValidator()
+
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"valid", `{"name":"abc","kind":"small","count":3,"ratio":0.5,"active":true,"note":null}`, nil},
+		{"empty body", ``, []string{" required"}},
+		{"invalid json", `{"name":`, []string{" json"}},
+		{"not an object", `["abc"]`, []string{" type"}},
+		{"missing required", `{"count":3}`, []string{"name required", "kind required"}},
+		{"null for a required type", `{"name":null,"kind":"small"}`, []string{"name required"}},
+		{"string for integer", `{"name":"abc","kind":"small","count":"3"}`, []string{"count type"}},
+		{"fraction for integer", `{"name":"abc","kind":"small","count":2.5}`, []string{"count type"}},
+		{"integer for number", `{"name":"abc","kind":"small","ratio":2}`, nil},
+		{"string for number", `{"name":"abc","kind":"small","ratio":"2"}`, []string{"ratio type"}},
+		{"string for boolean", `{"name":"abc","kind":"small","active":"true"}`, []string{"active type"}},
+		{"number for string", `{"name":1,"kind":"small"}`, []string{"name type"}},
+		{"too short", `{"name":"a","kind":"small"}`, []string{"name min"}},
+		{"too long", `{"name":"abcdef","kind":"small"}`, []string{"name max"}},
+		{"length counts runes", `{"name":"ñññññ","kind":"small"}`, nil},
+		{"below minimum", `{"name":"abc","kind":"small","count":0}`, []string{"count min"}},
+		{"above maximum", `{"name":"abc","kind":"small","count":11}`, []string{"count max"}},
+		{"not in enum", `{"name":"abc","kind":"medium"}`, []string{"kind oneof"}},
+		{"unknown properties are ignored", `{"name":"abc","kind":"small","extra":{"x":1}}`, nil},
+		{"nullable union", `{"name":"abc","kind":"small","note":"hi"}`, nil},
+		{"wrong type for union", `{"name":"abc","kind":"small","note":1}`, []string{"note type"}},
+		{"several violations", `{"name":"a","kind":"medium","count":"x"}`, []string{"count type", "kind oneof", "name min"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fieldRules(v.ValidateRequestBody(create, []byte(tt.body)))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateRequestBody(%s) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateFormats(t *testing.T) {
+	v, create, _ := testValidator()
+
+	tests := []struct {
+		field string
+		value string
+		want  string
+	}{
+		{"email", `"jane@example.com"`, ""},
+		{"email", `"Jane <jane@example.com>"`, "email"},
+		{"email", `"jane"`, "email"},
+		{"site", `"https://example.com/hooks"`, ""},
+		{"site", `"/hooks"`, "url"},
+		{"site", `"example.com"`, "url"},
+		{"seen_at", `"2024-05-01T10:00:00Z"`, ""},
+		{"seen_at", `"2024-05-01T10:00:00+07:00"`, ""},
+		{"seen_at", `"2024-05-01"`, "datetime"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field+" "+tt.value, func(t *testing.T) {
+			body := `{"name":"abc","kind":"small","` + tt.field + `":` + tt.value + `}`
+			violations := v.ValidateRequestBody(create, []byte(body))
+
+			var want []string
+			if tt.want != "" {
+				want = []string{tt.field + " " + tt.want}
+			}
+			if got := fieldRules(violations); !reflect.DeepEqual(got, want) {
+				t.Errorf("ValidateRequestBody(%s) = %q, want %q", body, got, want)
+			}
+		})
+	}
+}
+
+func TestValidateNestedObjectsAndArrays(t *testing.T) {
+	v, create, _ := testValidator()
+
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"valid", `{"name":"abc","kind":"small","owner":{"id":1,"address":{"city":"Hanoi"}},"tags":["a","b"],"labels":{"x":1}}`, nil},
+		{"nested required through a ref", `{"name":"abc","kind":"small","owner":{}}`, []string{"owner.id required"}},
+		{"nested type", `{"name":"abc","kind":"small","owner":{"id":1,"address":{"city":1}}}`, []string{"owner.address.city type"}},
+		{"object for array", `{"name":"abc","kind":"small","tags":{"a":1}}`, []string{"tags type"}},
+		{"too few items", `{"name":"abc","kind":"small","tags":[]}`, []string{"tags min"}},
+		{"too many items", `{"name":"abc","kind":"small","tags":["a","b","c"]}`, []string{"tags max"}},
+		{"items are checked by index", `{"name":"abc","kind":"small","tags":["a",""]}`, []string{"tags[1] min"}},
+		{"item type", `{"name":"abc","kind":"small","tags":[1]}`, []string{"tags[0] type"}},
+		{"additional properties", `{"name":"abc","kind":"small","labels":{"x":1,"y":"2"}}`, []string{"labels.y type"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fieldRules(v.ValidateRequestBody(create, []byte(tt.body)))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateRequestBody(%s) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateViolationDetails(t *testing.T) {
+	v, create, _ := testValidator()
+
+	got := v.ValidateRequestBody(create, []byte(`{"name":"abcdef","kind":"medium","count":0,"tags":[]}`))
+	want := []Violation{
+		{Field: "count", Rule: "min", Kind: "number", Param: "1", Message: "must be at least 1"},
+		{Field: "kind", Rule: "oneof", Param: "small, large", Message: "must be one of small, large"},
+		{Field: "name", Rule: "max", Kind: "string", Param: "5", Message: "must be at most 5 characters long"},
+		{Field: "tags", Rule: "min", Kind: "array", Param: "1", Message: "must contain at least 1 items"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ValidateRequestBody() = %+v, want %+v", got, want)
+	}
+}
+
+func TestValidateParameters(t *testing.T) {
+	v, _, get := testValidator()
+
+	tests := []struct {
+		name  string
+		path  map[string]string
+		query string
+		want  []string
+	}{
+		{"valid", map[string]string{"id": "7"}, "sort=asc&page=2&ratio=0.5&active=true", nil},
+		{"missing required", map[string]string{"id": "7"}, "", []string{"sort required"}},
+		{"empty counts as missing", map[string]string{"id": "7"}, "sort=", []string{"sort required"}},
+		{"missing path parameter", nil, "sort=asc", []string{"id required"}},
+		{"not an integer", map[string]string{"id": "seven"}, "sort=asc&page=1.5", []string{"id type", "page type"}},
+		{"not a number", map[string]string{"id": "7"}, "sort=asc&ratio=half", []string{"ratio type"}},
+		{"not a boolean", map[string]string{"id": "7"}, "sort=asc&active=yes", []string{"active type"}},
+		{"coerced values are checked", map[string]string{"id": "0"}, "sort=up", []string{"id min", "sort oneof"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := fieldRules(v.ValidateParameters(get, tt.path, query))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateParameters(%v, %q) = %q, want %q", tt.path, tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateResponseBody(t *testing.T) {
+	v, create, _ := testValidator()
+
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   []string
+	}{
+		{"valid", 201, `{"id":1}`, nil},
+		{"invalid", 201, `{"id":"1"}`, []string{"id type"}},
+		{"missing required", 201, `{}`, []string{"id required"}},
+		{"no body documented", 204, ``, nil},
+		{"undocumented status", 500, `{"error":"boom"}`, []string{" status"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fieldRules(v.ValidateResponseBody(create, tt.status, []byte(tt.body)))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateResponseBody(%d, %s) = %q, want %q", tt.status, tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOperation(t *testing.T) {
+	v, create, get := testValidator()
+
+	if op, ok := v.Operation("POST", "/things"); !ok || op != create {
+		t.Errorf("Operation(POST, /things) = %v, %v", op, ok)
+	}
+	if op, ok := v.Operation("GET", "/things/:id"); !ok || op != get {
+		t.Errorf("Operation(GET, /things/:id) = %v, %v", op, ok)
+	}
+	if _, ok := v.Operation("DELETE", "/things/:id"); ok {
+		t.Error("Operation(DELETE, /things/:id) found an undocumented method")
+	}
+	if _, ok := v.Operation("GET", "/other"); ok {
+		t.Error("Operation(GET, /other) found an undocumented path")
+	}
+}
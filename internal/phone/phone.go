@@ -0,0 +1,31 @@
+// Package phone parses and formats phone numbers with libphonenumber. It has no
+// dependencies on the rest of the application, so that both the configuration
+// and the services can use it.
+package phone
+
+import (
+	"errors"
+
+	"github.com/ttacon/libphonenumber"
+)
+
+// ErrInvalid is returned for numbers that cannot be parsed or are not valid in
+// their region
+var ErrInvalid = errors.New("invalid phone number")
+
+// SupportedRegion reports whether region, an upper-case ISO 3166-1 alpha-2
+// code, has phone number metadata
+func SupportedRegion(region string) bool {
+	_, ok := libphonenumber.GetSupportedRegions()[region]
+	return ok
+}
+
+// ToE164 converts number to E.164, reading national numbers and international
+// prefixes as dialled from region
+func ToE164(number, region string) (string, error) {
+	parsed, err := libphonenumber.Parse(number, region)
+	if err != nil || !libphonenumber.IsValidNumber(parsed) {
+		return "", ErrInvalid
+	}
+	return libphonenumber.Format(parsed, libphonenumber.E164), nil
+}
@@ -0,0 +1,37 @@
+package phone
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSupportedRegion(t *testing.T) {
+	for region, want := range map[string]bool{"VN": true, "US": true, "GB": true, "vn": false, "XX": false, "": false} {
+		if got := SupportedRegion(region); got != want {
+			t.Errorf("SupportedRegion(%q) = %v, want %v", region, got, want)
+		}
+	}
+}
+
+func TestToE164(t *testing.T) {
+	tests := []struct {
+		number string
+		region string
+		want   string
+		err    error
+	}{
+		{"0912 345 678", "VN", "+84912345678", nil},
+		{"+84 912 345 678", "US", "+84912345678", nil},
+		{"(201) 555-0123", "US", "+12015550123", nil},
+		{"011 84 912 345 678", "US", "+84912345678", nil},
+		{"12", "VN", "", ErrInvalid},
+		{"not a number", "VN", "", ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		got, err := ToE164(tt.number, tt.region)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("ToE164(%q, %q) = %q, %v, want %q, %v", tt.number, tt.region, got, err, tt.want, tt.err)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package problem
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/raytr/go-template/internal/i18n"
+)
+
+// ContentType is the media type of RFC 7807 problem details
+const ContentType = "application/problem+json"
+
+// Problem type URIs, relative to the API base URL
+const (
+	TypeValidation = "/problems/validation-error"
+)
+
+// FieldError describes one invalid field of a request
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Problem is an RFC 7807 problem details object extended with field errors
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// Validation builds a 400 problem listing the invalid fields
+func Validation(l i18n.Localizer, instance string, errs []FieldError) *Problem {
+	return &Problem{
+		Type:     TypeValidation,
+		Title:    l.Message("validation.title"),
+		Status:   400,
+		Detail:   l.Message("validation.detail"),
+		Instance: instance,
+		Errors:   errs,
+	}
+}
+
+// Abort writes the problem as application/problem+json and stops the handler chain
+func Abort(c *gin.Context, p *Problem) {
+	c.Header("Content-Type", ContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
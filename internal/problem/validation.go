@@ -0,0 +1,141 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/raytr/go-template/internal/i18n"
+)
+
+// UseJSONFieldNames makes v report fields by their JSON name, or their form name
+// for query structs, instead of the Go field name
+func UseJSONFieldNames(v *validator.Validate) {
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "form"} {
+			name := strings.Split(field.Tag.Get(tag), ",")[0]
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return field.Name
+	})
+}
+
+// FromBindingError translates an error returned by gin's ShouldBind* methods into
+// a 400 problem. Validation failures list one field error per failed rule.
+//
+// Field errors are worded by RuleMessage rather than by translations registered
+// with the validator's RegisterTranslation: OpenAPI violations carry no
+// validator.FieldError, and sharing the rule.* catalog keys keeps one wording
+// for both.
+func FromBindingError(l i18n.Localizer, instance string, err error) *Problem {
+	var (
+		validationErrs validator.ValidationErrors
+		typeErr        *json.UnmarshalTypeError
+		syntaxErr      *json.SyntaxError
+	)
+
+	switch {
+	case errors.As(err, &validationErrs):
+		errs := make([]FieldError, len(validationErrs))
+		for i, fe := range validationErrs {
+			errs[i] = FieldError{
+				Field:   fieldPath(fe),
+				Rule:    fe.Tag(),
+				Message: RuleMessage(l, fe.Tag(), kindOf(fe.Kind()), ruleParam(fe)),
+			}
+		}
+		return Validation(l, instance, errs)
+	case errors.As(err, &typeErr):
+		return Validation(l, instance, []FieldError{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Message: RuleMessage(l, "type", "", jsonType(typeErr.Type.Kind())),
+		}})
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return Validation(l, instance, []FieldError{{
+			Rule:    "json",
+			Message: RuleMessage(l, "json", "", ""),
+		}})
+	default:
+		p := Validation(l, instance, nil)
+		p.Detail = l.Message("malformed.detail")
+		return p
+	}
+}
+
+// RuleMessage returns the message of a failed validation rule. kind is "string",
+// "array" or "number" for rules whose wording depends on the value's type.
+func RuleMessage(l i18n.Localizer, rule, kind, param string) string {
+	if kind != "" {
+		key := "rule." + rule + "." + kind
+		if msg := l.Message(key, param); msg != key {
+			return msg
+		}
+	}
+	if msg := l.Message("rule."+rule, param); msg != "rule."+rule {
+		return msg
+	}
+	return l.Message("rule.invalid")
+}
+
+// fieldPath returns the dotted JSON path of the field, without the root struct name
+func fieldPath(fe validator.FieldError) string {
+	_, path, found := strings.Cut(fe.Namespace(), ".")
+	if !found {
+		return fe.Field()
+	}
+	return path
+}
+
+func ruleParam(fe validator.FieldError) string {
+	if fe.Tag() == "oneof" {
+		return strings.Join(strings.Fields(fe.Param()), ", ")
+	}
+	return fe.Param()
+}
+
+// kindOf groups a kind into the wording categories of RuleMessage
+func kindOf(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return "array"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	default:
+		return ""
+	}
+}
+
+// jsonType names the JSON type expected for a Go kind
+func jsonType(kind reflect.Kind) string {
+	switch kindOf(kind) {
+	case "number":
+		if kind == reflect.Float32 || kind == reflect.Float64 {
+			return "number"
+		}
+		return "integer"
+	case "array":
+		if kind == reflect.Map {
+			return "object"
+		}
+		return "array"
+	case "string":
+		return "string"
+	}
+	if kind == reflect.Bool {
+		return "boolean"
+	}
+	return "object"
+}
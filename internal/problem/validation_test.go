@@ -0,0 +1,45 @@
+package problem
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/raytr/go-template/internal/i18n"
+)
+
+type address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type widget struct {
+	Name    string   `json:"name" validate:"required"`
+	Size    string   `json:"size" validate:"oneof=small large"`
+	Count   int      `json:"count" validate:"max=10"`
+	Tags    []string `json:"tags" validate:"max=2"`
+	Code    string   `json:"code" validate:"uuid"`
+	Address address  `json:"address"`
+}
+
+func TestFromBindingErrorLocalizesValidatorTags(t *testing.T) {
+	v := validator.New()
+	UseJSONFieldNames(v)
+	err := v.Struct(widget{Size: "medium", Count: 11, Tags: []string{"a", "b", "c"}, Code: "x"})
+
+	got := FromBindingError(i18n.For("vi"), "/widgets", err)
+	want := []FieldError{
+		{Field: "name", Rule: "required", Message: "là bắt buộc"},
+		{Field: "size", Rule: "oneof", Message: "phải là một trong các giá trị small, large"},
+		{Field: "count", Rule: "max", Message: "phải nhỏ hơn hoặc bằng 10"},
+		{Field: "tags", Rule: "max", Message: "chỉ được có tối đa 2 phần tử"},
+		// Tags without a catalog entry get the generic message
+		{Field: "code", Rule: "uuid", Message: "không hợp lệ"},
+		{Field: "address.city", Rule: "required", Message: "là bắt buộc"},
+	}
+	if !reflect.DeepEqual(got.Errors, want) {
+		t.Errorf("Errors = %+v, want %+v", got.Errors, want)
+	}
+	if got.Status != 400 || got.Title != "Yêu cầu không hợp lệ" {
+		t.Errorf("problem = %d %q, want a localized 400", got.Status, got.Title)
+	}
+}
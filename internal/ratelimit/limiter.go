@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter applies per-route rules, falling back to a default rule, on top of a Store
+type Limiter struct {
+	store       Store
+	mu          sync.RWMutex
+	defaultRule *Rule
+	routes      map[string]Rule
+}
+
+// NewLimiter creates a new limiter. A nil defaultRule leaves routes without
+// their own rule unlimited. Route keys are "<METHOD> <path pattern>".
+func NewLimiter(store Store, defaultRule *Rule, routes map[string]Rule) *Limiter {
+	return &Limiter{
+		store:       store,
+		defaultRule: defaultRule,
+		routes:      routes,
+	}
+}
+
+// SetRules replaces the rules, e.g. after a configuration reload. Buckets keep
+// their tokens and refill at the new rate.
+func (l *Limiter) SetRules(defaultRule *Rule, routes map[string]Rule) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.defaultRule = defaultRule
+	l.routes = routes
+}
+
+// RuleFor returns the rule that applies to a route
+func (l *Limiter) RuleFor(method, path string) (Rule, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if rule, ok := l.routes[method+" "+path]; ok {
+		return rule, true
+	}
+	if l.defaultRule != nil {
+		return *l.defaultRule, true
+	}
+	return Rule{}, false
+}
+
+// Allow takes a token for the client identified by identity on the given route.
+// The second return value is false when no rule applies to the route.
+func (l *Limiter) Allow(ctx context.Context, identity, method, path string) (Result, bool, error) {
+	rule, ok := l.RuleFor(method, path)
+	if !ok {
+		return Result{Allowed: true}, false, nil
+	}
+
+	result, err := l.store.Take(ctx, identity+"|"+method+" "+path, rule, time.Now())
+	if err != nil {
+		return Result{}, true, err
+	}
+
+	return result, true, nil
+}
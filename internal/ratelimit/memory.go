@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// MemoryStore keeps buckets in process memory. It is suitable for a single instance.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	idleTTL time.Duration
+	swept   time.Time
+}
+
+// NewMemoryStore creates a new in-memory store. Buckets untouched for idleTTL are dropped.
+func NewMemoryStore(idleTTL time.Duration) *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		idleTTL: idleTTL,
+	}
+}
+
+// Take removes one token from the bucket identified by key
+func (s *MemoryStore) Take(_ context.Context, key string, rule Rule, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(rule.Burst), last: now}
+		s.buckets[key] = b
+	}
+
+	tokens, result := refill(b.tokens, b.last, rule, now)
+	b.tokens = tokens
+	b.last = now
+
+	return result, nil
+}
+
+// sweep drops idle buckets at most once per idleTTL. The caller must hold s.mu.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.swept) < s.idleTTL {
+		return
+	}
+	s.swept = now
+
+	for key, b := range s.buckets {
+		if now.Sub(b.last) >= s.idleTTL {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BucketRow represents the rate_limit_buckets table in the database. It is
+// exported so that check-schema compares the table with it.
+type BucketRow struct {
+	Key       string    `gorm:"primaryKey;type:varchar(255)"`
+	Tokens    float64   `gorm:"type:double precision;not null"`
+	UpdatedAt time.Time `gorm:"type:timestamp;not null;index:idx_rate_limit_buckets_updated_at"`
+}
+
+// TableName specifies the table name for BucketRow
+func (BucketRow) TableName() string {
+	return "rate_limit_buckets"
+}
+
+// PostgresStore keeps buckets in the rate_limit_buckets table so limits are shared across instances
+type PostgresStore struct {
+	db      *gorm.DB
+	idleTTL time.Duration
+	mu      sync.Mutex
+	swept   time.Time
+}
+
+// NewPostgresStore creates a new Postgres-backed store. Buckets untouched for idleTTL are deleted.
+func NewPostgresStore(db *gorm.DB, idleTTL time.Duration) *PostgresStore {
+	return &PostgresStore{
+		db:      db,
+		idleTTL: idleTTL,
+	}
+}
+
+// Take removes one token from the bucket identified by key
+func (s *PostgresStore) Take(ctx context.Context, key string, rule Rule, now time.Time) (Result, error) {
+	var result Result
+
+	// updated_at is a TIMESTAMP, which keeps the wall clock and drops the zone,
+	// so every value written to or compared with it is in UTC
+	now = now.UTC()
+
+	s.sweep(ctx, now)
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		seed := &BucketRow{Key: key, Tokens: float64(rule.Burst), UpdatedAt: now}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(seed).Error; err != nil {
+			return err
+		}
+
+		var row BucketRow
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("key = ?", key).
+			First(&row).Error; err != nil {
+			return err
+		}
+
+		var tokens float64
+		tokens, result = refill(row.Tokens, row.UpdatedAt, rule, now)
+
+		return tx.Model(&BucketRow{}).
+			Where("key = ?", key).
+			Updates(map[string]interface{}{"tokens": tokens, "updated_at": now}).Error
+	})
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+
+	return result, nil
+}
+
+// sweep deletes idle buckets at most once per idleTTL per instance
+func (s *PostgresStore) sweep(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	due := now.Sub(s.swept) >= s.idleTTL
+	if due {
+		s.swept = now
+	}
+	s.mu.Unlock()
+
+	if due {
+		if err := s.DeleteIdle(ctx, now.Add(-s.idleTTL)); err != nil {
+			log.Printf("Rate limiter cleanup error: %v", err)
+		}
+	}
+}
+
+// DeleteIdle removes buckets that have not been touched since before
+func (s *PostgresStore) DeleteIdle(ctx context.Context, before time.Time) error {
+	err := s.db.WithContext(ctx).
+		Where("updated_at < ?", before.UTC()).
+		Delete(&BucketRow{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to delete idle rate limit buckets: %w", err)
+	}
+	return nil
+}
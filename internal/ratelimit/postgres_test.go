@@ -0,0 +1,136 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// argFunc matches a query argument with a function
+type argFunc func(driver.Value) bool
+
+func (f argFunc) Match(v driver.Value) bool {
+	return f(v)
+}
+
+// fakeBucket is the rate_limit_buckets row of one key
+type fakeBucket struct {
+	exists    bool
+	tokens    float64
+	updatedAt time.Time
+}
+
+// stored returns what a TIMESTAMP column gives back for v: pgx keeps the wall
+// clock and labels it UTC, whatever the zone of the value written
+func stored(v driver.Value) (time.Time, bool) {
+	t, ok := v.(time.Time)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC), true
+}
+
+func newMockStore(t *testing.T) (*PostgresStore, sqlmock.Sqlmock) {
+	t.Helper()
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	return NewPostgresStore(db, time.Hour), mock
+}
+
+// expectTake sets up the statements of one Take at now on b. A new bucket must
+// be seeded with the burst at now, and the row read back is b as it was left.
+func expectTake(mock sqlmock.Sqlmock, b *fakeBucket, rule Rule, now time.Time, sweep bool) {
+	if sweep {
+		mock.ExpectBegin()
+		mock.ExpectExec(`DELETE FROM "rate_limit_buckets" WHERE updated_at <`).
+			WithArgs(argFunc(func(v driver.Value) bool {
+				t, ok := v.(time.Time)
+				return ok && t.Location() == time.UTC
+			})).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+	}
+
+	seed := !b.exists
+	if seed {
+		b.exists, b.tokens, b.updatedAt = true, float64(rule.Burst), now.UTC()
+	}
+	row := *b
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "rate_limit_buckets"`).
+		WithArgs("key", sqlmock.AnyArg(), argFunc(func(v driver.Value) bool {
+			t, ok := stored(v)
+			return ok && (!seed || t.Equal(row.updatedAt))
+		})).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT \* FROM "rate_limit_buckets" WHERE key = \$1 .* FOR UPDATE`).
+		WillReturnRows(sqlmock.NewRows([]string{"key", "tokens", "updated_at"}).
+			AddRow("key", row.tokens, row.updatedAt))
+	mock.ExpectExec(`UPDATE "rate_limit_buckets" SET "tokens"=\$1,"updated_at"=\$2 WHERE key = \$3`).
+		WithArgs(argFunc(func(v driver.Value) bool {
+			tokens, ok := v.(float64)
+			b.tokens = tokens
+			return ok
+		}), argFunc(func(v driver.Value) bool {
+			t, ok := stored(v)
+			b.updatedAt = t
+			return ok
+		}), "key").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+func TestPostgresStoreRefillsInAnyTimeZone(t *testing.T) {
+	rule := Rule{Limit: 1, Period: time.Second, Burst: 1}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	takes := []struct {
+		after   time.Duration
+		allowed bool
+	}{
+		{0, true},
+		{500 * time.Millisecond, false},
+		{1500 * time.Millisecond, true},
+		{1600 * time.Millisecond, false},
+	}
+
+	zones := []*time.Location{time.UTC, time.FixedZone("UTC+7", 7*3600), time.FixedZone("UTC-5", -5*3600)}
+	for _, zone := range zones {
+		t.Run(zone.String(), func(t *testing.T) {
+			store, mock := newMockStore(t)
+			b := &fakeBucket{}
+
+			for i, take := range takes {
+				now := start.Add(take.after).In(zone)
+				expectTake(mock, b, rule, now, i == 0)
+
+				result, err := store.Take(context.Background(), "key", rule, now)
+				if err != nil {
+					t.Fatalf("take %d failed: %v", i, err)
+				}
+				if result.Allowed != take.allowed {
+					t.Fatalf("take after %s: allowed = %v, want %v", take.after, result.Allowed, take.allowed)
+				}
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule describes a token bucket: Burst tokens that refill at Limit per Period
+type Rule struct {
+	Limit  int
+	Period time.Duration
+	Burst  int
+}
+
+// RatePerSecond returns the refill rate of the bucket in tokens per second
+func (r Rule) RatePerSecond() float64 {
+	return float64(r.Limit) / r.Period.Seconds()
+}
+
+// String formats the rule in the syntax accepted by ParseRule
+func (r Rule) String() string {
+	return fmt.Sprintf("%d/%s:%d", r.Limit, r.Period, r.Burst)
+}
+
+// ParseRule parses a rule written as "<limit>/<period>[:<burst>]", e.g. "100/1m" or "10/1s:20".
+// Burst defaults to limit.
+func ParseRule(s string) (Rule, error) {
+	spec, burstStr, hasBurst := strings.Cut(strings.TrimSpace(s), ":")
+
+	limitStr, periodStr, ok := strings.Cut(spec, "/")
+	if !ok {
+		return Rule{}, fmt.Errorf("invalid rate limit %q: expected <limit>/<period>[:<burst>]", s)
+	}
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit < 1 {
+		return Rule{}, fmt.Errorf("invalid rate limit %q: limit must be a positive integer", s)
+	}
+
+	period, err := time.ParseDuration(periodStr)
+	if err != nil || period <= 0 {
+		return Rule{}, fmt.Errorf("invalid rate limit %q: period must be a positive duration", s)
+	}
+
+	burst := limit
+	if hasBurst {
+		burst, err = strconv.Atoi(burstStr)
+		if err != nil || burst < 1 {
+			return Rule{}, fmt.Errorf("invalid rate limit %q: burst must be a positive integer", s)
+		}
+	}
+
+	return Rule{Limit: limit, Period: period, Burst: burst}, nil
+}
+
+// ParseRouteRules parses comma separated "<METHOD> <path>=<rule>" entries,
+// e.g. "GET /api/v1/users=10/1s:20,POST /api/v1/users=5/1m"
+func ParseRouteRules(s string) (map[string]Rule, error) {
+	rules := make(map[string]Rule)
+
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		route, ruleStr, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid route rate limit %q: expected <METHOD> <path>=<rule>", entry)
+		}
+
+		rule, err := ParseRule(ruleStr)
+		if err != nil {
+			return nil, err
+		}
+
+		rules[strings.TrimSpace(route)] = rule
+	}
+
+	return rules, nil
+}
+
+// Result is the outcome of taking a token from a bucket
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	ResetAfter time.Duration
+}
+
+// Store keeps token bucket state. Implementations must be safe for concurrent use.
+type Store interface {
+	// Take removes one token from the bucket identified by key
+	Take(ctx context.Context, key string, rule Rule, now time.Time) (Result, error)
+}
+
+// refill computes the state of a bucket after taking a token.
+// Stores that keep state outside the process use the same arithmetic in SQL or Lua.
+func refill(tokens float64, last time.Time, rule Rule, now time.Time) (float64, Result) {
+	rate := rule.RatePerSecond()
+	burst := float64(rule.Burst)
+
+	elapsed := now.Sub(last).Seconds()
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	tokens = math.Min(burst, tokens+elapsed*rate)
+
+	result := Result{Limit: rule.Burst}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - tokens) / rate)
+	}
+
+	result.Remaining = int(math.Floor(tokens))
+	result.ResetAfter = secondsToDuration((burst - tokens) / rate)
+
+	return tokens, result
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
@@ -0,0 +1,116 @@
+package ratelimit
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Rule
+		wantErr bool
+	}{
+		{in: "100/1m", want: Rule{Limit: 100, Period: time.Minute, Burst: 100}},
+		{in: " 10/1s:20 ", want: Rule{Limit: 10, Period: time.Second, Burst: 20}},
+		{in: "100", wantErr: true},
+		{in: "0/1s", wantErr: true},
+		{in: "10/0s", wantErr: true},
+		{in: "10/soon", wantErr: true},
+		{in: "10/1s:0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRule(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRule(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRule(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseRouteRules(t *testing.T) {
+	got, err := ParseRouteRules("GET /api/v1/users=10/1s:20, POST /api/v1/users=5/1m,")
+	if err != nil {
+		t.Fatalf("ParseRouteRules failed: %v", err)
+	}
+	want := map[string]Rule{
+		"GET /api/v1/users":  {Limit: 10, Period: time.Second, Burst: 20},
+		"POST /api/v1/users": {Limit: 5, Period: time.Minute, Burst: 5},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseRouteRules = %+v, want %+v", got, want)
+	}
+
+	if _, err := ParseRouteRules("GET /api/v1/users"); err == nil {
+		t.Error("ParseRouteRules accepted an entry without a rule")
+	}
+}
+
+func TestRefill(t *testing.T) {
+	rule := Rule{Limit: 2, Period: time.Second, Burst: 4}
+	last := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		tokens     float64
+		elapsed    time.Duration
+		wantTokens float64
+		want       Result
+	}{
+		{"full", 4, 0, 3, Result{Allowed: true, Limit: 4, Remaining: 3, ResetAfter: 500 * time.Millisecond}},
+		{"empty", 0, 0, 0, Result{Limit: 4, RetryAfter: 500 * time.Millisecond, ResetAfter: 2 * time.Second}},
+		{"refilled", 0, time.Second, 1, Result{Allowed: true, Limit: 4, Remaining: 1, ResetAfter: 1500 * time.Millisecond}},
+		{"capped at burst", 1, time.Hour, 3, Result{Allowed: true, Limit: 4, Remaining: 3, ResetAfter: 500 * time.Millisecond}},
+		{"clock went back", 0.5, -time.Second, 0.5, Result{Limit: 4, RetryAfter: 250 * time.Millisecond, ResetAfter: 1750 * time.Millisecond}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, got := refill(tt.tokens, last, rule, last.Add(tt.elapsed))
+			if tokens != tt.wantTokens {
+				t.Errorf("tokens = %v, want %v", tokens, tt.wantTokens)
+			}
+			if got != tt.want {
+				t.Errorf("result = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore(time.Minute)
+	rule := Rule{Limit: 1, Period: time.Second, Burst: 2}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("UTC+7", 7*3600))
+
+	takes := []struct {
+		key     string
+		after   time.Duration
+		allowed bool
+	}{
+		{"a", 0, true},
+		{"a", 0, true},
+		{"a", 0, false},
+		{"b", 0, true},
+		{"a", time.Second, true},
+		{"a", time.Second, false},
+		// Idle buckets are dropped and start again full
+		{"a", 2 * time.Minute, true},
+		{"a", 2 * time.Minute, true},
+	}
+
+	for i, take := range takes {
+		result, err := store.Take(context.Background(), take.key, rule, now.Add(take.after))
+		if err != nil {
+			t.Fatalf("take %d failed: %v", i, err)
+		}
+		if result.Allowed != take.allowed {
+			t.Errorf("take %d of %q after %s: allowed = %v, want %v", i, take.key, take.after, result.Allowed, take.allowed)
+		}
+	}
+}
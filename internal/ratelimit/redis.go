@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// takeScript implements the same token bucket arithmetic as refill atomically in Redis.
+// KEYS[1] bucket key; ARGV: rate per second, burst, now in microseconds.
+// Returns {allowed, remaining, retry_after_us, reset_after_us}.
+var takeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "last")
+local tokens = tonumber(state[1]) or burst
+local last = tonumber(state[2]) or now
+
+local elapsed = math.max(0, now - last) / 1e6
+tokens = math.min(burst, tokens + elapsed * rate)
+
+local allowed = 0
+local retry_after = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  retry_after = math.ceil((1 - tokens) / rate * 1e6)
+end
+
+local reset_after = math.ceil((burst - tokens) / rate * 1e6)
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "last", tostring(now))
+redis.call("PEXPIRE", KEYS[1], math.ceil(reset_after / 1e3) + 1000)
+
+return {allowed, math.floor(tokens), retry_after, reset_after}
+`)
+
+// RedisStore keeps buckets in a Redis-compatible server so limits are shared across instances
+type RedisStore struct {
+	client    redis.UniversalClient
+	keyPrefix string
+}
+
+// NewRedisStore creates a new Redis-backed store
+func NewRedisStore(client redis.UniversalClient, keyPrefix string) *RedisStore {
+	return &RedisStore{
+		client:    client,
+		keyPrefix: keyPrefix,
+	}
+}
+
+// Take removes one token from the bucket identified by key
+func (s *RedisStore) Take(ctx context.Context, key string, rule Rule, now time.Time) (Result, error) {
+	values, err := takeScript.Run(ctx, s.client, []string{s.keyPrefix + key},
+		rule.RatePerSecond(), rule.Burst, now.UnixMicro(),
+	).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+
+	return Result{
+		Allowed:    values[0] == 1,
+		Limit:      rule.Burst,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Microsecond,
+		ResetAfter: time.Duration(values[3]) * time.Microsecond,
+	}, nil
+}
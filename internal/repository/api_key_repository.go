@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/raytr/go-template/internal/model"
+	"gorm.io/gorm"
+)
+
+// ErrAPIKeyNotFound is returned when no active API key matches the lookup
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// APIKeyRepository handles database operations for API keys using GORM
+type APIKeyRepository struct {
+	db *gorm.DB
+	*BasePaginationMethods
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(db *gorm.DB) *APIKeyRepository {
+	return &APIKeyRepository{
+		db:                    db,
+		BasePaginationMethods: NewPaginationMethods(db),
+	}
+}
+
+// Create inserts a new API key into the database
+func (r *APIKeyRepository) Create(key *model.APIKeyEntity) error {
+	if err := r.db.Create(key).Error; err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+	return nil
+}
+
+// GetByPrefix retrieves an API key by its public prefix
+func (r *APIKeyRepository) GetByPrefix(prefix string) (*model.APIKeyEntity, error) {
+	var key model.APIKeyEntity
+
+	if err := r.db.Where("prefix = ?", prefix).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	return &key, nil
+}
+
+// GetAll retrieves all API keys with pagination
+func (r *APIKeyRepository) GetAll(pagination *model.PaginationRequest) ([]*model.APIKeyEntity, error) {
+	var keys []*model.APIKeyEntity
+
+	if err := r.GetPaginatedRecords(&keys, &model.APIKeyEntity{}, pagination, "created_at DESC"); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Count returns the total number of API keys
+func (r *APIKeyRepository) Count() (int64, error) {
+	return r.CountRecords(&model.APIKeyEntity{})
+}
+
+// Revoke marks an API key as revoked
+func (r *APIKeyRepository) Revoke(id uint, at time.Time) error {
+	result := r.db.Model(&model.APIKeyEntity{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at)
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke api key: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// TouchLastUsed records when an API key was last used
+func (r *APIKeyRepository) TouchLastUsed(id uint, at time.Time) error {
+	err := r.db.Model(&model.APIKeyEntity{}).
+		Where("id = ?", id).
+		Update("last_used_at", at).Error
+	if err != nil {
+		return fmt.Errorf("failed to update api key usage: %w", err)
+	}
+	return nil
+}
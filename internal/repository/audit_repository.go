@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/raytr/go-template/internal/model"
+	"gorm.io/gorm"
+)
+
+// AuditRepository handles database operations for audit events using GORM
+type AuditRepository struct {
+	db *gorm.DB
+	*BasePaginationMethods
+}
+
+// NewAuditRepository creates a new audit repository
+func NewAuditRepository(db *gorm.DB) *AuditRepository {
+	return &AuditRepository{
+		db:                    db,
+		BasePaginationMethods: NewPaginationMethods(db),
+	}
+}
+
+// WithTx returns a copy of the repository that runs its queries in tx
+func (r *AuditRepository) WithTx(tx *gorm.DB) *AuditRepository {
+	return NewAuditRepository(tx)
+}
+
+// Create inserts a new audit event into the database
+func (r *AuditRepository) Create(event *model.AuditEventEntity) error {
+	if err := r.db.Create(event).Error; err != nil {
+		return fmt.Errorf("failed to create audit event: %w", err)
+	}
+	return nil
+}
+
+// GetAll retrieves audit events matching the filter with pagination, newest first
+func (r *AuditRepository) GetAll(filter *model.AuditEventFilter, pagination *model.PaginationRequest) ([]*model.AuditEventEntity, error) {
+	var events []*model.AuditEventEntity
+
+	query := r.applyFilter(r.db.Model(&model.AuditEventEntity{}), filter).Order("created_at DESC, id DESC")
+	query = r.ApplyPagination(query, pagination)
+
+	if err := query.Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to get audit events: %w", err)
+	}
+
+	return events, nil
+}
+
+// Count returns the number of audit events matching the filter
+func (r *AuditRepository) Count(filter *model.AuditEventFilter) (int64, error) {
+	var count int64
+
+	if err := r.applyFilter(r.db.Model(&model.AuditEventEntity{}), filter).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count audit events: %w", err)
+	}
+
+	return count, nil
+}
+
+// applyFilter adds a WHERE clause for every filter field that is set
+func (r *AuditRepository) applyFilter(query *gorm.DB, filter *model.AuditEventFilter) *gorm.DB {
+	if filter == nil {
+		return query
+	}
+
+	if filter.ActorType != "" {
+		query = query.Where("actor_type = ?", filter.ActorType)
+	}
+	if filter.ActorID != "" {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.RequestID != "" {
+		query = query.Where("request_id = ?", filter.RequestID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	return query
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/raytr/go-template/internal/flags"
+	"github.com/raytr/go-template/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrFeatureFlagNotFound is returned when no flag is stored under the key
+var ErrFeatureFlagNotFound = errors.New("feature flag not found")
+
+// FeatureFlagRepository handles database operations for feature flags using GORM
+type FeatureFlagRepository struct {
+	db *gorm.DB
+}
+
+// NewFeatureFlagRepository creates a new feature flag repository
+func NewFeatureFlagRepository(db *gorm.DB) *FeatureFlagRepository {
+	return &FeatureFlagRepository{db: db}
+}
+
+// WithTx returns a copy of the repository that runs its queries in tx
+func (r *FeatureFlagRepository) WithTx(tx *gorm.DB) *FeatureFlagRepository {
+	return NewFeatureFlagRepository(tx)
+}
+
+// Save inserts the flag or replaces the one stored under its key
+func (r *FeatureFlagRepository) Save(flag *model.FeatureFlagEntity) error {
+	err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"description", "enabled", "percentage", "user_ids", "roles", "variants", "updated_at"}),
+	}).Create(flag).Error
+	if err != nil {
+		return fmt.Errorf("failed to save feature flag: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the flag stored under key
+func (r *FeatureFlagRepository) Delete(key string) error {
+	result := r.db.Delete(&model.FeatureFlagEntity{}, "key = ?", key)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete feature flag: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrFeatureFlagNotFound
+	}
+	return nil
+}
+
+// GetAll retrieves every stored feature flag
+func (r *FeatureFlagRepository) GetAll() ([]*model.FeatureFlagEntity, error) {
+	var list []*model.FeatureFlagEntity
+
+	if err := r.db.Order("key").Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("failed to get feature flags: %w", err)
+	}
+
+	return list, nil
+}
+
+// ListFlags returns the stored flags as definitions for a flags.Store. A row that
+// cannot be converted fails the whole list so that the store keeps its flags.
+func (r *FeatureFlagRepository) ListFlags() ([]flags.Flag, error) {
+	list, err := r.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]flags.Flag, len(list))
+	for i, entity := range list {
+		if result[i], err = entity.ToFlag(); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
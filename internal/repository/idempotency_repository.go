@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/raytr/go-template/internal/model"
+	"gorm.io/gorm"
+)
+
+// IdempotencyRepository handles database operations for idempotency keys using GORM.
+// The TIMESTAMP columns keep no time zone, so times are written and compared in UTC.
+type IdempotencyRepository struct {
+	db *gorm.DB
+}
+
+// NewIdempotencyRepository creates a new idempotency repository
+func NewIdempotencyRepository(db *gorm.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{
+		db: db,
+	}
+}
+
+// Acquire claims key for a new request. It succeeds when the key is unused,
+// expired, or held by a request with the same fingerprint whose lock is older
+// than staleBefore (its handler crashed). Otherwise it returns the existing
+// record and acquired is false.
+func (r *IdempotencyRepository) Acquire(rec *model.IdempotencyKeyEntity, staleBefore time.Time) (acquired bool, existing *model.IdempotencyKeyEntity, err error) {
+	// The conflicting row may be swept between the two statements; try again then
+	for attempt := 0; attempt < 2; attempt++ {
+		var ids []uint64
+		err := r.db.Raw(`
+			INSERT INTO idempotency_keys (scope, key, fingerprint, status, locked_at, expires_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT (scope, key) DO UPDATE SET
+				fingerprint = EXCLUDED.fingerprint,
+				status = EXCLUDED.status,
+				response_status = NULL,
+				response_headers = NULL,
+				response_body = NULL,
+				locked_at = EXCLUDED.locked_at,
+				expires_at = EXCLUDED.expires_at,
+				created_at = EXCLUDED.created_at
+			WHERE idempotency_keys.expires_at <= EXCLUDED.locked_at
+				OR (idempotency_keys.status = ?
+					AND idempotency_keys.locked_at <= ?
+					AND idempotency_keys.fingerprint = EXCLUDED.fingerprint)
+			RETURNING id`,
+			rec.Scope, rec.Key, rec.Fingerprint, model.IdempotencyStatusProcessing, rec.LockedAt.UTC(), rec.ExpiresAt.UTC(),
+			model.IdempotencyStatusProcessing, staleBefore.UTC(),
+		).Scan(&ids).Error
+		if err != nil {
+			return false, nil, fmt.Errorf("failed to acquire idempotency key: %w", err)
+		}
+		if len(ids) == 1 {
+			rec.ID = ids[0]
+			rec.Status = model.IdempotencyStatusProcessing
+			return true, nil, nil
+		}
+
+		var found model.IdempotencyKeyEntity
+		err = r.db.Where("scope = ? AND key = ?", rec.Scope, rec.Key).First(&found).Error
+		if err == nil {
+			return false, &found, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil, fmt.Errorf("failed to get idempotency key: %w", err)
+		}
+	}
+
+	return false, nil, fmt.Errorf("failed to acquire idempotency key: key keeps disappearing")
+}
+
+// Complete stores the response of the request holding the key
+func (r *IdempotencyRepository) Complete(id uint64, status int, headers map[string]string, body []byte) error {
+	err := r.db.Model(&model.IdempotencyKeyEntity{ID: id}).
+		Updates(&model.IdempotencyKeyEntity{
+			Status:          model.IdempotencyStatusCompleted,
+			ResponseStatus:  status,
+			ResponseHeaders: headers,
+			ResponseBody:    body,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+// Release deletes a key so that the request can be retried
+func (r *IdempotencyRepository) Release(id uint64) error {
+	if err := r.db.Delete(&model.IdempotencyKeyEntity{}, id).Error; err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired deletes the keys that expired before now and returns how many it deleted
+func (r *IdempotencyRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at <= ?", now.UTC()).Delete(&model.IdempotencyKeyEntity{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/raytr/go-template/internal/model"
+)
+
+const (
+	acquireKey = `INSERT INTO idempotency_keys`
+	findKey    = `SELECT \* FROM "idempotency_keys" WHERE scope = \$1 AND key = \$2`
+)
+
+var keyColumns = []string{"id", "scope", "key", "fingerprint", "status", "response_status", "response_headers", "response_body", "locked_at", "expires_at", "created_at"}
+
+func newKey(now time.Time) *model.IdempotencyKeyEntity {
+	return &model.IdempotencyKeyEntity{
+		Scope:       "user:1 /api/v1/users",
+		Key:         "key-1",
+		Fingerprint: "fp-1",
+		LockedAt:    now,
+		ExpiresAt:   now.Add(24 * time.Hour),
+	}
+}
+
+func TestIdempotencyRepositoryAcquire(t *testing.T) {
+	local := time.FixedZone("UTC+7", 7*3600)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, local)
+	staleBefore := now.Add(-time.Minute)
+
+	tests := []struct {
+		name     string
+		expect   func(mock sqlmock.Sqlmock)
+		acquired bool
+		existing string
+	}{
+		{
+			name: "unused key",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(acquireKey).
+					WithArgs("user:1 /api/v1/users", "key-1", "fp-1", "processing", utcTime{}, utcTime{}, "processing", utcTime{}).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+			},
+			acquired: true,
+		},
+		{
+			// The stale lock check is made by the upsert, which returns the row it took over
+			name: "stale lock taken over",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(acquireKey).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+						"processing", staleBefore.UTC()).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+			},
+			acquired: true,
+		},
+		{
+			name: "held by a running request",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(acquireKey).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectQuery(findKey).
+					WithArgs("user:1 /api/v1/users", "key-1", 1).
+					WillReturnRows(sqlmock.NewRows(keyColumns).
+						AddRow(3, "user:1 /api/v1/users", "key-1", "fp-1", "processing", nil, nil, nil, now, now, now))
+			},
+			existing: "processing",
+		},
+		{
+			name: "completed with another body",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(acquireKey).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectQuery(findKey).
+					WillReturnRows(sqlmock.NewRows(keyColumns).
+						AddRow(3, "user:1 /api/v1/users", "key-1", "fp-2", "completed", 201, `{"Content-Type":"application/json"}`, []byte(`{}`), now, now, now))
+			},
+			existing: "completed",
+		},
+		{
+			name: "swept between the statements",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(acquireKey).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectQuery(findKey).WillReturnRows(sqlmock.NewRows(keyColumns))
+				mock.ExpectQuery(acquireKey).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+			},
+			acquired: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			tt.expect(mock)
+
+			rec := newKey(now)
+			acquired, existing, err := NewIdempotencyRepository(db).Acquire(rec, staleBefore)
+			if err != nil {
+				t.Fatalf("Acquire() error = %v", err)
+			}
+			if acquired != tt.acquired {
+				t.Fatalf("Acquire() acquired = %v, want %v", acquired, tt.acquired)
+			}
+
+			if tt.acquired {
+				if rec.ID == 0 || rec.Status != model.IdempotencyStatusProcessing {
+					t.Errorf("acquired key = %+v, want an ID and the processing status", rec)
+				}
+				if existing != nil {
+					t.Errorf("Acquire() existing = %+v, want nil", existing)
+				}
+				return
+			}
+			if existing == nil || existing.Status != tt.existing {
+				t.Fatalf("Acquire() existing = %+v, want status %s", existing, tt.existing)
+			}
+		})
+	}
+}
+
+func TestIdempotencyRepositoryAcquireKeepsDisappearing(t *testing.T) {
+	db, mock := newMockDB(t)
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery(acquireKey).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery(findKey).WillReturnRows(sqlmock.NewRows(keyColumns))
+	}
+
+	now := time.Now()
+	if _, _, err := NewIdempotencyRepository(db).Acquire(newKey(now), now); err == nil {
+		t.Fatal("Acquire() error = nil, want an error")
+	}
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/raytr/go-template/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OutboxRepository handles database operations for outbox events using GORM.
+// The TIMESTAMP columns keep no time zone, so times are written and compared in UTC.
+type OutboxRepository struct {
+	db *gorm.DB
+}
+
+// NewOutboxRepository creates a new outbox repository
+func NewOutboxRepository(db *gorm.DB) *OutboxRepository {
+	return &OutboxRepository{
+		db: db,
+	}
+}
+
+// WithTx returns a copy of the repository that runs its queries in tx
+func (r *OutboxRepository) WithTx(tx *gorm.DB) *OutboxRepository {
+	return NewOutboxRepository(tx)
+}
+
+// Create inserts a new outbox event into the database
+func (r *OutboxRepository) Create(event *model.OutboxEventEntity) error {
+	event.NextAttemptAt = event.NextAttemptAt.UTC()
+	if err := r.db.Create(event).Error; err != nil {
+		return fmt.Errorf("failed to create outbox event: %w", err)
+	}
+	return nil
+}
+
+// ClaimDue claims up to limit pending events that are due at now, oldest first,
+// by moving their next attempt to leaseUntil. Other relays skip them until the
+// lease expires, so a relay that dies while publishing leaves them to be retried.
+// The claim commits on its own: no lock is held while the events are published.
+func (r *OutboxRepository) ClaimDue(now time.Time, limit int, leaseUntil time.Time) ([]*model.OutboxEventEntity, error) {
+	due := r.db.Model(&model.OutboxEventEntity{}).
+		Select("id").
+		Where("status = ? AND next_attempt_at <= ?", model.OutboxStatusPending, now.UTC()).
+		Order("id").
+		Limit(limit).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+
+	var events []*model.OutboxEventEntity
+	err := r.db.Model(&events).
+		Clauses(clause.Returning{}).
+		Where("id IN (?)", due).
+		Update("next_attempt_at", leaseUntil.UTC()).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox events: %w", err)
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].ID < events[j].ID
+	})
+	return events, nil
+}
+
+// MarkDelivered records a successful delivery
+func (r *OutboxRepository) MarkDelivered(id uint64, attempts int, at time.Time) error {
+	err := r.db.Model(&model.OutboxEventEntity{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":       model.OutboxStatusDelivered,
+			"attempts":     attempts,
+			"last_error":   nil,
+			"delivered_at": at.UTC(),
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox event delivered: %w", err)
+	}
+	return nil
+}
+
+// MarkRetry records a failed delivery and schedules the next attempt
+func (r *OutboxRepository) MarkRetry(id uint64, attempts int, lastError string, nextAttemptAt time.Time) error {
+	err := r.db.Model(&model.OutboxEventEntity{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":        attempts,
+			"last_error":      lastError,
+			"next_attempt_at": nextAttemptAt.UTC(),
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to schedule outbox event retry: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed records that an event exhausted its delivery attempts
+func (r *OutboxRepository) MarkFailed(id uint64, attempts int, lastError string) error {
+	err := r.db.Model(&model.OutboxEventEntity{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":     model.OutboxStatusFailed,
+			"attempts":   attempts,
+			"last_error": lastError,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox event failed: %w", err)
+	}
+	return nil
+}
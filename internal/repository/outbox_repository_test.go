@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/raytr/go-template/internal/model"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// utcTime matches a time written in UTC
+type utcTime struct{}
+
+func (utcTime) Match(v driver.Value) bool {
+	t, ok := v.(time.Time)
+	return ok && t.Location() == time.UTC
+}
+
+// newMockDB returns a Postgres handle backed by sqlmock. Statements run
+// without GORM's default transaction.
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		conn.Close()
+	})
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{
+		Logger:                 logger.Discard,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	return db, mock
+}
+
+func TestOutboxRepositoryWritesUTC(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewOutboxRepository(db)
+	local := time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("UTC-5", -5*3600))
+
+	mock.ExpectQuery(`INSERT INTO "outbox_events"`).
+		WithArgs("id", "user.created", "user", "42", sqlmock.AnyArg(), "pending", 0, "", utcTime{}, nil, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	event := &model.OutboxEventEntity{
+		EventID: "id", EventType: "user.created", AggregateType: "user", AggregateID: "42",
+		Payload: []byte(`{}`), Status: model.OutboxStatusPending, NextAttemptAt: local,
+	}
+	if err := repo.Create(event); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	mock.ExpectQuery(`UPDATE "outbox_events" SET "next_attempt_at"=\$1 WHERE id IN \(SELECT "id" FROM "outbox_events" WHERE status = \$2 AND next_attempt_at <= \$3`).
+		WithArgs(utcTime{}, model.OutboxStatusPending, utcTime{}, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	if _, err := repo.ClaimDue(local, 10, local.Add(time.Minute)); err != nil {
+		t.Fatalf("ClaimDue failed: %v", err)
+	}
+
+	mock.ExpectExec(`UPDATE "outbox_events" SET "attempts"=\$1,"last_error"=\$2,"next_attempt_at"=\$3`).
+		WithArgs(1, "boom", utcTime{}, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := repo.MarkRetry(1, 1, "boom", local); err != nil {
+		t.Fatalf("MarkRetry failed: %v", err)
+	}
+
+	mock.ExpectExec(`UPDATE "outbox_events" SET "attempts"=\$1,"delivered_at"=\$2,"last_error"=\$3,"status"=\$4`).
+		WithArgs(2, utcTime{}, nil, model.OutboxStatusDelivered, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := repo.MarkDelivered(1, 2, local); err != nil {
+		t.Fatalf("MarkDelivered failed: %v", err)
+	}
+}
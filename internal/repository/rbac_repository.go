@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/raytr/go-template/internal/model"
+	"gorm.io/gorm"
+)
+
+// RBACRepository handles database operations for roles and permissions using GORM
+type RBACRepository struct {
+	db *gorm.DB
+}
+
+// NewRBACRepository creates a new RBAC repository
+func NewRBACRepository(db *gorm.DB) *RBACRepository {
+	return &RBACRepository{
+		db: db,
+	}
+}
+
+// GetRoleNamesByUserID retrieves the names of the roles assigned to a user
+func (r *RBACRepository) GetRoleNamesByUserID(userID uint) ([]string, error) {
+	var names []string
+
+	err := r.db.Model(&model.RoleEntity{}).
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", userID).
+		Order("roles.name").
+		Pluck("roles.name", &names).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user roles: %w", err)
+	}
+
+	return names, nil
+}
+
+// GetPermissionNamesByRoles retrieves the distinct permission names granted to the given roles
+func (r *RBACRepository) GetPermissionNamesByRoles(roleNames []string) ([]string, error) {
+	var names []string
+
+	if len(roleNames) == 0 {
+		return names, nil
+	}
+
+	err := r.db.Model(&model.PermissionEntity{}).
+		Distinct("permissions.name").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Joins("JOIN roles ON roles.id = role_permissions.role_id").
+		Where("roles.name IN ?", roleNames).
+		Pluck("permissions.name", &names).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role permissions: %w", err)
+	}
+
+	return names, nil
+}
+
+// AssignRole grants the named role to a user, ignoring existing assignments
+func (r *RBACRepository) AssignRole(userID uint, roleName string) error {
+	var role model.RoleEntity
+
+	if err := r.db.Where("name = ?", roleName).First(&role).Error; err != nil {
+		return fmt.Errorf("failed to find role %q: %w", roleName, err)
+	}
+
+	err := r.db.Exec(
+		"INSERT INTO user_roles (user_id, role_id) VALUES (?, ?) ON CONFLICT DO NOTHING",
+		userID, role.ID,
+	).Error
+	if err != nil {
+		return fmt.Errorf("failed to assign role: %w", err)
+	}
+
+	return nil
+}
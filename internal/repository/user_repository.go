@@ -3,9 +3,22 @@ package repository
 import (
 	"errors"
 	"fmt"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/raytr/go-template/internal/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// emailUniqueIndex is the case-insensitive unique index on users.email
+const emailUniqueIndex = "users_email_lower_key"
+
+var (
+	// ErrUserNotFound is returned when no user matches the lookup
+	ErrUserNotFound = errors.New("user not found")
+	// ErrEmailTaken is returned when another user already has the email, in any case
+	ErrEmailTaken = errors.New("email is already in use")
 )
 
 // UserRepository handles database operations for users using GORM
@@ -22,9 +35,17 @@ func NewUserRepository(db *gorm.DB) *UserRepository {
 	}
 }
 
+// WithTx returns a copy of the repository that runs its queries in tx
+func (r *UserRepository) WithTx(tx *gorm.DB) *UserRepository {
+	return NewUserRepository(tx)
+}
+
 // Create inserts a new user into the database
 func (r *UserRepository) Create(user *model.UserEntity) error {
 	if err := r.db.Create(user).Error; err != nil {
+		if isEmailConflict(err) {
+			return ErrEmailTaken
+		}
 		return fmt.Errorf("failed to create user: %w", err)
 	}
 	return nil
@@ -36,7 +57,7 @@ func (r *UserRepository) GetByID(id uint) (*model.UserEntity, error) {
 
 	if err := r.db.First(&user, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
@@ -44,6 +65,96 @@ func (r *UserRepository) GetByID(id uint) (*model.UserEntity, error) {
 	return &user, nil
 }
 
+// GetByCode retrieves a user by code
+func (r *UserRepository) GetByCode(code string) (*model.UserEntity, error) {
+	return r.getByCode(r.db, code)
+}
+
+// GetByCodeForUpdate retrieves a user by code and locks the row until the transaction ends
+func (r *UserRepository) GetByCodeForUpdate(code string) (*model.UserEntity, error) {
+	return r.getByCode(r.db.Clauses(clause.Locking{Strength: "UPDATE"}), code)
+}
+
+func (r *UserRepository) getByCode(db *gorm.DB, code string) (*model.UserEntity, error) {
+	var user model.UserEntity
+
+	if err := db.Where("code = ?", code).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return &user, nil
+}
+
+// Upsert inserts the user, or replaces the fields of the user with the same code.
+// It fills in the ID and timestamps and reports whether a row was inserted.
+func (r *UserRepository) Upsert(user *model.UserEntity) (bool, error) {
+	var result struct {
+		ID        uint
+		CreatedAt time.Time
+		UpdatedAt time.Time
+		Inserted  bool
+	}
+
+	// xmax is 0 for a freshly inserted row version
+	err := r.db.Raw(`
+		INSERT INTO users (code, name, email, phone, address)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (code) DO UPDATE SET
+			name = EXCLUDED.name,
+			email = EXCLUDED.email,
+			phone = EXCLUDED.phone,
+			address = EXCLUDED.address
+		RETURNING id, created_at, updated_at, (xmax = 0) AS inserted`,
+		user.Code, user.Name, user.Email, user.Phone, user.Address,
+	).Scan(&result).Error
+	if err != nil {
+		if isEmailConflict(err) {
+			return false, ErrEmailTaken
+		}
+		return false, fmt.Errorf("failed to upsert user: %w", err)
+	}
+
+	user.ID = result.ID
+	user.CreatedAt = result.CreatedAt
+	user.UpdatedAt = result.UpdatedAt
+
+	return result.Inserted, nil
+}
+
+// GetByEmail retrieves a user by email, ignoring case
+func (r *UserRepository) GetByEmail(email string) (*model.UserEntity, error) {
+	var user model.UserEntity
+
+	if err := r.db.Where("lower(email) = lower(?)", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return &user, nil
+}
+
+// FindDuplicateEmails lists the emails shared by several users when case is ignored
+func (r *UserRepository) FindDuplicateEmails() ([]*model.DuplicateEmail, error) {
+	var duplicates []*model.DuplicateEmail
+
+	err := r.db.Raw(`
+		SELECT lower(email) AS email, array_agg(id ORDER BY id) AS user_ids
+		FROM users
+		GROUP BY lower(email)
+		HAVING count(*) > 1
+		ORDER BY lower(email)`).Scan(&duplicates).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find duplicate emails: %w", err)
+	}
+
+	return duplicates, nil
+}
+
 // GetAll retrieves all users with pagination
 func (r *UserRepository) GetAll(pagination *model.PaginationRequest) ([]*model.UserEntity, error) {
 	var users []*model.UserEntity
@@ -58,6 +169,9 @@ func (r *UserRepository) GetAll(pagination *model.PaginationRequest) ([]*model.U
 // Update updates an existing user
 func (r *UserRepository) Update(user *model.UserEntity) error {
 	if err := r.db.Save(user).Error; err != nil {
+		if isEmailConflict(err) {
+			return ErrEmailTaken
+		}
 		return fmt.Errorf("failed to update user: %w", err)
 	}
 	return nil
@@ -71,7 +185,7 @@ func (r *UserRepository) Delete(id uint) error {
 	}
 
 	if result.RowsAffected == 0 {
-		return fmt.Errorf("user not found")
+		return ErrUserNotFound
 	}
 
 	return nil
@@ -81,3 +195,9 @@ func (r *UserRepository) Delete(id uint) error {
 func (r *UserRepository) Count() (int64, error) {
 	return r.CountRecords(&model.UserEntity{})
 }
+
+// isEmailConflict reports whether err violates the unique email index
+func isEmailConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == emailUniqueIndex
+}
@@ -0,0 +1,285 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/raytr/go-template/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrWebhookNotFound is returned when no webhook subscription has the ID
+	ErrWebhookNotFound = errors.New("webhook subscription not found")
+	// ErrWebhookDeliveryNotFound is returned when no webhook delivery has the ID
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+// WebhookRepository handles database operations for webhook subscriptions and deliveries using GORM.
+// The TIMESTAMP columns keep no time zone, so times are written and compared in UTC.
+type WebhookRepository struct {
+	db *gorm.DB
+	*BasePaginationMethods
+}
+
+// NewWebhookRepository creates a new webhook repository
+func NewWebhookRepository(db *gorm.DB) *WebhookRepository {
+	return &WebhookRepository{
+		db:                    db,
+		BasePaginationMethods: NewPaginationMethods(db),
+	}
+}
+
+// WithTx returns a copy of the repository that runs its queries in tx
+func (r *WebhookRepository) WithTx(tx *gorm.DB) *WebhookRepository {
+	return NewWebhookRepository(tx)
+}
+
+// CreateSubscription inserts a new webhook subscription into the database
+func (r *WebhookRepository) CreateSubscription(sub *model.WebhookSubscriptionEntity) error {
+	if err := r.db.Create(sub).Error; err != nil {
+		return fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	return nil
+}
+
+// GetSubscriptionByID retrieves a webhook subscription by ID
+func (r *WebhookRepository) GetSubscriptionByID(id uint) (*model.WebhookSubscriptionEntity, error) {
+	var sub model.WebhookSubscriptionEntity
+
+	if err := r.db.First(&sub, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook subscription: %w", err)
+	}
+
+	return &sub, nil
+}
+
+// GetAllSubscriptions retrieves all webhook subscriptions with pagination
+func (r *WebhookRepository) GetAllSubscriptions(pagination *model.PaginationRequest) ([]*model.WebhookSubscriptionEntity, error) {
+	var subs []*model.WebhookSubscriptionEntity
+
+	if err := r.GetPaginatedRecords(&subs, &model.WebhookSubscriptionEntity{}, pagination, "created_at DESC"); err != nil {
+		return nil, err
+	}
+
+	return subs, nil
+}
+
+// CountSubscriptions returns the total number of webhook subscriptions
+func (r *WebhookRepository) CountSubscriptions() (int64, error) {
+	return r.CountRecords(&model.WebhookSubscriptionEntity{})
+}
+
+// UpdateSubscription updates an existing webhook subscription
+func (r *WebhookRepository) UpdateSubscription(sub *model.WebhookSubscriptionEntity) error {
+	if err := r.db.Save(sub).Error; err != nil {
+		return fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+	return nil
+}
+
+// DeleteSubscription removes a webhook subscription and its deliveries
+func (r *WebhookRepository) DeleteSubscription(id uint) error {
+	result := r.db.Delete(&model.WebhookSubscriptionEntity{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// EnqueueDeliveries creates a pending delivery of the event for every active
+// subscription whose filter matches its type, or that subscribes to "*"
+func (r *WebhookRepository) EnqueueDeliveries(eventID, eventType string, payload []byte, now time.Time) error {
+	err := r.db.Exec(`
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload, status, next_attempt_at)
+		SELECT id, ?, ?, ?, ?, ?
+		FROM webhook_subscriptions
+		WHERE active AND (? = ANY(event_types) OR '*' = ANY(event_types))
+		ON CONFLICT (subscription_id, event_id) DO NOTHING`,
+		eventID, eventType, payload, model.WebhookDeliveryPending, now.UTC(), eventType,
+	).Error
+	if err != nil {
+		return fmt.Errorf("failed to enqueue webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// ClaimDueDeliveries claims up to limit pending deliveries that are due at now,
+// oldest first, by moving their next attempt to leaseUntil. Other dispatchers
+// skip them until the lease expires. The claim commits on its own, so no lock
+// is held while the deliveries are sent.
+func (r *WebhookRepository) ClaimDueDeliveries(now time.Time, limit int, leaseUntil time.Time) ([]*model.WebhookDeliveryEntity, error) {
+	due := r.db.Model(&model.WebhookDeliveryEntity{}).
+		Select("id").
+		Where("status = ? AND next_attempt_at <= ?", model.WebhookDeliveryPending, now.UTC()).
+		Order("id").
+		Limit(limit).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+
+	var deliveries []*model.WebhookDeliveryEntity
+	err := r.db.Model(&deliveries).
+		Clauses(clause.Returning{}).
+		Where("id IN (?)", due).
+		Update("next_attempt_at", leaseUntil.UTC()).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].ID < deliveries[j].ID
+	})
+	return deliveries, nil
+}
+
+// GetDeliveryByID retrieves a webhook delivery by ID
+func (r *WebhookRepository) GetDeliveryByID(id uint64) (*model.WebhookDeliveryEntity, error) {
+	var delivery model.WebhookDeliveryEntity
+
+	if err := r.db.First(&delivery, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookDeliveryNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+
+	return &delivery, nil
+}
+
+// GetDeliveries retrieves webhook deliveries matching the filter with pagination, newest first
+func (r *WebhookRepository) GetDeliveries(filter *model.WebhookDeliveryFilter, pagination *model.PaginationRequest) ([]*model.WebhookDeliveryEntity, error) {
+	var deliveries []*model.WebhookDeliveryEntity
+
+	query := r.applyDeliveryFilter(r.db.Model(&model.WebhookDeliveryEntity{}), filter).Order("id DESC")
+	query = r.ApplyPagination(query, pagination)
+
+	if err := query.Find(&deliveries).Error; err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// CountDeliveries returns the number of webhook deliveries matching the filter
+func (r *WebhookRepository) CountDeliveries(filter *model.WebhookDeliveryFilter) (int64, error) {
+	var count int64
+
+	if err := r.applyDeliveryFilter(r.db.Model(&model.WebhookDeliveryEntity{}), filter).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count webhook deliveries: %w", err)
+	}
+
+	return count, nil
+}
+
+// UpdateDelivery records the delivery state after an attempt. It only applies
+// while the delivery is still pending under the lease it was claimed with, so a
+// redelivery scheduled meanwhile is kept. It reports whether the update applied.
+func (r *WebhookRepository) UpdateDelivery(delivery *model.WebhookDeliveryEntity, leasedUntil time.Time) (bool, error) {
+	var deliveredAt interface{}
+	if delivery.DeliveredAt != nil {
+		deliveredAt = delivery.DeliveredAt.UTC()
+	}
+
+	result := r.leased(delivery.ID, leasedUntil).
+		Updates(map[string]interface{}{
+			"status":           delivery.Status,
+			"attempts":         delivery.Attempts,
+			"last_status_code": delivery.LastStatusCode,
+			"last_error":       delivery.LastError,
+			"next_attempt_at":  delivery.NextAttemptAt.UTC(),
+			"delivered_at":     deliveredAt,
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to update webhook delivery: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// CancelDelivery records that a delivery will not be attempted, with the reason.
+// Like UpdateDelivery, it only applies under the lease and reports whether it did.
+func (r *WebhookRepository) CancelDelivery(id uint64, reason string, leasedUntil time.Time) (bool, error) {
+	result := r.leased(id, leasedUntil).
+		Updates(map[string]interface{}{
+			"status":     model.WebhookDeliveryCancelled,
+			"last_error": reason,
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to cancel webhook delivery: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// leased selects a delivery that is still pending under the lease a dispatcher claimed it with
+func (r *WebhookRepository) leased(id uint64, leasedUntil time.Time) *gorm.DB {
+	return r.db.Model(&model.WebhookDeliveryEntity{}).
+		Where("id = ? AND status = ? AND next_attempt_at = ?", id, model.WebhookDeliveryPending, leasedUntil.UTC())
+}
+
+// Redeliver resets a delivery to pending so it is attempted again with a fresh attempt budget
+func (r *WebhookRepository) Redeliver(id uint64, now time.Time) error {
+	result := r.db.Model(&model.WebhookDeliveryEntity{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":          model.WebhookDeliveryPending,
+			"attempts":        0,
+			"next_attempt_at": now.UTC(),
+			"delivered_at":    nil,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to schedule webhook redelivery: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrWebhookDeliveryNotFound
+	}
+
+	return nil
+}
+
+// CreateAttempt records a single delivery attempt
+func (r *WebhookRepository) CreateAttempt(attempt *model.WebhookDeliveryAttemptEntity) error {
+	if err := r.db.Create(attempt).Error; err != nil {
+		return fmt.Errorf("failed to record webhook delivery attempt: %w", err)
+	}
+	return nil
+}
+
+// GetAttempts retrieves every attempt made for a delivery, oldest first
+func (r *WebhookRepository) GetAttempts(deliveryID uint64) ([]*model.WebhookDeliveryAttemptEntity, error) {
+	var attempts []*model.WebhookDeliveryAttemptEntity
+
+	if err := r.db.Where("delivery_id = ?", deliveryID).Order("id").Find(&attempts).Error; err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery attempts: %w", err)
+	}
+
+	return attempts, nil
+}
+
+// applyDeliveryFilter adds a WHERE clause for every filter field that is set
+func (r *WebhookRepository) applyDeliveryFilter(query *gorm.DB, filter *model.WebhookDeliveryFilter) *gorm.DB {
+	if filter == nil {
+		return query
+	}
+
+	if filter.SubscriptionID != 0 {
+		query = query.Where("subscription_id = ?", filter.SubscriptionID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.EventID != "" {
+		query = query.Where("event_id = ?", filter.EventID)
+	}
+
+	return query
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/raytr/go-template/internal/model"
+)
+
+func TestWebhookRepositoryWritesUTC(t *testing.T) {
+	db, mock := newMockDB(t)
+	repo := NewWebhookRepository(db)
+	local := time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("UTC-5", -5*3600))
+
+	mock.ExpectExec(`INSERT INTO webhook_deliveries`).
+		WithArgs("id", "user.created", sqlmock.AnyArg(), model.WebhookDeliveryPending, utcTime{}, "user.created").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := repo.EnqueueDeliveries("id", "user.created", []byte(`{}`), local); err != nil {
+		t.Fatalf("EnqueueDeliveries failed: %v", err)
+	}
+
+	mock.ExpectQuery(`UPDATE "webhook_deliveries" SET "next_attempt_at"=\$1 WHERE id IN \(SELECT "id" FROM "webhook_deliveries" WHERE status = \$2 AND next_attempt_at <= \$3`).
+		WithArgs(utcTime{}, model.WebhookDeliveryPending, utcTime{}, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	if _, err := repo.ClaimDueDeliveries(local, 10, local.Add(time.Minute)); err != nil {
+		t.Fatalf("ClaimDueDeliveries failed: %v", err)
+	}
+
+	mock.ExpectExec(`UPDATE "webhook_deliveries" SET "attempts"=\$1,"delivered_at"=\$2,"next_attempt_at"=\$3,"status"=\$4 WHERE id = \$5`).
+		WithArgs(0, nil, utcTime{}, model.WebhookDeliveryPending, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := repo.Redeliver(1, local); err != nil {
+		t.Fatalf("Redeliver failed: %v", err)
+	}
+}
+
+func TestWebhookRepositoryUpdateDeliveryKeepsRedelivery(t *testing.T) {
+	lease := time.Date(2024, 5, 1, 12, 5, 0, 0, time.UTC)
+	delivered := lease.Add(-4 * time.Minute)
+	statusCode := 200
+	delivery := &model.WebhookDeliveryEntity{
+		ID: 7, Status: model.WebhookDeliveryDelivered, Attempts: 1, LastStatusCode: &statusCode,
+		NextAttemptAt: lease, DeliveredAt: &delivered,
+	}
+
+	tests := []struct {
+		name     string
+		affected int64
+		want     bool
+	}{
+		{"still leased", 1, true},
+		{"redelivered meanwhile", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			mock.ExpectExec(`UPDATE "webhook_deliveries" SET "attempts"=\$1,"delivered_at"=\$2,"last_error"=\$3,"last_status_code"=\$4,"next_attempt_at"=\$5,"status"=\$6 WHERE id = \$7 AND status = \$8 AND next_attempt_at = \$9`).
+				WithArgs(1, delivered, "", 200, lease, model.WebhookDeliveryDelivered, 7, model.WebhookDeliveryPending, lease).
+				WillReturnResult(sqlmock.NewResult(0, tt.affected))
+
+			updated, err := NewWebhookRepository(db).UpdateDelivery(delivery, lease)
+			if err != nil {
+				t.Fatalf("UpdateDelivery failed: %v", err)
+			}
+			if updated != tt.want {
+				t.Errorf("UpdateDelivery = %v, want %v", updated, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/raytr/go-template/internal/auth"
+	"github.com/raytr/go-template/internal/model"
+	"github.com/raytr/go-template/internal/repository"
+)
+
+// APIKeyService handles business logic for API keys
+type APIKeyService struct {
+	apiKeyRepo *repository.APIKeyRepository
+	policy     *auth.Policy
+	*BasePaginationService
+}
+
+// NewAPIKeyService creates a new API key service
+func NewAPIKeyService(apiKeyRepo *repository.APIKeyRepository, policy *auth.Policy) *APIKeyService {
+	return &APIKeyService{
+		apiKeyRepo:            apiKeyRepo,
+		policy:                policy,
+		BasePaginationService: NewBasePaginationService(),
+	}
+}
+
+// CreateAPIKey creates a new API key and returns it with its plaintext value.
+// Callers can only grant scopes they hold themselves.
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, req *model.CreateAPIKeyReq) (*model.APIKeyEntity, string, error) {
+	if err := s.policy.Authorize(ctx, auth.PermAPIKeysManage); err != nil {
+		return nil, "", err
+	}
+
+	if err := validateAPIKey(req); err != nil {
+		return nil, "", err
+	}
+
+	for _, scope := range req.Scopes {
+		if err := s.policy.Authorize(ctx, auth.Permission(scope)); err != nil {
+			return nil, "", err
+		}
+	}
+
+	plaintext, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+
+	key := &model.APIKeyEntity{
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   hash,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	}
+
+	if principal, ok := auth.PrincipalFromContext(ctx); ok && principal.UserID != 0 {
+		createdBy := principal.UserID
+		key.CreatedBy = &createdBy
+	}
+
+	if err := s.apiKeyRepo.Create(key); err != nil {
+		return nil, "", err
+	}
+
+	return key, plaintext, nil
+}
+
+// GetAllAPIKeys retrieves all API keys with pagination
+func (s *APIKeyService) GetAllAPIKeys(ctx context.Context, page, pageSize int) ([]*model.APIKeyEntity, int64, error) {
+	if err := s.policy.Authorize(ctx, auth.PermAPIKeysManage); err != nil {
+		return nil, 0, err
+	}
+
+	pagination, err := s.CreatePaginationRequest(page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	keys, err := s.apiKeyRepo.GetAll(pagination)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	totalCount, err := s.apiKeyRepo.Count()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return keys, totalCount, nil
+}
+
+// RevokeAPIKey revokes an API key so it can no longer authenticate
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, id uint) error {
+	if err := s.policy.Authorize(ctx, auth.PermAPIKeysManage); err != nil {
+		return err
+	}
+
+	return s.apiKeyRepo.Revoke(id, time.Now())
+}
+
+// validateAPIKey checks that the scopes are known permissions and that the key
+// does not expire in the past
+func validateAPIKey(req *model.CreateAPIKeyReq) error {
+	verr := &ValidationError{}
+
+	for _, scope := range req.Scopes {
+		if !auth.IsKnownPermission(scope) {
+			names := make([]string, len(auth.KnownPermissions))
+			for i, perm := range auth.KnownPermissions {
+				names[i] = string(perm)
+			}
+			verr.Add("scopes", "oneof", strings.Join(names, " "))
+			break
+		}
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		verr.Add("expires_at", "future", "")
+	}
+
+	return verr.OrNil()
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+
+	"github.com/raytr/go-template/internal/auth"
+	"github.com/raytr/go-template/internal/model"
+	"github.com/raytr/go-template/internal/repository"
+)
+
+// AuditService handles business logic for the audit log
+type AuditService struct {
+	auditRepo *repository.AuditRepository
+	policy    *auth.Policy
+	*BasePaginationService
+}
+
+// NewAuditService creates a new audit service
+func NewAuditService(auditRepo *repository.AuditRepository, policy *auth.Policy) *AuditService {
+	return &AuditService{
+		auditRepo:             auditRepo,
+		policy:                policy,
+		BasePaginationService: NewBasePaginationService(),
+	}
+}
+
+// GetAuditEvents retrieves audit events matching the filter with pagination
+func (s *AuditService) GetAuditEvents(ctx context.Context, filter *model.AuditEventFilter, page, pageSize int) ([]*model.AuditEventEntity, int64, error) {
+	if err := s.policy.Authorize(ctx, auth.PermAuditRead); err != nil {
+		return nil, 0, err
+	}
+
+	pagination, err := s.CreatePaginationRequest(page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	events, err := s.auditRepo.GetAll(filter, pagination)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	totalCount, err := s.auditRepo.Count(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return events, totalCount, nil
+}
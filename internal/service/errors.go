@@ -0,0 +1,54 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ValidationError lists the fields of a request that failed the service's rules
+type ValidationError struct {
+	Fields []FieldViolation
+}
+
+// FieldViolation is one failed rule. Param is the rule's argument, if any.
+type FieldViolation struct {
+	Field string
+	Rule  string
+	Param string
+}
+
+// Add records a failed rule
+func (e *ValidationError) Add(field, rule, param string) {
+	e.Fields = append(e.Fields, FieldViolation{Field: field, Rule: rule, Param: param})
+}
+
+// OrNil returns e if it recorded any failure
+func (e *ValidationError) OrNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		parts[i] = f.Field + ": " + f.Rule
+		if f.Param != "" {
+			parts[i] += "=" + strconv.Quote(f.Param)
+		}
+	}
+	return "validation failed: " + strings.Join(parts, ", ")
+}
+
+// ConflictError reports that a write would duplicate a unique value. Code
+// identifies the conflict, e.g. "email_taken".
+type ConflictError struct {
+	Code  string
+	Field string
+	Value string
+}
+
+func (e *ConflictError) Error() string {
+	return e.Field + " " + strconv.Quote(e.Value) + " is already in use"
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"log"
+
+	"github.com/raytr/go-template/internal/auth"
+	"github.com/raytr/go-template/internal/flags"
+	"github.com/raytr/go-template/internal/model"
+	"github.com/raytr/go-template/internal/repository"
+)
+
+// FeatureFlagService handles business logic for managing feature flags. Other
+// replicas see changes at their next refresh.
+type FeatureFlagService struct {
+	flagRepo *repository.FeatureFlagRepository
+	policy   *auth.Policy
+	store    *flags.Store
+}
+
+// NewFeatureFlagService creates a new feature flag service
+func NewFeatureFlagService(flagRepo *repository.FeatureFlagRepository, policy *auth.Policy, store *flags.Store) *FeatureFlagService {
+	return &FeatureFlagService{
+		flagRepo: flagRepo,
+		policy:   policy,
+		store:    store,
+	}
+}
+
+// GetAllFlags returns every flag definition with its source
+func (s *FeatureFlagService) GetAllFlags(ctx context.Context) ([]flags.Flag, error) {
+	if err := s.policy.Authorize(ctx, auth.PermFlagsManage); err != nil {
+		return nil, err
+	}
+
+	return s.store.All(), nil
+}
+
+// SetFlag stores the definition of a flag, replacing any configured one
+func (s *FeatureFlagService) SetFlag(ctx context.Context, key string, req *model.SetFeatureFlagReq) (*flags.Flag, error) {
+	if err := s.policy.Authorize(ctx, auth.PermFlagsManage); err != nil {
+		return nil, err
+	}
+
+	if err := validateFlag(key, req); err != nil {
+		return nil, err
+	}
+
+	entity, err := model.NewFeatureFlagEntity(key, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.flagRepo.Save(entity); err != nil {
+		return nil, err
+	}
+	s.refresh()
+
+	flag, err := entity.ToFlag()
+	if err != nil {
+		return nil, err
+	}
+	return &flag, nil
+}
+
+// DeleteFlag removes the stored definition of a flag. A configured definition
+// applies again.
+func (s *FeatureFlagService) DeleteFlag(ctx context.Context, key string) error {
+	if err := s.policy.Authorize(ctx, auth.PermFlagsManage); err != nil {
+		return err
+	}
+
+	if err := s.flagRepo.Delete(key); err != nil {
+		return err
+	}
+	s.refresh()
+
+	return nil
+}
+
+// refresh applies a change on this replica at once. The change is saved, so a
+// failure is only logged and the next periodic refresh picks it up.
+func (s *FeatureFlagService) refresh() {
+	if err := s.store.Refresh(); err != nil {
+		log.Printf("Feature flags refresh error: %v", err)
+	}
+}
+
+// validateFlag checks the key and variants of a flag definition
+func validateFlag(key string, req *model.SetFeatureFlagReq) error {
+	verr := &ValidationError{}
+
+	if !flags.ValidKey(key) {
+		verr.Add("key", "pattern", flags.KeyPattern)
+	}
+
+	seen := make(map[string]bool, len(req.Variants))
+	for _, v := range req.Variants {
+		if seen[v.Name] {
+			verr.Add("variants", "unique", "name")
+			break
+		}
+		seen[v.Name] = true
+	}
+
+	return verr.OrNil()
+}
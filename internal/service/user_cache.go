@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/raytr/go-template/internal/cache"
+	"github.com/raytr/go-template/internal/model"
+	"github.com/raytr/go-template/internal/repository"
+)
+
+// userListVersionKey is bumped on every user write so that cached list pages
+// are never read again
+const userListVersionKey = "users:list:version"
+
+// userPage is a cached page of the user list
+type userPage struct {
+	Users []*model.UserEntity `json:"users"`
+	Total int64               `json:"total"`
+}
+
+// UserCache caches user reads made outside transactions. A nil UserCache reads
+// straight from the repository.
+type UserCache struct {
+	cache *cache.Cache
+}
+
+// NewUserCache creates a user cache on top of c
+func NewUserCache(c *cache.Cache) *UserCache {
+	return &UserCache{
+		cache: c,
+	}
+}
+
+// GetByID returns the user with the given ID
+func (uc *UserCache) GetByID(ctx context.Context, userRepo *repository.UserRepository, id uint) (*model.UserEntity, error) {
+	if uc == nil {
+		return userRepo.GetByID(id)
+	}
+
+	return cache.Fetch(ctx, uc.cache, userKey(id), func() (*model.UserEntity, error) {
+		return userRepo.GetByID(id)
+	})
+}
+
+// GetPage returns a page of users and the total number of users
+func (uc *UserCache) GetPage(ctx context.Context, userRepo *repository.UserRepository, pagination *model.PaginationRequest) ([]*model.UserEntity, int64, error) {
+	load := func() (*userPage, error) {
+		users, err := userRepo.GetAll(pagination)
+		if err != nil {
+			return nil, err
+		}
+		total, err := userRepo.Count()
+		if err != nil {
+			return nil, err
+		}
+		return &userPage{Users: users, Total: total}, nil
+	}
+
+	var (
+		page *userPage
+		err  error
+	)
+	if uc == nil {
+		page, err = load()
+	} else {
+		// Pages are keyed by the list version, so bumping it drops them all
+		version := uc.cache.Version(ctx, userListVersionKey)
+		key := fmt.Sprintf("users:list:v%d:%d:%d", version, pagination.Page, pagination.PageSize)
+		page, err = cache.Fetch(ctx, uc.cache, key, load)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return page.Users, page.Total, nil
+}
+
+// Invalidate drops the cached users with the given IDs and every cached list
+// page. Call it once the write is committed.
+func (uc *UserCache) Invalidate(ctx context.Context, ids ...uint) {
+	if uc == nil {
+		return
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = userKey(id)
+	}
+	uc.cache.Invalidate(ctx, keys, userListVersionKey)
+}
+
+func userKey(id uint) string {
+	return fmt.Sprintf("users:id:%d", id)
+}
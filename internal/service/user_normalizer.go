@@ -0,0 +1,188 @@
+package service
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/raytr/go-template/internal/model"
+	"github.com/raytr/go-template/internal/phone"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Defaults for UserRules fields left empty
+const (
+	DefaultPhoneRegion = "VN"
+	DefaultCodePattern = `^[A-Z0-9][A-Z0-9_-]{1,49}$`
+)
+
+// UserRules configures how user fields are normalized and validated
+type UserRules struct {
+	// PhoneRegion is the region (ISO 3166-1 alpha-2) of phone numbers written
+	// without a country code
+	PhoneRegion string
+	// CodePattern is matched against the upper-cased user code
+	CodePattern string
+	// AllowedEmailDomains, when set, lists the only accepted email domains
+	AllowedEmailDomains []string
+	// DeniedEmailDomains lists rejected email domains
+	DeniedEmailDomains []string
+}
+
+// UserNormalizer normalizes and validates user input. Every path that writes
+// users goes through it so that the same rules apply everywhere.
+type UserNormalizer struct {
+	phoneRegion string
+	codePattern *regexp.Regexp
+	allowed     []string
+	denied      []string
+}
+
+// NewUserNormalizer creates a normalizer for the given rules
+func NewUserNormalizer(rules UserRules) (*UserNormalizer, error) {
+	if rules.PhoneRegion == "" {
+		rules.PhoneRegion = DefaultPhoneRegion
+	}
+	if rules.CodePattern == "" {
+		rules.CodePattern = DefaultCodePattern
+	}
+
+	phoneRegion := strings.ToUpper(rules.PhoneRegion)
+	if !phone.SupportedRegion(phoneRegion) {
+		return nil, fmt.Errorf("unsupported phone region %q", rules.PhoneRegion)
+	}
+
+	codePattern, err := regexp.Compile(rules.CodePattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid code pattern: %w", err)
+	}
+
+	return &UserNormalizer{
+		phoneRegion: phoneRegion,
+		codePattern: codePattern,
+		allowed:     lowerAll(rules.AllowedEmailDomains),
+		denied:      lowerAll(rules.DeniedEmailDomains),
+	}, nil
+}
+
+// NormalizeCreate normalizes req in place and validates every field
+func (n *UserNormalizer) NormalizeCreate(req *model.CreateUserReq) error {
+	var errs ValidationError
+
+	req.Code = n.code(req.Code, &errs)
+	req.Name = n.name(req.Name, &errs)
+	req.Email = n.email(req.Email, &errs)
+	req.Phone = n.phone(req.Phone, &errs)
+	req.Address = strings.TrimSpace(req.Address)
+
+	return errs.OrNil()
+}
+
+// NormalizeUpdate normalizes req in place and validates the fields it sets
+func (n *UserNormalizer) NormalizeUpdate(req *model.UpdateUserReq) error {
+	var errs ValidationError
+
+	if req.Name != "" {
+		req.Name = n.name(req.Name, &errs)
+	}
+	if req.Email != "" {
+		req.Email = n.email(req.Email, &errs)
+	}
+	if req.Phone != "" {
+		req.Phone = n.phone(req.Phone, &errs)
+	}
+	req.Address = strings.TrimSpace(req.Address)
+
+	return errs.OrNil()
+}
+
+// NormalizeCode returns the stored form of a user code, for lookups by code
+func (n *UserNormalizer) NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// code upper-cases the code and matches it against the configured pattern
+func (n *UserNormalizer) code(code string, errs *ValidationError) string {
+	code = n.NormalizeCode(code)
+
+	switch {
+	case code == "":
+		errs.Add("code", "required", "")
+	case !n.codePattern.MatchString(code):
+		errs.Add("code", "pattern", n.codePattern.String())
+	}
+
+	return code
+}
+
+// name trims the name, collapses inner whitespace and applies Unicode NFC
+func (n *UserNormalizer) name(name string, errs *ValidationError) string {
+	name = norm.NFC.String(strings.Join(strings.Fields(name), " "))
+
+	switch {
+	case name == "":
+		errs.Add("name", "notblank", "")
+	case utf8.RuneCountInString(name) > 255:
+		errs.Add("name", "max", "255")
+	}
+
+	return name
+}
+
+// email lower-cases the address and checks its domain against the allow and deny lists
+func (n *UserNormalizer) email(email string, errs *ValidationError) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		errs.Add("email", "email", "")
+		return email
+	}
+
+	domain := email[strings.LastIndex(email, "@")+1:]
+	if len(n.allowed) > 0 && !matchesDomain(domain, n.allowed) || matchesDomain(domain, n.denied) {
+		errs.Add("email", "email_domain", domain)
+	}
+
+	return email
+}
+
+// phone converts the number to E.164, reading national numbers and
+// international prefixes as dialled from the default region. Empty numbers are
+// left empty.
+func (n *UserNormalizer) phone(number string, errs *ValidationError) string {
+	number = strings.TrimSpace(number)
+	if number == "" {
+		return ""
+	}
+
+	e164, err := phone.ToE164(number, n.phoneRegion)
+	if err != nil {
+		errs.Add("phone", "e164", "")
+		return number
+	}
+
+	return e164
+}
+
+// matchesDomain reports whether domain is one of domains or a subdomain of one
+func matchesDomain(domain string, domains []string) bool {
+	for _, d := range domains {
+		if domain == d || strings.HasSuffix(domain, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+func lowerAll(values []string) []string {
+	lowered := make([]string, 0, len(values))
+	for _, v := range values {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			lowered = append(lowered, v)
+		}
+	}
+	return lowered
+}
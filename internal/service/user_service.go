@@ -1,107 +1,386 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"strings"
 
+	"github.com/raytr/go-template/internal/audit"
+	"github.com/raytr/go-template/internal/auth"
+	"github.com/raytr/go-template/internal/events"
 	"github.com/raytr/go-template/internal/model"
 	"github.com/raytr/go-template/internal/repository"
+	"gorm.io/gorm"
 )
 
+// auditEntityUser is the entity type recorded in audit events for users
+const auditEntityUser = "user"
+
 // UserService handles business logic for users
 type UserService struct {
-	userRepo *repository.UserRepository
+	userRepo    *repository.UserRepository
+	auditRepo   *repository.AuditRepository
+	outboxRepo  *repository.OutboxRepository
+	webhookRepo *repository.WebhookRepository
+	transactor  *repository.Transactor
+	policy      *auth.Policy
+	normalizer  *UserNormalizer
+	cache       *UserCache
 	*BasePaginationService
 }
 
 // NewUserService creates a new user service
-func NewUserService(userRepo *repository.UserRepository) *UserService {
+func NewUserService(
+	userRepo *repository.UserRepository,
+	auditRepo *repository.AuditRepository,
+	outboxRepo *repository.OutboxRepository,
+	webhookRepo *repository.WebhookRepository,
+	transactor *repository.Transactor,
+	policy *auth.Policy,
+	normalizer *UserNormalizer,
+	cache *UserCache,
+) *UserService {
 	return &UserService{
 		userRepo:              userRepo,
+		auditRepo:             auditRepo,
+		outboxRepo:            outboxRepo,
+		webhookRepo:           webhookRepo,
+		transactor:            transactor,
+		policy:                policy,
+		normalizer:            normalizer,
+		cache:                 cache,
 		BasePaginationService: NewBasePaginationService(),
 	}
 }
 
 // CreateUser creates a new user
-func (s *UserService) CreateUser(req *model.CreateUserReq) (*model.UserEntity, error) {
+func (s *UserService) CreateUser(ctx context.Context, req *model.CreateUserReq) (*model.UserEntity, error) {
+	if err := s.policy.Authorize(ctx, auth.PermUsersCreate); err != nil {
+		return nil, err
+	}
+
+	if err := s.normalizer.NormalizeCreate(req); err != nil {
+		return nil, err
+	}
+
 	// Create user entity
 	user := &model.UserEntity{
 		Code:    req.Code,
 		Name:    req.Name,
-		Email:   strings.ToLower(req.Email),
+		Email:   req.Email,
 		Phone:   req.Phone,
 		Address: req.Address,
 	}
 
-	// Save to database
-	if err := s.userRepo.Create(user); err != nil {
+	// Save to database together with its audit and domain events
+	err := s.transactor.WithinTransaction(ctx, func(tx *gorm.DB) error {
+		userRepo := s.userRepo.WithTx(tx)
+
+		if err := checkEmailAvailable(userRepo, user.Email, 0); err != nil {
+			return err
+		}
+		if err := userRepo.Create(user); err != nil {
+			return emailConflict(err, user.Email)
+		}
+
+		event := audit.NewEvent(ctx, model.AuditActionUserCreated, auditEntityUser, user.ID, audit.Diff(nil, user))
+		if err := s.auditRepo.WithTx(tx).Create(event); err != nil {
+			return err
+		}
+
+		return s.emit(tx, events.NewUserCreated, user)
+	})
+	if err != nil {
 		return nil, err
 	}
+	s.cache.Invalidate(ctx, user.ID)
 
 	return user, nil
 }
 
 // GetUserByID retrieves a user by ID
-func (s *UserService) GetUserByID(id uint) (*model.UserEntity, error) {
-	return s.userRepo.GetByID(id)
+func (s *UserService) GetUserByID(ctx context.Context, id uint) (*model.UserEntity, error) {
+	if err := s.policy.AuthorizeOwned(ctx, auth.PermUsersRead, id); err != nil {
+		return nil, err
+	}
+
+	return s.cache.GetByID(ctx, s.userRepo, id)
 }
 
-// GetAllUsers retrieves all users with pagination
-func (s *UserService) GetAllUsers(page, pageSize int) ([]*model.UserEntity, int64, error) {
-	// Create and validate pagination request
-	pagination, err := s.CreatePaginationRequest(page, pageSize)
+// GetUserByEmail retrieves a user by email, ignoring case
+func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*model.UserEntity, error) {
+	return s.getOwned(ctx, func() (*model.UserEntity, error) {
+		return s.userRepo.GetByEmail(strings.TrimSpace(email))
+	})
+}
+
+// GetUserByCode retrieves a user by code
+func (s *UserService) GetUserByCode(ctx context.Context, code string) (*model.UserEntity, error) {
+	return s.getOwned(ctx, func() (*model.UserEntity, error) {
+		return s.userRepo.GetByCode(s.normalizer.NormalizeCode(code))
+	})
+}
+
+// getOwned looks up a user and then checks that the caller may read it
+func (s *UserService) getOwned(ctx context.Context, lookup func() (*model.UserEntity, error)) (*model.UserEntity, error) {
+	user, err := lookup()
 	if err != nil {
-		return nil, 0, err
+		// Callers limited to their own user get the same answer whether or not
+		// the user exists
+		if authErr := s.policy.Authorize(ctx, auth.PermUsersRead); authErr != nil {
+			return nil, s.policy.AuthorizeOwned(ctx, auth.PermUsersRead, 0)
+		}
+		return nil, err
 	}
 
-	// Get users using new pagination system
-	users, err := s.userRepo.GetAll(pagination)
-	if err != nil {
+	if err := s.policy.AuthorizeOwned(ctx, auth.PermUsersRead, user.ID); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// GetAllUsers retrieves all users with pagination
+func (s *UserService) GetAllUsers(ctx context.Context, page, pageSize int) ([]*model.UserEntity, int64, error) {
+	if err := s.policy.Authorize(ctx, auth.PermUsersList); err != nil {
 		return nil, 0, err
 	}
 
-	// Get total count
-	totalCount, err := s.userRepo.Count()
+	// Create and validate pagination request
+	pagination, err := s.CreatePaginationRequest(page, pageSize)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	return users, totalCount, nil
+	return s.cache.GetPage(ctx, s.userRepo, pagination)
 }
 
 // UpdateUser updates an existing user
-func (s *UserService) UpdateUser(id uint, req *model.UpdateUserReq) (*model.UserEntity, error) {
-	// Check if user exists
-	existingUser, err := s.userRepo.GetByID(id)
+func (s *UserService) UpdateUser(ctx context.Context, id uint, req *model.UpdateUserReq) (*model.UserEntity, error) {
+	if err := s.policy.AuthorizeOwned(ctx, auth.PermUsersUpdate, id); err != nil {
+		return nil, err
+	}
+
+	if err := s.normalizer.NormalizeUpdate(req); err != nil {
+		return nil, err
+	}
+
+	var existingUser *model.UserEntity
+
+	err := s.transactor.WithinTransaction(ctx, func(tx *gorm.DB) error {
+		userRepo := s.userRepo.WithTx(tx)
+
+		// Check if user exists
+		var err error
+		existingUser, err = userRepo.GetByID(id)
+		if err != nil {
+			return err
+		}
+		before := *existingUser
+
+		// Update fields if provided
+		if req.Name != "" {
+			existingUser.Name = req.Name
+		}
+
+		if req.Email != "" && req.Email != existingUser.Email {
+			if err := checkEmailAvailable(userRepo, req.Email, id); err != nil {
+				return err
+			}
+			existingUser.Email = req.Email
+		}
+
+		if req.Phone != "" {
+			existingUser.Phone = req.Phone
+		}
+
+		if req.Address != "" {
+			existingUser.Address = req.Address
+		}
+
+		// Update user in database
+		if err := userRepo.Update(existingUser); err != nil {
+			return emailConflict(err, existingUser.Email)
+		}
+
+		changes := audit.Diff(&before, existingUser)
+		if len(changes) == 0 {
+			return nil
+		}
+
+		event := audit.NewEvent(ctx, model.AuditActionUserUpdated, auditEntityUser, id, changes)
+		if err := s.auditRepo.WithTx(tx).Create(event); err != nil {
+			return err
+		}
+
+		return s.emit(tx, events.NewUserUpdated, existingUser)
+	})
 	if err != nil {
 		return nil, err
 	}
+	s.cache.Invalidate(ctx, id)
+
+	return existingUser, nil
+}
 
-	// Update fields if provided
-	if req.Name != "" {
-		existingUser.Name = req.Name
+// DeleteUser deletes a user
+func (s *UserService) DeleteUser(ctx context.Context, id uint) error {
+	return s.deleteUser(ctx, func(userRepo *repository.UserRepository) (*model.UserEntity, error) {
+		return userRepo.GetByID(id)
+	})
+}
+
+// DeleteUserByCode deletes the user with the given code
+func (s *UserService) DeleteUserByCode(ctx context.Context, code string) error {
+	return s.deleteUser(ctx, func(userRepo *repository.UserRepository) (*model.UserEntity, error) {
+		return userRepo.GetByCode(s.normalizer.NormalizeCode(code))
+	})
+}
+
+func (s *UserService) deleteUser(ctx context.Context, lookup func(*repository.UserRepository) (*model.UserEntity, error)) error {
+	if err := s.policy.Authorize(ctx, auth.PermUsersDelete); err != nil {
+		return err
 	}
 
-	if req.Email != "" {
-		existingUser.Email = strings.ToLower(req.Email)
+	var deletedID uint
+	err := s.transactor.WithinTransaction(ctx, func(tx *gorm.DB) error {
+		userRepo := s.userRepo.WithTx(tx)
+
+		existingUser, err := lookup(userRepo)
+		if err != nil {
+			return err
+		}
+
+		if err := userRepo.Delete(existingUser.ID); err != nil {
+			return err
+		}
+		deletedID = existingUser.ID
+
+		event := audit.NewEvent(ctx, model.AuditActionUserDeleted, auditEntityUser, existingUser.ID, audit.Diff(existingUser, nil))
+		if err := s.auditRepo.WithTx(tx).Create(event); err != nil {
+			return err
+		}
+
+		return s.emit(tx, events.NewUserDeleted, existingUser)
+	})
+	if err != nil {
+		return err
 	}
 
-	if req.Phone != "" {
-		existingUser.Phone = req.Phone
+	s.cache.Invalidate(ctx, deletedID)
+	return nil
+}
+
+// UpsertUserByCode creates the user with the given code, or replaces all of its
+// fields. Repeating the same request changes nothing. It reports whether the
+// user was created.
+func (s *UserService) UpsertUserByCode(ctx context.Context, code string, req *model.UpsertUserReq) (*model.UserEntity, bool, error) {
+	if err := s.policy.Authorize(ctx, auth.PermUsersCreate); err != nil {
+		return nil, false, err
+	}
+	if err := s.policy.Authorize(ctx, auth.PermUsersUpdate); err != nil {
+		return nil, false, err
 	}
 
-	if req.Address != "" {
-		existingUser.Address = req.Address
+	// Same rules as a create, the code coming from the path
+	normalized := &model.CreateUserReq{Code: code, Name: req.Name, Email: req.Email, Phone: req.Phone, Address: req.Address}
+	if err := s.normalizer.NormalizeCreate(normalized); err != nil {
+		return nil, false, err
 	}
 
-	// Update user in database
-	if err := s.userRepo.Update(existingUser); err != nil {
-		return nil, err
+	user := &model.UserEntity{
+		Code:    normalized.Code,
+		Name:    normalized.Name,
+		Email:   normalized.Email,
+		Phone:   normalized.Phone,
+		Address: normalized.Address,
 	}
 
-	return existingUser, nil
+	var created bool
+	err := s.transactor.WithinTransaction(ctx, func(tx *gorm.DB) error {
+		userRepo := s.userRepo.WithTx(tx)
+
+		before, err := userRepo.GetByCodeForUpdate(user.Code)
+		if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+			return err
+		}
+
+		var selfID uint
+		if before != nil {
+			selfID = before.ID
+		}
+		if err := checkEmailAvailable(userRepo, user.Email, selfID); err != nil {
+			return err
+		}
+
+		created, err = userRepo.Upsert(user)
+		if err != nil {
+			return emailConflict(err, user.Email)
+		}
+
+		action, build := model.AuditActionUserUpdated, events.NewUserUpdated
+		if created {
+			action, build = model.AuditActionUserCreated, events.NewUserCreated
+		}
+
+		changes := audit.Diff(before, user)
+		if len(changes) == 0 {
+			return nil
+		}
+
+		event := audit.NewEvent(ctx, action, auditEntityUser, user.ID, changes)
+		if err := s.auditRepo.WithTx(tx).Create(event); err != nil {
+			return err
+		}
+
+		return s.emit(tx, build, user)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	s.cache.Invalidate(ctx, user.ID)
+
+	return user, created, nil
 }
 
-// DeleteUser deletes a user
-func (s *UserService) DeleteUser(id uint) error {
-	return s.userRepo.Delete(id)
+// checkEmailAvailable returns a conflict when a user other than selfID has email.
+// The unique index still catches races between the check and the write.
+func checkEmailAvailable(userRepo *repository.UserRepository, email string, selfID uint) error {
+	other, err := userRepo.GetByEmail(email)
+	switch {
+	case errors.Is(err, repository.ErrUserNotFound):
+		return nil
+	case err != nil:
+		return err
+	case other.ID != selfID:
+		return &ConflictError{Code: "email_taken", Field: "email", Value: email}
+	}
+	return nil
+}
+
+// emailConflict converts a unique index violation on the email into a ConflictError
+func emailConflict(err error, email string) error {
+	if errors.Is(err, repository.ErrEmailTaken) {
+		return &ConflictError{Code: "email_taken", Field: "email", Value: email}
+	}
+	return err
+}
+
+// emit writes a domain event for user to the outbox and queues it for
+// matching webhook subscriptions, all in tx
+func (s *UserService) emit(tx *gorm.DB, build func(*model.UserEntity) (*events.Event, error), user *model.UserEntity) error {
+	event, err := build(user)
+	if err != nil {
+		return err
+	}
+
+	row, err := event.ToOutbox()
+	if err != nil {
+		return err
+	}
+	if err := s.outboxRepo.WithTx(tx).Create(row); err != nil {
+		return err
+	}
+
+	return s.webhookRepo.WithTx(tx).EnqueueDeliveries(row.EventID, row.EventType, row.Payload, row.NextAttemptAt)
 }
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/raytr/go-template/internal/auth"
+	"github.com/raytr/go-template/internal/events"
+	"github.com/raytr/go-template/internal/model"
+	"github.com/raytr/go-template/internal/repository"
+	"github.com/raytr/go-template/internal/webhook"
+)
+
+// WebhookService handles business logic for webhook subscriptions and deliveries
+type WebhookService struct {
+	webhookRepo         *repository.WebhookRepository
+	policy              *auth.Policy
+	allowPrivateTargets bool
+	*BasePaginationService
+}
+
+// NewWebhookService creates a new webhook service. Subscriptions to loopback,
+// link-local and private addresses are rejected unless allowPrivateTargets is set.
+func NewWebhookService(webhookRepo *repository.WebhookRepository, policy *auth.Policy, allowPrivateTargets bool) *WebhookService {
+	return &WebhookService{
+		webhookRepo:           webhookRepo,
+		policy:                policy,
+		allowPrivateTargets:   allowPrivateTargets,
+		BasePaginationService: NewBasePaginationService(),
+	}
+}
+
+// CreateWebhook creates a new webhook subscription and returns it with its signing secret
+func (s *WebhookService) CreateWebhook(ctx context.Context, req *model.CreateWebhookReq) (*model.WebhookSubscriptionEntity, error) {
+	if err := s.policy.Authorize(ctx, auth.PermWebhooksManage); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateURL(ctx, req.URL); err != nil {
+		return nil, err
+	}
+	if err := validateEventTypes(req.EventTypes); err != nil {
+		return nil, err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		var err error
+		if secret, err = webhook.GenerateSecret(); err != nil {
+			return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+		}
+	}
+
+	sub := &model.WebhookSubscriptionEntity{
+		URL:         req.URL,
+		EventTypes:  req.EventTypes,
+		Secret:      secret,
+		Description: req.Description,
+		Active:      true,
+	}
+
+	if err := s.webhookRepo.CreateSubscription(sub); err != nil {
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+// GetWebhookByID retrieves a webhook subscription by ID
+func (s *WebhookService) GetWebhookByID(ctx context.Context, id uint) (*model.WebhookSubscriptionEntity, error) {
+	if err := s.policy.Authorize(ctx, auth.PermWebhooksManage); err != nil {
+		return nil, err
+	}
+
+	return s.webhookRepo.GetSubscriptionByID(id)
+}
+
+// GetAllWebhooks retrieves all webhook subscriptions with pagination
+func (s *WebhookService) GetAllWebhooks(ctx context.Context, page, pageSize int) ([]*model.WebhookSubscriptionEntity, int64, error) {
+	if err := s.policy.Authorize(ctx, auth.PermWebhooksManage); err != nil {
+		return nil, 0, err
+	}
+
+	pagination, err := s.CreatePaginationRequest(page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	subs, err := s.webhookRepo.GetAllSubscriptions(pagination)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	totalCount, err := s.webhookRepo.CountSubscriptions()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return subs, totalCount, nil
+}
+
+// UpdateWebhook updates an existing webhook subscription
+func (s *WebhookService) UpdateWebhook(ctx context.Context, id uint, req *model.UpdateWebhookReq) (*model.WebhookSubscriptionEntity, error) {
+	if err := s.policy.Authorize(ctx, auth.PermWebhooksManage); err != nil {
+		return nil, err
+	}
+
+	sub, err := s.webhookRepo.GetSubscriptionByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.URL != "" {
+		if err := s.validateURL(ctx, req.URL); err != nil {
+			return nil, err
+		}
+		sub.URL = req.URL
+	}
+
+	if len(req.EventTypes) > 0 {
+		if err := validateEventTypes(req.EventTypes); err != nil {
+			return nil, err
+		}
+		sub.EventTypes = req.EventTypes
+	}
+
+	if req.Secret != "" {
+		sub.Secret = req.Secret
+	}
+
+	if req.Description != "" {
+		sub.Description = req.Description
+	}
+
+	if req.Active != nil {
+		sub.Active = *req.Active
+	}
+
+	if err := s.webhookRepo.UpdateSubscription(sub); err != nil {
+		return nil, err
+	}
+
+	return sub, nil
+}
+
+// DeleteWebhook deletes a webhook subscription and its delivery history
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id uint) error {
+	if err := s.policy.Authorize(ctx, auth.PermWebhooksManage); err != nil {
+		return err
+	}
+
+	return s.webhookRepo.DeleteSubscription(id)
+}
+
+// GetDeliveries retrieves webhook deliveries matching the filter with pagination.
+// Filtering on the dead status lists the dead-letter deliveries.
+func (s *WebhookService) GetDeliveries(ctx context.Context, filter *model.WebhookDeliveryFilter, page, pageSize int) ([]*model.WebhookDeliveryEntity, int64, error) {
+	if err := s.policy.Authorize(ctx, auth.PermWebhooksManage); err != nil {
+		return nil, 0, err
+	}
+
+	pagination, err := s.CreatePaginationRequest(page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	deliveries, err := s.webhookRepo.GetDeliveries(filter, pagination)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	totalCount, err := s.webhookRepo.CountDeliveries(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return deliveries, totalCount, nil
+}
+
+// GetDeliveryAttempts retrieves the attempt history of a delivery
+func (s *WebhookService) GetDeliveryAttempts(ctx context.Context, deliveryID uint64) ([]*model.WebhookDeliveryAttemptEntity, error) {
+	if err := s.policy.Authorize(ctx, auth.PermWebhooksManage); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.webhookRepo.GetDeliveryByID(deliveryID); err != nil {
+		return nil, err
+	}
+
+	return s.webhookRepo.GetAttempts(deliveryID)
+}
+
+// Redeliver schedules a delivery, typically a dead one, to be attempted again immediately
+func (s *WebhookService) Redeliver(ctx context.Context, deliveryID uint64) error {
+	if err := s.policy.Authorize(ctx, auth.PermWebhooksManage); err != nil {
+		return err
+	}
+
+	return s.webhookRepo.Redeliver(deliveryID, time.Now())
+}
+
+// validateURL rejects targets that are not public http(s) addresses
+func (s *WebhookService) validateURL(ctx context.Context, rawURL string) error {
+	if s.allowPrivateTargets {
+		return nil
+	}
+
+	if err := webhook.CheckURL(ctx, rawURL); err != nil {
+		verr := &ValidationError{}
+		verr.Add("url", "public_url", "")
+		return verr
+	}
+	return nil
+}
+
+// validateEventTypes checks that every entry is a known event type or "*"
+func validateEventTypes(eventTypes []string) error {
+	verr := &ValidationError{}
+
+	for _, t := range eventTypes {
+		if t != "*" && !events.IsKnownType(t) {
+			verr.Add("event_types", "oneof", "* "+strings.Join(events.Types, " "))
+			break
+		}
+	}
+
+	return verr.OrNil()
+}
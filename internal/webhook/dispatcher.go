@@ -0,0 +1,205 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/raytr/go-template/internal/model"
+	"github.com/raytr/go-template/internal/repository"
+	"gorm.io/gorm"
+)
+
+// DispatcherConfig controls how the dispatcher polls and retries
+type DispatcherConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	Timeout      time.Duration
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	// LeaseTimeout hides a claimed batch from other dispatchers. It must exceed
+	// BatchSize times Timeout.
+	LeaseTimeout time.Duration
+	// AllowPrivateTargets lets deliveries reach loopback and private addresses
+	AllowPrivateTargets bool
+}
+
+// Dispatcher sends pending webhook deliveries. Deliveries that fail MaxAttempts
+// times are moved to the dead-letter list.
+type Dispatcher struct {
+	webhookRepo *repository.WebhookRepository
+	transactor  *repository.Transactor
+	client      *http.Client
+	cfg         DispatcherConfig
+}
+
+// NewDispatcher creates a new webhook dispatcher
+func NewDispatcher(
+	webhookRepo *repository.WebhookRepository,
+	transactor *repository.Transactor,
+	cfg DispatcherConfig,
+) *Dispatcher {
+	return &Dispatcher{
+		webhookRepo: webhookRepo,
+		transactor:  transactor,
+		client:      NewClient(cfg.Timeout, cfg.AllowPrivateTargets),
+		cfg:         cfg,
+	}
+}
+
+// Run polls for due deliveries until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := d.ProcessBatch(ctx)
+			if err != nil {
+				log.Printf("Webhook dispatcher error: %v", err)
+				break
+			}
+			if n < d.cfg.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch claims one batch of due deliveries and attempts them. Each
+// attempt is recorded in its own short transaction once the request is done.
+// It returns the number of deliveries handled.
+func (d *Dispatcher) ProcessBatch(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	deliveries, err := d.webhookRepo.ClaimDueDeliveries(now, d.cfg.BatchSize, now.Add(d.cfg.LeaseTimeout))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, delivery := range deliveries {
+		// The lease expires and the delivery is attempted again
+		if err := d.attempt(ctx, delivery); err != nil {
+			return len(deliveries), err
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+// attempt sends one delivery and records the attempt and resulting state
+func (d *Dispatcher) attempt(ctx context.Context, delivery *model.WebhookDeliveryEntity) error {
+	sub, err := d.webhookRepo.GetSubscriptionByID(delivery.SubscriptionID)
+	if err != nil {
+		return err
+	}
+
+	// The claim moved the next attempt to the lease, which guards the update
+	leasedUntil := delivery.NextAttemptAt
+
+	// Not a failure of the endpoint, so it uses no attempt and is not retried
+	if !sub.Active {
+		cancelled, err := d.webhookRepo.CancelDelivery(delivery.ID, "subscription is inactive", leasedUntil)
+		if cancelled {
+			log.Printf("Webhook delivery %d cancelled: subscription %d is inactive", delivery.ID, sub.ID)
+		}
+		return err
+	}
+
+	started := time.Now()
+	statusCode, sendErr := d.send(ctx, sub, delivery)
+
+	delivery.Attempts++
+	record := &model.WebhookDeliveryAttemptEntity{
+		DeliveryID: delivery.ID,
+		Attempt:    delivery.Attempts,
+		DurationMs: int(time.Since(started).Milliseconds()),
+	}
+	if statusCode != 0 {
+		record.StatusCode = &statusCode
+		delivery.LastStatusCode = &statusCode
+	}
+	if sendErr != nil {
+		record.Error = sendErr.Error()
+	}
+
+	now := time.Now().UTC()
+	switch {
+	case sendErr == nil:
+		delivery.Status = model.WebhookDeliveryDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+	case delivery.Attempts >= d.cfg.MaxAttempts:
+		delivery.Status = model.WebhookDeliveryDead
+		delivery.LastError = sendErr.Error()
+		log.Printf("Webhook delivery %d to subscription %d moved to dead-letter list after %d attempts: %v",
+			delivery.ID, sub.ID, delivery.Attempts, sendErr)
+	default:
+		delivery.LastError = sendErr.Error()
+		delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts))
+	}
+
+	return d.transactor.WithinTransaction(ctx, func(tx *gorm.DB) error {
+		webhookRepo := d.webhookRepo.WithTx(tx)
+		if err := webhookRepo.CreateAttempt(record); err != nil {
+			return err
+		}
+		updated, err := webhookRepo.UpdateDelivery(delivery, leasedUntil)
+		if err == nil && !updated {
+			log.Printf("Webhook delivery %d changed while it was sent, keeping its new state", delivery.ID)
+		}
+		return err
+	})
+}
+
+// send POSTs the signed payload and returns the response status code
+func (d *Dispatcher) send(ctx context.Context, sub *model.WebhookSubscriptionEntity, delivery *model.WebhookDeliveryEntity) (int, error) {
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEventType, delivery.EventType)
+	req.Header.Set(HeaderEventID, delivery.EventID)
+	req.Header.Set(HeaderDelivery, strconv.FormatUint(delivery.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// backoff returns the delay before the given attempt number is retried
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.cfg.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= d.cfg.MaxBackoff {
+			return d.cfg.MaxBackoff
+		}
+	}
+	return delay
+}
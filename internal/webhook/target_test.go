@@ -0,0 +1,84 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"0.0.0.0", false},
+		{"0.1.2.3", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.100.100.200", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+		{"224.0.0.1", false},
+		{"255.255.255.255", false},
+	}
+
+	for _, tt := range tests {
+		if got := PublicAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("PublicAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://93.184.216.34/hooks", true},
+		{"http://[2606:2800:220:1:248:1893:25c8:1946]:8080/hooks", true},
+		{"http://127.0.0.1:8080/hooks", false},
+		{"http://localhost/hooks", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"http://[::1]/hooks", false},
+		{"ftp://93.184.216.34/hooks", false},
+		{"https:///hooks", false},
+	}
+
+	for _, tt := range tests {
+		err := CheckURL(context.Background(), tt.url)
+		if got := err == nil; got != tt.want {
+			t.Errorf("CheckURL(%q) = %v, want allowed %v", tt.url, err, tt.want)
+		}
+		if err != nil && !errors.Is(err, ErrPrivateTarget) {
+			t.Errorf("CheckURL(%q) = %v, want ErrPrivateTarget", tt.url, err)
+		}
+	}
+}
+
+func TestNewClientRefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, err := NewClient(time.Second, false).Get(server.URL)
+	if !errors.Is(err, ErrPrivateTarget) {
+		t.Errorf("Get(%s) = %v, want ErrPrivateTarget", server.URL, err)
+	}
+
+	resp, err := NewClient(time.Second, true).Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get(%s) with private targets allowed failed: %v", server.URL, err)
+	}
+	resp.Body.Close()
+}
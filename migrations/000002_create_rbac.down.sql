@@ -0,0 +1,10 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_user_roles_role_id;
+
+-- Drop join tables
+DROP TABLE IF EXISTS user_roles;
+DROP TABLE IF EXISTS role_permissions;
+
+-- Drop tables
+DROP TABLE IF EXISTS permissions;
+DROP TABLE IF EXISTS roles;
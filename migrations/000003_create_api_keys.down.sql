@@ -0,0 +1,8 @@
+-- Remove API key management permission
+DELETE FROM permissions WHERE name = 'api_keys:manage';
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_api_keys_created_at;
+
+-- Drop api_keys table
+DROP TABLE IF EXISTS api_keys;
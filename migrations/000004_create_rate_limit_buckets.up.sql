@@ -0,0 +1,9 @@
+-- Create rate_limit_buckets table used by the postgres rate limit backend
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    key VARCHAR(255) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- Create indexes for idle bucket cleanup
+CREATE INDEX idx_rate_limit_buckets_updated_at ON rate_limit_buckets(updated_at);
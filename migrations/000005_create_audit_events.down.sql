@@ -0,0 +1,11 @@
+-- Remove audit read permission
+DELETE FROM permissions WHERE name = 'audit:read';
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_audit_events_created_at;
+DROP INDEX IF EXISTS idx_audit_events_request_id;
+DROP INDEX IF EXISTS idx_audit_events_actor;
+DROP INDEX IF EXISTS idx_audit_events_entity;
+
+-- Drop audit_events table
+DROP TABLE IF EXISTS audit_events;
@@ -0,0 +1,6 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_outbox_events_aggregate;
+DROP INDEX IF EXISTS idx_outbox_events_pending;
+
+-- Drop outbox_events table
+DROP TABLE IF EXISTS outbox_events;
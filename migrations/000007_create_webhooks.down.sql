@@ -0,0 +1,15 @@
+-- Remove webhook management permission
+DELETE FROM permissions WHERE name = 'webhooks:manage';
+
+-- Drop trigger
+DROP TRIGGER IF EXISTS update_webhook_subscriptions_updated_at ON webhook_subscriptions;
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_webhook_delivery_attempts_delivery;
+DROP INDEX IF EXISTS idx_webhook_deliveries_subscription;
+DROP INDEX IF EXISTS idx_webhook_deliveries_pending;
+
+-- Drop tables
+DROP TABLE IF EXISTS webhook_delivery_attempts;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_subscriptions;
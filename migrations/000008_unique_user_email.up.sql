@@ -0,0 +1,4 @@
+-- Emails are unique regardless of case. This fails while duplicates exist;
+-- list them with `make report-duplicate-emails` and merge them first.
+DROP INDEX IF EXISTS idx_users_email;
+CREATE UNIQUE INDEX users_email_lower_key ON users (lower(email));
@@ -0,0 +1,8 @@
+-- Remove feature flag management permission
+DELETE FROM permissions WHERE name = 'flags:manage';
+
+-- Drop trigger
+DROP TRIGGER IF EXISTS update_feature_flags_updated_at ON feature_flags;
+
+-- Drop feature_flags table
+DROP TABLE IF EXISTS feature_flags;
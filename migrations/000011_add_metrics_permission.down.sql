@@ -0,0 +1,2 @@
+-- Remove metrics permission
+DELETE FROM permissions WHERE name = 'metrics:read';
@@ -0,0 +1,9 @@
+-- Seed metrics permission
+INSERT INTO permissions (name, description) VALUES
+    ('metrics:read', 'Read cache and runtime metrics')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r, permissions p
+WHERE r.name = 'admin' AND p.name = 'metrics:read'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,11 @@
+// Package migrations holds the SQL migrations compiled into the binary.
+package migrations
+
+import (
+	"embed"
+)
+
+// FS holds the migration files, named <version>_<name>.<up|down>.sql
+//
+//go:embed *.sql
+var FS embed.FS